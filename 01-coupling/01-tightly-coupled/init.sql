@@ -1 +0,0 @@
-CREATE DATABASE tightly_coupled;
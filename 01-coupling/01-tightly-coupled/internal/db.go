@@ -2,6 +2,7 @@ package internal
 
 import (
 	"errors"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
@@ -10,6 +11,7 @@ import (
 var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrEmailAlreadyExists = errors.New("email already exists")
+	ErrUserModified       = errors.New("user has been modified in the meantime")
 )
 
 type UserStorage struct {
@@ -49,7 +51,18 @@ func (s UserStorage) ByID(id int) (User, error) {
 
 func (s UserStorage) Add(user User) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		result := tx.Omit("Emails").Create(&user)
+		// Check the address ignoring the case, so it doesn't depend on the unique index's collation.
+		var count int64
+		result := tx.Model(&Email{}).Where("LOWER(address) = LOWER(?)", user.Emails[0].Address).Count(&count)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if count > 0 {
+			return ErrEmailAlreadyExists
+		}
+
+		result = tx.Omit("Emails").Create(&user)
 		if result.Error != nil {
 			return result.Error
 		}
@@ -69,9 +82,25 @@ func (s UserStorage) Add(user User) error {
 	})
 }
 
+// Update saves the user only if its version didn't change since it was read.
 func (s UserStorage) Update(user User) error {
-	result := s.db.Save(user)
-	return result.Error
+	result := s.db.Model(&User{}).
+		Where("id = ? AND version = ?", user.ID, user.Version).
+		Updates(map[string]interface{}{
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUserModified
+	}
+
+	return nil
 }
 
 func (s UserStorage) Delete(id int) error {
@@ -2,10 +2,8 @@ package internal
 
 import (
 	"encoding/json"
-	"errors"
 	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -23,8 +21,7 @@ func NewUserHandler(storage UserStorage) UserHandler {
 func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.storage.All()
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -41,26 +38,22 @@ func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	userID, err := parseUserID(chi.URLParam(r, "userID"))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	user, err := h.storage.ByID(userID)
 	if err != nil {
-		log.Println(err)
-		if errors.Is(err, ErrUserNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
 	user.SetDisplayName()
 
+	w.Header().Set("ETag", userETag(user.Version))
+
 	err = json.NewEncoder(w).Encode(user)
 	if err != nil {
 		log.Println(err)
@@ -71,10 +64,9 @@ func (h UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 func (h UserHandler) PostUser(w http.ResponseWriter, r *http.Request) {
 	var user User
-	err := json.NewDecoder(r.Body).Decode(&user)
+	err := decodeRequestBody(r, &user)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -86,19 +78,21 @@ func (h UserHandler) PostUser(w http.ResponseWriter, r *http.Request) {
 
 	err = user.Validate()
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
+	emailAddress, err := NewEmailAddress(user.Emails[0].Address)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user.Emails[0].Address = emailAddress.String()
+
 	err = h.storage.Add(user)
 	if err != nil {
-		log.Println(err)
-		if errors.Is(err, ErrEmailAlreadyExists) {
-			w.WriteHeader(http.StatusBadRequest)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
@@ -106,10 +100,9 @@ func (h UserHandler) PostUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	userID, err := parseUserID(chi.URLParam(r, "userID"))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
@@ -118,17 +111,21 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
 		LastName  *string `json:"last_name"`
 	}
 	var update userUpdate
-	err = json.NewDecoder(r.Body).Decode(&update)
+	err = decodeRequestBody(r, &update)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	user, err := h.storage.ByID(userID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
+		return
+	}
+
+	err = checkIfMatch(r.Header.Get("If-Match"), userETag(user.Version))
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -142,15 +139,13 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
 
 	err = user.Validate()
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	err = h.storage.Update(user)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -158,17 +153,15 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	userID, err := parseUserID(chi.URLParam(r, "userID"))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	err = h.storage.Delete(userID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -2,8 +2,6 @@ package internal
 
 import (
 	"time"
-
-	"github.com/go-playground/validator/v10"
 )
 
 type User struct {
@@ -15,12 +13,13 @@ type User struct {
 	Emails       []Email    `json:"emails" validate:"required,dive" gorm:"constraint:OnDelete:CASCADE"`
 	PasswordHash string     `json:"-"`
 	LastIP       string     `json:"-"`
+	Version      int        `json:"-" gorm:"not null;default:1"`
 	CreatedAt    *time.Time `json:"-"`
 	UpdatedAt    *time.Time `json:"-"`
 }
 
 func (u *User) Validate() error {
-	validate := validator.New()
+	validate := newValidator()
 	return validate.Struct(u)
 }
 
@@ -38,7 +37,7 @@ func (u *User) SetDisplayName() {
 
 type Email struct {
 	ID      int    `json:"-" gorm:"primaryKey"`
-	Address string `json:"address" validate:"required,email" gorm:"size:256;uniqueIndex"`
+	Address string `json:"address" validate:"required,email_address" gorm:"size:256;uniqueIndex"`
 	Primary bool   `json:"primary"`
 	UserID  int    `json:"-"`
 }
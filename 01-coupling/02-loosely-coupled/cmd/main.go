@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/02-loosely-coupled/internal"
 	"github.com/go-chi/chi/v5"
@@ -12,19 +14,32 @@ import (
 )
 
 func main() {
-	db, err := gorm.Open(mysql.Open("root@tcp(mysql)/loosely_coupled?parseTime=true"), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open("root@tcp(mysql)/loosely_coupled?parseTime=true&multiStatements=true"), &gorm.Config{})
 	if err != nil {
 		log.Fatal("failed to connect database")
 	}
 
-	err = db.AutoMigrate(&internal.UserDBModel{})
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("failed to apply migrations")
+		log.Fatal("failed to connect database")
+	}
+
+	migrator, err := internal.NewMigrator(sqlDB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = migrator.RunCommand(context.Background(), os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	err = db.AutoMigrate(&internal.EmailDBModel{})
+	err = migrator.Up(context.Background())
 	if err != nil {
-		log.Fatal("failed to apply migrations")
+		log.Fatal("failed to apply migrations: ", err)
 	}
 
 	storage := internal.NewUserStorage(db)
@@ -3,12 +3,16 @@ module github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/02-loosely-c
 go 1.16
 
 require (
+	github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate v0.0.0-00010101000000-000000000000
 	github.com/go-chi/chi/v5 v5.0.3
 	github.com/go-playground/universal-translator v0.17.0 // indirect
 	github.com/go-playground/validator v9.31.0+incompatible // indirect
-	github.com/go-playground/validator/v10 v10.6.1 // indirect
-	github.com/go-sql-driver/mysql v1.6.0 // indirect
+	github.com/go-playground/validator/v10 v10.6.1
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/leodido/go-urn v1.2.1 // indirect
+	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
 	gorm.io/driver/mysql v1.1.0
 	gorm.io/gorm v1.21.10
 )
+
+replace github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate => ../../migrate
@@ -1 +0,0 @@
-CREATE DATABASE loosely_coupled;
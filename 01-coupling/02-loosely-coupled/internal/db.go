@@ -11,6 +11,7 @@ import (
 var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrEmailAlreadyExists = errors.New("email already exists")
+	ErrUserModified       = errors.New("user has been modified in the meantime")
 )
 
 type UserDBModel struct {
@@ -20,6 +21,7 @@ type UserDBModel struct {
 	Emails       []EmailDBModel `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	PasswordHash string         `gorm:"column:password_hash"`
 	LastIP       string         `gorm:"column:last_ip"`
+	Version      int            `gorm:"column:version;not null;default:1"`
 	CreatedAt    *time.Time     `gorm:"column:created_at"`
 	UpdatedAt    *time.Time     `gorm:"column:updated_at"`
 }
@@ -76,7 +78,18 @@ func (s UserStorage) ByID(id int) (UserDBModel, error) {
 
 func (s UserStorage) Add(user UserDBModel) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		result := tx.Omit("Emails").Create(&user)
+		// Check the address ignoring the case, so it doesn't depend on the unique index's collation.
+		var count int64
+		result := tx.Model(&EmailDBModel{}).Where("LOWER(address) = LOWER(?)", user.Emails[0].Address).Count(&count)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if count > 0 {
+			return ErrEmailAlreadyExists
+		}
+
+		result = tx.Omit("Emails").Create(&user)
 		if result.Error != nil {
 			return result.Error
 		}
@@ -96,9 +109,25 @@ func (s UserStorage) Add(user UserDBModel) error {
 	})
 }
 
+// Update saves the user only if its version didn't change since it was read.
 func (s UserStorage) Update(user UserDBModel) error {
-	result := s.db.Save(user)
-	return result.Error
+	result := s.db.Model(&UserDBModel{}).
+		Where("id = ? AND version = ?", user.ID, user.Version).
+		Updates(map[string]interface{}{
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUserModified
+	}
+
+	return nil
 }
 
 func (s UserStorage) Delete(id int) error {
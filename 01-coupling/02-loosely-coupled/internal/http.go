@@ -2,12 +2,8 @@ package internal
 
 import (
 	"encoding/json"
-	"errors"
 	"log"
 	"net/http"
-	"strconv"
-
-	"github.com/go-playground/validator/v10"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -15,7 +11,7 @@ import (
 type CreateUserRequest struct {
 	FirstName string `json:"first_name" validate:"required_without=LastName"`
 	LastName  string `json:"last_name" validate:"required_without=FirstName"`
-	Email     string `json:"email" validate:"required,email"`
+	Email     string `json:"email" validate:"required,email_address"`
 }
 
 type UpdateUserRequest struct {
@@ -49,8 +45,7 @@ func NewUserHandler(storage UserStorage) UserHandler {
 func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.storage.All()
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -68,26 +63,22 @@ func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	userID, err := parseUserID(chi.URLParam(r, "userID"))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	user, err := h.storage.ByID(userID)
 	if err != nil {
-		log.Println(err)
-		if errors.Is(err, ErrUserNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
 	userResponse := userResponseFromDBModel(user)
 
+	w.Header().Set("ETag", userETag(user.Version))
+
 	err = json.NewEncoder(w).Encode(userResponse)
 	if err != nil {
 		log.Println(err)
@@ -98,31 +89,32 @@ func (h UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 func (h UserHandler) PostUser(w http.ResponseWriter, r *http.Request) {
 	var createUserRequest CreateUserRequest
-	err := json.NewDecoder(r.Body).Decode(&createUserRequest)
+	err := decodeRequestBody(r, &createUserRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	validate := validator.New()
+	validate := newValidator()
 	err = validate.Struct(createUserRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
+		return
+	}
+
+	emailAddress, err := NewEmailAddress(createUserRequest.Email)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
+	createUserRequest.Email = emailAddress.String()
+
 	user := userDBModelFromCreateRequest(createUserRequest)
 
 	err = h.storage.Add(user)
 	if err != nil {
-		log.Println(err)
-		if errors.Is(err, ErrEmailAlreadyExists) {
-			w.WriteHeader(http.StatusBadRequest)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
@@ -130,18 +122,16 @@ func (h UserHandler) PostUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	userID, err := parseUserID(chi.URLParam(r, "userID"))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	var updateUserRequest UpdateUserRequest
-	err = json.NewDecoder(r.Body).Decode(&updateUserRequest)
+	err = decodeRequestBody(r, &updateUserRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -151,18 +141,22 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validate := validator.New()
+	validate := newValidator()
 	err = validate.Struct(updateUserRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	user, err := h.storage.ByID(userID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
+		return
+	}
+
+	err = checkIfMatch(r.Header.Get("If-Match"), userETag(user.Version))
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -175,14 +169,13 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if displayName(user.FirstName, user.LastName) == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, errNameRequired)
 		return
 	}
 
 	err = h.storage.Update(user)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -190,17 +183,15 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	userID, err := parseUserID(chi.URLParam(r, "userID"))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	err = h.storage.Delete(userID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// userETag returns the entity tag of the user, derived from the row version.
+func userETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// checkIfMatch verifies the If-Match header against the current entity tag of the user.
+// The header is required, so clients can't overwrite changes they haven't seen.
+func checkIfMatch(ifMatch string, etag string) error {
+	if ifMatch == "" {
+		return errPreconditionRequired
+	}
+
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return nil
+		}
+	}
+
+	return errPreconditionFailed
+}
@@ -0,0 +1,23 @@
+-- IF NOT EXISTS keeps databases created by the old gorm AutoMigrate working.
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `first_name` longtext,
+  `last_name` longtext,
+  `password_hash` longtext,
+  `last_ip` longtext,
+  `version` bigint NOT NULL DEFAULT 1,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `emails` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `address` varchar(256) DEFAULT NULL,
+  `primary` tinyint(1) DEFAULT NULL,
+  `user_id` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_emails_address` (`address`),
+  KEY `fk_users_emails` (`user_id`),
+  CONSTRAINT `fk_users_emails` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/03-loosely-coupled-generated/internal"
 	"github.com/go-chi/chi/v5"
@@ -12,11 +14,29 @@ import (
 )
 
 func main() {
-	db, err := sql.Open("mysql", "root@tcp(mysql)/loosely_coupled_app_layer?parseTime=true")
+	db, err := sql.Open("mysql", "root@tcp(mysql)/loosely_coupled_generated?parseTime=true&multiStatements=true")
 	if err != nil {
 		log.Fatal("failed to connect to the database")
 	}
 
+	migrator, err := internal.NewMigrator(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = migrator.RunCommand(context.Background(), os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err = migrator.Up(context.Background())
+	if err != nil {
+		log.Fatal("failed to apply migrations: ", err)
+	}
+
 	storage := internal.NewUserStorage(db)
 	h := internal.NewUserHandler(storage)
 
@@ -24,7 +44,18 @@ func main() {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Logger)
 
-	handler := internal.HandlerFromMux(h, r)
+	// Responses are validated only in tests, as it needs buffering them.
+	validator, err := internal.NewOpenAPIValidator(internal.OpenAPIValidatorOptions{
+		ValidateResponses: os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	handler := internal.HandlerWithOptions(h, internal.ChiServerOptions{
+		BaseRouter:  r,
+		Middlewares: []internal.MiddlewareFunc{validator},
+	})
 
 	err = http.ListenAndServe(":8080", handler)
 	if err != nil {
@@ -3,12 +3,17 @@ module github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/03-loosely-c
 go 1.16
 
 require (
-	github.com/deepmap/oapi-codegen v1.8.1 // indirect
-	github.com/friendsofgo/errors v0.9.2 // indirect
+	github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate v0.0.0-00010101000000-000000000000
+	github.com/deepmap/oapi-codegen v1.8.1
+	github.com/friendsofgo/errors v0.9.2
+	github.com/getkin/kin-openapi v0.61.0
 	github.com/go-chi/chi/v5 v5.0.3
-	github.com/go-playground/validator/v10 v10.6.1 // indirect
-	github.com/go-sql-driver/mysql v1.6.0 // indirect
-	github.com/volatiletech/null/v8 v8.1.2 // indirect
-	github.com/volatiletech/sqlboiler/v4 v4.6.0 // indirect
-	github.com/volatiletech/strmangle v0.0.1 // indirect
+	github.com/go-playground/validator/v10 v10.6.1
+	github.com/go-sql-driver/mysql v1.6.0
+	github.com/volatiletech/null/v8 v8.1.2
+	github.com/volatiletech/sqlboiler/v4 v4.6.0
+	github.com/volatiletech/strmangle v0.0.1
+	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
 )
+
+replace github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate => ../../migrate
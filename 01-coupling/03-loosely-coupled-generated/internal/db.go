@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/03-loosely-coupled-generated/models"
 	"github.com/go-sql-driver/mysql"
@@ -15,6 +16,7 @@ import (
 var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrEmailAlreadyExists = errors.New("email already exists")
+	ErrUserModified       = errors.New("user has been modified in the meantime")
 )
 
 type UserStorage struct {
@@ -60,6 +62,16 @@ func (s UserStorage) Add(ctx context.Context, user *models.User, email *models.E
 		}
 	}()
 
+	// Check the address ignoring the case, so it doesn't depend on the unique index's collation.
+	exists, err := models.Emails(qm.Where("LOWER(address) = LOWER(?)", email.Address)).Exists(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrEmailAlreadyExists
+	}
+
 	err = user.Insert(ctx, tx, boil.Infer())
 	if err != nil {
 		return err
@@ -79,9 +91,23 @@ func (s UserStorage) Add(ctx context.Context, user *models.User, email *models.E
 	return nil
 }
 
+// Update saves the user only if its version didn't change since it was read.
 func (s UserStorage) Update(ctx context.Context, user *models.User) error {
-	_, err := user.Update(ctx, s.db, boil.Whitelist(models.UserColumns.FirstName, models.UserColumns.LastName))
-	return err
+	rowsAffected, err := models.Users(qm.Where("id = ? AND version = ?", user.ID, user.Version)).UpdateAll(ctx, s.db, models.M{
+		models.UserColumns.FirstName: user.FirstName,
+		models.UserColumns.LastName:  user.LastName,
+		models.UserColumns.Version:   user.Version + 1,
+		models.UserColumns.UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserModified
+	}
+
+	return nil
 }
 
 func (s UserStorage) Delete(ctx context.Context, id int) error {
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrEmailRequired = errors.New("email address is required")
+	ErrInvalidEmail  = errors.New("invalid email address")
+)
+
+const (
+	maxEmailAddressLength = 254
+	maxLocalPartLength    = 64
+)
+
+// domainProfile converts internationalized domain names to their ASCII (punycode) form.
+var domainProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+)
+
+// EmailAddress is a valid, normalized e-mail address.
+//
+// The domain is lowercased and stored in its ASCII form, so "John@Bücher.example"
+// and "John@xn--bcher-kva.example" are the same address. The local part is kept as it is,
+// because it's case-sensitive according to RFC 5321. Use Equal to compare addresses.
+type EmailAddress struct {
+	address string
+}
+
+func NewEmailAddress(raw string) (EmailAddress, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return EmailAddress{}, ErrEmailRequired
+	}
+
+	parsed, err := mail.ParseAddress(raw)
+	if err != nil {
+		return EmailAddress{}, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+
+	// ParseAddress accepts also addresses with a display name, like "John <john@example.com>".
+	if parsed.Name != "" || parsed.Address != raw {
+		return EmailAddress{}, fmt.Errorf("%w: expected a bare address", ErrInvalidEmail)
+	}
+
+	at := strings.LastIndex(parsed.Address, "@")
+	localPart, domain := parsed.Address[:at], parsed.Address[at+1:]
+
+	if len(localPart) > maxLocalPartLength {
+		return EmailAddress{}, fmt.Errorf("%w: local part longer than %d characters", ErrInvalidEmail, maxLocalPartLength)
+	}
+
+	asciiDomain, err := domainProfile.ToASCII(domain)
+	if err != nil {
+		return EmailAddress{}, fmt.Errorf("%w: invalid domain: %v", ErrInvalidEmail, err)
+	}
+
+	address := localPart + "@" + strings.ToLower(asciiDomain)
+	if len(address) > maxEmailAddressLength {
+		return EmailAddress{}, fmt.Errorf("%w: longer than %d characters", ErrInvalidEmail, maxEmailAddressLength)
+	}
+
+	return EmailAddress{
+		address: address,
+	}, nil
+}
+
+func (a EmailAddress) String() string {
+	return a.address
+}
+
+// Equal compares addresses case-insensitively, the same way the storage checks their uniqueness.
+func (a EmailAddress) Equal(other EmailAddress) bool {
+	return strings.EqualFold(a.address, other.address)
+}
@@ -2,13 +2,10 @@ package internal
 
 import (
 	"encoding/json"
-	"errors"
 	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/03-loosely-coupled-generated/models"
-	"github.com/go-playground/validator/v10"
 )
 
 type UserHandler struct {
@@ -24,16 +21,18 @@ func NewUserHandler(storage UserStorage) UserHandler {
 func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.storage.All(r.Context())
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	var usersResponse []UserResponse
+	// Not nil, so no users are encoded as an empty array, as the spec requires.
+	usersResponse := []UserResponse{}
 	for _, u := range users {
 		usersResponse = append(usersResponse, userResponseFromDBModel(u))
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+
 	err = json.NewEncoder(w).Encode(usersResponse)
 	if err != nil {
 		log.Println(err)
@@ -43,26 +42,23 @@ func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h UserHandler) GetUser(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
-	userID, err := strconv.Atoi(string(rawUserID))
+	userID, err := parseUserID(rawUserID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	user, err := h.storage.ByID(r.Context(), userID)
 	if err != nil {
-		log.Println(err)
-		if errors.Is(err, ErrUserNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
 	userResponse := userResponseFromDBModel(user)
 
+	w.Header().Set("ETag", userETag(int(user.Version)))
+	w.Header().Set("Content-Type", "application/json")
+
 	err = json.NewEncoder(w).Encode(userResponse)
 	if err != nil {
 		log.Println(err)
@@ -73,60 +69,62 @@ func (h UserHandler) GetUser(w http.ResponseWriter, r *http.Request, rawUserID U
 
 func (h UserHandler) PostUser(w http.ResponseWriter, r *http.Request) {
 	var postUserRequest PostUserRequest
-	err := json.NewDecoder(r.Body).Decode(&postUserRequest)
+	err := decodeRequestBody(r, &postUserRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	type createRequest struct {
-		Email     string `validate:"required,email"`
-		FirstName string `validate:"required_without=LastName"`
-		LastName  string `validate:"required_without=FirstName"`
+		Email     string  `json:"email" validate:"required,email_address"`
+		FirstName *string `json:"first_name" validate:"required_without=LastName"`
+		LastName  *string `json:"last_name" validate:"required_without=FirstName"`
 	}
 
-	validate := validator.New()
+	validate := newValidator()
 	err = validate.Struct(createRequest(postUserRequest))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
+		return
+	}
+
+	emailAddress, err := NewEmailAddress(postUserRequest.Email)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	user := &models.User{
-		FirstName: postUserRequest.FirstName,
-		LastName:  postUserRequest.LastName,
+	user := &models.User{}
+
+	if postUserRequest.FirstName != nil {
+		user.FirstName = *postUserRequest.FirstName
+	}
+
+	if postUserRequest.LastName != nil {
+		user.LastName = *postUserRequest.LastName
 	}
-	email := &models.Email{Address: postUserRequest.Email}
+	email := &models.Email{Address: emailAddress.String()}
 
 	err = h.storage.Add(r.Context(), user, email)
 	if err != nil {
-		log.Println(err)
-		if errors.Is(err, ErrEmailAlreadyExists) {
-			w.WriteHeader(http.StatusBadRequest)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
-func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
-	userID, err := strconv.Atoi(string(rawUserID))
+func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request, rawUserID UserID, params PatchUserParams) {
+	userID, err := parseUserID(rawUserID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	var patchUserRequest PatchUserRequest
-	err = json.NewDecoder(r.Body).Decode(&patchUserRequest)
+	err = decodeRequestBody(r, &patchUserRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -137,22 +135,26 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request, rawUserID
 	}
 
 	type updateRequest struct {
-		FirstName *string `validate:"required_without=LastName"`
-		LastName  *string `validate:"required_without=FirstName"`
+		FirstName *string `json:"first_name" validate:"required_without=LastName"`
+		LastName  *string `json:"last_name" validate:"required_without=FirstName"`
 	}
 
-	validate := validator.New()
+	validate := newValidator()
 	err = validate.Struct(updateRequest(patchUserRequest))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	user, err := h.storage.ByID(r.Context(), userID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
+		return
+	}
+
+	err = checkIfMatch(ifMatchFromParams(params), userETag(int(user.Version)))
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -165,14 +167,13 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request, rawUserID
 	}
 
 	if displayName(user.FirstName, user.LastName) == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, errNameRequired)
 		return
 	}
 
 	err = h.storage.Update(r.Context(), user)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -180,17 +181,15 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request, rawUserID
 }
 
 func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
-	userID, err := strconv.Atoi(string(rawUserID))
+	userID, err := parseUserID(rawUserID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	err = h.storage.Delete(r.Context(), userID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
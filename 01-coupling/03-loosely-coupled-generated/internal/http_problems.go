@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Problem types returned in the "type" field of problem details (RFC 7807).
+const (
+	problemTypeInvalidRequestBody   = "/problems/invalid-request-body"
+	problemTypeInvalidUserID        = "/problems/invalid-user-id"
+	problemTypeValidationFailed     = "/problems/validation-failed"
+	problemTypeUserNotFound         = "/problems/user-not-found"
+	problemTypeEmailAlreadyExists   = "/problems/email-already-exists"
+	problemTypePreconditionRequired = "/problems/precondition-required"
+	problemTypePreconditionFailed   = "/problems/precondition-failed"
+	problemTypeInvalidResponse      = "/problems/invalid-response"
+)
+
+var (
+	errInvalidRequestBody   = errors.New("invalid request body")
+	errInvalidUserID        = errors.New("invalid user ID")
+	errPreconditionRequired = errors.New("the If-Match header with the user's ETag is required")
+	errPreconditionFailed   = errors.New("the user has been modified since it was fetched")
+	errNameRequired         = errors.New("either first name or last name is required")
+)
+
+func decodeRequestBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+	}
+
+	return nil
+}
+
+func parseUserID(rawUserID UserID) (int, error) {
+	userID, err := strconv.Atoi(string(rawUserID))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidUserID, err)
+	}
+
+	return userID, nil
+}
+
+// newValidator returns a validator reporting fields by their JSON names.
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+
+	// "email_address" accepts the same addresses as NewEmailAddress, unlike the built-in "email" rule.
+	err := validate.RegisterValidation("email_address", func(fl validator.FieldLevel) bool {
+		_, err := NewEmailAddress(fl.Field().String())
+		return err == nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return validate
+}
+
+// writeError logs the error and responds with the matching problem details.
+// Errors not known to the HTTP layer become an Internal Server Error without details.
+func writeError(w http.ResponseWriter, err error) {
+	log.Println(err)
+
+	problem := problemFromError(err)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+
+	err = json.NewEncoder(w).Encode(problem)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func problemFromError(err error) Problem {
+	var validationErrors validator.ValidationErrors
+	var fieldsErr fieldsValidationError
+
+	switch {
+	case errors.Is(err, errInvalidRequestBody):
+		return newProblem(problemTypeInvalidRequestBody, "Invalid request body", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errInvalidUserID):
+		return newProblem(problemTypeInvalidUserID, "Invalid user ID", http.StatusBadRequest, err.Error())
+	case errors.As(err, &validationErrors):
+		return newValidationProblem("the request has invalid fields", fieldErrorsFromValidation(validationErrors))
+	case errors.As(err, &fieldsErr):
+		return newValidationProblem("the request has invalid fields", fieldsErr.fieldErrors)
+	case errors.Is(err, errNameRequired):
+		return newValidationProblem(err.Error(), []FieldError{
+			{Field: "first_name", Message: err.Error()},
+			{Field: "last_name", Message: err.Error()},
+		})
+	case errors.Is(err, ErrEmailRequired), errors.Is(err, ErrInvalidEmail):
+		return newValidationProblem(err.Error(), []FieldError{
+			{Field: "email", Message: err.Error()},
+		})
+	case errors.Is(err, ErrUserNotFound):
+		return newProblem(problemTypeUserNotFound, "User not found", http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrEmailAlreadyExists):
+		return newProblem(problemTypeEmailAlreadyExists, "E-mail already exists", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errPreconditionRequired):
+		return newProblem(problemTypePreconditionRequired, "Precondition required", http.StatusPreconditionRequired, err.Error())
+	case errors.Is(err, errPreconditionFailed), errors.Is(err, ErrUserModified):
+		return newProblem(problemTypePreconditionFailed, "Precondition failed", http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, errInvalidResponse):
+		return newProblem(problemTypeInvalidResponse, "Invalid response", http.StatusInternalServerError, err.Error())
+	default:
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+		}
+	}
+}
+
+func newProblem(problemType string, title string, status int, detail string) Problem {
+	return Problem{
+		Type:   problemType,
+		Title:  title,
+		Status: status,
+		Detail: &detail,
+	}
+}
+
+func newValidationProblem(detail string, fieldErrors []FieldError) Problem {
+	problem := newProblem(problemTypeValidationFailed, "Validation failed", http.StatusBadRequest, detail)
+	problem.Errors = &fieldErrors
+
+	return problem
+}
+
+func fieldErrorsFromValidation(validationErrors validator.ValidationErrors) []FieldError {
+	var fieldErrors []FieldError
+	for _, e := range validationErrors {
+		// The namespace starts with the validated struct's name, e.g. "createRequest.email".
+		field := strings.SplitN(e.Namespace(), ".", 2)[1]
+
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   field,
+			Message: validationMessage(e),
+		})
+	}
+
+	return fieldErrors
+}
+
+func validationMessage(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "email", "email_address":
+		return "must be a valid e-mail address"
+	default:
+		return fmt.Sprintf("failed on the %s rule", e.Tag())
+	}
+}
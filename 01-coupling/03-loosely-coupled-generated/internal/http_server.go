@@ -27,7 +27,7 @@ type ServerInterface interface {
 	GetUser(w http.ResponseWriter, r *http.Request, userID UserID)
 	// Update user
 	// (PATCH /users/{userID})
-	PatchUser(w http.ResponseWriter, r *http.Request, userID UserID)
+	PatchUser(w http.ResponseWriter, r *http.Request, userID UserID, params PatchUserParams)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -135,8 +135,32 @@ func (siw *ServerInterfaceWrapper) PatchUser(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PatchUserParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			http.Error(w, fmt.Sprintf("Expected one value for If-Match, got %d", n), http.StatusBadRequest)
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-Match", runtime.ParamLocationHeader, valueList[0], &IfMatch)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid format for parameter If-Match: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		params.IfMatch = &IfMatch
+
+	}
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.PatchUser(w, r, userID)
+		siw.Handler.PatchUser(w, r, userID, params)
 	}
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -0,0 +1,111 @@
+// Package internal provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/deepmap/oapi-codegen version v1.8.2 DO NOT EDIT.
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Base64 encoded, gzipped, json marshaled Swagger object
+var swaggerSpec = []string{
+
+	"H4sIAAAAAAAC/8RX227jNhD9lQHbhxbVxk52gV34rc1la/SyQZrtSxAUtDiyuJBIlRxtahj694KkZOtC",
+	"O2nrReEXi5fhOXNmhsMtS3VZaYWKLFtsWcUNL5HQ+C+Z/cIpzd1fgTY1siKpFVuw63u+Bp0B5Qi1RQMG",
+	"qTYKBaw28P76/gzu8M8aLVl4kpTrmkAScINg8BOmhMKPw5uLd3BrMNVKSGfab5MGRQJcCTB9I8AV6JoE",
+	"d7u1wtbC+cXQwg2XBYozljDpkObIBRqWMMVLZAu2zF4FTgmzaY4ld+RoU7k5S0aqNWuahDlSy6sp8Y+O",
+	"7PKqs15xyve2200JMy0LtiBT47GTGrfYVlpZ9B6/NXpVYOn+ploRKnJ/eVUVMuUOw6wKK777ZB2gbc/2",
+	"1wYztmBfzfaSzsKsnXV2/YkjLY3RxpNuFztb1yWXxV2LzA1URldoSAaYXAiD1kYYJawysuRm05tbaV0g",
+	"V6xp+q552FnZ73lMuj165eLE2buRWIgAcoIjc3NRFCVay9cYV7cPIpjYb4hBuHUR46RvozoGxFj6I8TB",
+	"OGZu3Bz4uWQKtOAHN/7MD+5rYii1pRFIXhQfMrZ4GMNFJ28krV/58QjK/4FfX6SANyLNlnG1aSkOVd3h",
+	"fWyS4dwe0WPjfkk/64YI2wkQSFwWFr65u7mEt+/mb79lycilYUnEp39VBVc+d8FWmMpMpkAaKJcWdJrW",
+	"xqBKsaumbXrHPIkuB+z0hKX6zAspwEey7Qy1tZMlTBKW9rkC0cuyfWxxY/jGfVviVEeO/vH+/hbCJKRa",
+	"9ISUinCNwZikIqL+b7k2BLYuXeaP6IO3EvFBGJhU5bslGMwweFIKVCSzjVTrF9gcRVq3yGPeEY8VhZBq",
+	"hyqkkLYq+GYX+VM1XUj7pS/SZ1iQIxINM3RynOyXyZ44g/Q87hop2OCY/uZkSHhH75DjbN9zL3LAwN0T",
+	"/g6rVJmO39h2p+j++zMaG1bMz86dQV2h4pVkC/b6bH722iU4p9zjmtW27YjW6OuqU9qn9FKwBXuP1Fkd",
+	"XOYX8/mRi/yfXeBDp0Wu8Q8/MT+W8bqgQ9Z28HoNQcLaHAxMgBcFBL7uMtc2Qri7adpWBy39oMXmZFzH",
+	"F1kz7ZMu5udTqS8NuubwBH74XgjgoPDJe8JPhhiYbUOT14TTCyScuufKj7cO6nfUD3E0+yWzYJ01jxO+",
+	"b6Z8f9Vw2fr7v1MOoFu+ydFAPyGr0ybIM/mRtK+B0OHe8/XUpb+HqtB/2yTutl4hWFQEUkH3goCnHBXU",
+	"leDkbhpJR58UzYmSE6xU62KvU9U90EYZ2nWs/1qr5NmV3eswyPoFysC4647WgS+dFx+dwJ2/m6b5ewBQ",
+	"BtTGMg8AAA==",
+}
+
+// GetSwagger returns the content of the embedded swagger specification file
+// or error if failed to decode
+func decodeSpec() ([]byte, error) {
+	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error base64 decoding spec: %s", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %s", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %s", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var rawSpec = decodeSpecCached()
+
+// a naive cached of a decoded swagger spec
+func decodeSpecCached() func() ([]byte, error) {
+	data, err := decodeSpec()
+	return func() ([]byte, error) {
+		return data, err
+	}
+}
+
+// Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
+func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
+	var res = make(map[string]func() ([]byte, error))
+	if len(pathToFile) > 0 {
+		res[pathToFile] = rawSpec
+	}
+
+	return res
+}
+
+// GetSwagger returns the Swagger specification corresponding to the generated code
+// in this file. The external references of Swagger specification are resolved.
+// The logic of resolving external references is tightly connected to "import-mapping" feature.
+// Externally referenced files must be embedded in the corresponding golang packages.
+// Urls can be supported but this task was out of the scope.
+func GetSwagger() (swagger *openapi3.T, err error) {
+	var resolvePath = PathToRawSpec("")
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
+		var pathToFile = url.String()
+		pathToFile = path.Clean(pathToFile)
+		getSpec, ok := resolvePath[pathToFile]
+		if !ok {
+			err1 := fmt.Errorf("path not found: %s", pathToFile)
+			return nil, err1
+		}
+		return getSpec()
+	}
+	var specData []byte
+	specData, err = rawSpec()
+	if err != nil {
+		return
+	}
+	swagger, err = loader.LoadFromData(specData)
+	if err != nil {
+		return
+	}
+	return
+}
@@ -9,6 +9,12 @@ type EmailResponse struct {
 	Primary bool   `json:"primary"`
 }
 
+// FieldError defines model for FieldError.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // PatchUserRequest defines model for PatchUserRequest.
 type PatchUserRequest struct {
 	// First name
@@ -20,14 +26,34 @@ type PatchUserRequest struct {
 
 // PostUserRequest defines model for PostUserRequest.
 type PostUserRequest struct {
+	// Embedded fields due to inline allOf schema
 	// E-mail
 	Email string `json:"email"`
 
 	// First name
-	FirstName string `json:"first_name"`
+	FirstName *string `json:"first_name,omitempty"`
 
 	// Last name
-	LastName string `json:"last_name"`
+	LastName *string `json:"last_name,omitempty"`
+	// Embedded fields due to inline allOf schema
+}
+
+// Problem details (RFC 7807)
+type Problem struct {
+	// Explanation specific to this occurrence of the problem
+	Detail *string `json:"detail,omitempty"`
+
+	// Invalid fields of the request
+	Errors *[]FieldError `json:"errors,omitempty"`
+
+	// HTTP status code
+	Status int `json:"status"`
+
+	// Short summary of the problem type
+	Title string `json:"title"`
+
+	// URI reference identifying the problem type
+	Type string `json:"type"`
 }
 
 // UserResponse defines model for UserResponse.
@@ -42,6 +68,9 @@ type UserResponse struct {
 // UsersResponse defines model for UsersResponse.
 type UsersResponse []UserResponse
 
+// IfMatch defines model for ifMatch.
+type IfMatch string
+
 // UserID defines model for userID.
 type UserID string
 
@@ -51,6 +80,12 @@ type PostUserJSONBody PostUserRequest
 // PatchUserJSONBody defines parameters for PatchUser.
 type PatchUserJSONBody PatchUserRequest
 
+// PatchUserParams defines parameters for PatchUser.
+type PatchUserParams struct {
+	// ETag of the user returned by GET. Requests without it are rejected with 428 Precondition Required, and requests with an outdated one with 412 Precondition Failed.
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
 // PostUserJSONRequestBody defines body for PostUser for application/json ContentType.
 type PostUserJSONRequestBody PostUserJSONBody
 
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+var errInvalidResponse = errors.New("the response doesn't match the API spec")
+
+// OpenAPIValidatorOptions configures the middleware returned by NewOpenAPIValidator.
+type OpenAPIValidatorOptions struct {
+	// ValidateResponses replaces responses not matching the spec with an Internal Server Error.
+	// It's meant for tests, as it buffers all responses.
+	ValidateResponses bool
+}
+
+// NewOpenAPIValidator returns a middleware validating requests against the spec embedded from openapi.yml.
+// Invalid requests get the same problem details as the ones returned by handlers.
+func NewOpenAPIValidator(options OpenAPIValidatorOptions) (MiddlewareFunc, error) {
+	spec, err := GetSwagger()
+	if err != nil {
+		return nil, err
+	}
+
+	router, err := legacy.NewRouter(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// The chi router already matched the request, so a missing route means the spec and the generated code differ.
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				writeError(w, fmt.Errorf("route not found in the API spec: %w", err))
+				return
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					MultiError: true,
+				},
+			}
+
+			err = openapi3filter.ValidateRequest(r.Context(), input)
+			if err != nil {
+				writeError(w, requestValidationError(err))
+				return
+			}
+
+			if !options.ValidateResponses {
+				next(w, r)
+				return
+			}
+
+			rw := &bufferedResponseWriter{
+				header: http.Header{},
+				status: http.StatusOK,
+			}
+
+			next(rw, r)
+
+			responseInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 rw.status,
+				Header:                 rw.header,
+				Options: &openapi3filter.Options{
+					IncludeResponseStatus: true,
+				},
+			}
+
+			err = openapi3filter.ValidateResponse(r.Context(), responseInput.SetBodyBytes(rw.body.Bytes()))
+			if err != nil {
+				// The first line is enough, the rest dumps the whole schema.
+				reason := strings.SplitN(err.Error(), "\n", 2)[0]
+				writeError(w, fmt.Errorf("%w: %s", errInvalidResponse, reason))
+				return
+			}
+
+			for k, v := range rw.header {
+				w.Header()[k] = v
+			}
+
+			w.WriteHeader(rw.status)
+
+			_, err = w.Write(rw.body.Bytes())
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}, nil
+}
+
+// fieldsValidationError is returned for requests with invalid fields, so they get the same problem details
+// as validation errors reported by the handlers.
+type fieldsValidationError struct {
+	fieldErrors []FieldError
+}
+
+func (e fieldsValidationError) Error() string {
+	var fields []string
+	for _, f := range e.fieldErrors {
+		fields = append(fields, f.Field+" "+f.Message)
+	}
+
+	return "invalid fields: " + strings.Join(fields, ", ")
+}
+
+// requestValidationError translates kin-openapi errors to the errors known to problemFromError.
+func requestValidationError(err error) error {
+	var fieldErrors []FieldError
+	for _, e := range flattenErrors(err) {
+		var requestErr *openapi3filter.RequestError
+		if !errors.As(e, &requestErr) {
+			return err
+		}
+
+		if requestErr.Parameter != nil {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   requestErr.Parameter.Name,
+				Message: parameterErrorMessage(requestErr),
+			})
+			continue
+		}
+
+		schemaErrors := schemaErrorsOf(requestErr.Err)
+		if len(schemaErrors) == 0 {
+			// Not a JSON matching the spec, e.g. a syntax error or a wrong Content-Type.
+			return fmt.Errorf("%w: %v", errInvalidRequestBody, requestErr)
+		}
+
+		for _, schemaErr := range schemaErrors {
+			field := strings.Join(schemaErr.JSONPointer(), ".")
+
+			if field == "" && schemaErr.SchemaField == "anyOf" {
+				alternatives := anyOfRequiredFields(schemaErr.Schema)
+				if len(alternatives) == 0 {
+					return fmt.Errorf("%w: %v", errInvalidRequestBody, requestErr)
+				}
+
+				// E.g., either first_name or last_name is required.
+				message := fmt.Sprintf("either %s is required", strings.Join(alternatives, " or "))
+				for _, alternative := range alternatives {
+					fieldErrors = append(fieldErrors, FieldError{
+						Field:   alternative,
+						Message: message,
+					})
+				}
+				continue
+			}
+
+			if field == "" {
+				return fmt.Errorf("%w: %v", errInvalidRequestBody, requestErr)
+			}
+
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   field,
+				Message: schemaErrorMessage(schemaErr),
+			})
+		}
+	}
+
+	return fieldsValidationError{fieldErrors: fieldErrors}
+}
+
+func parameterErrorMessage(err *openapi3filter.RequestError) string {
+	if errors.Is(err.Err, openapi3filter.ErrInvalidRequired) {
+		return "is required"
+	}
+
+	return err.Error()
+}
+
+func schemaErrorMessage(err *openapi3.SchemaError) string {
+	switch err.SchemaField {
+	case "required":
+		return "is required"
+	case "type":
+		return fmt.Sprintf("must be of type %s", err.Schema.Type)
+	default:
+		return err.Reason
+	}
+}
+
+// anyOfRequiredFields returns the fields required by the anyOf alternatives, if each requires a single field.
+func anyOfRequiredFields(schema *openapi3.Schema) []string {
+	var fields []string
+	for _, alternative := range schema.AnyOf {
+		if alternative.Value == nil || len(alternative.Value.Required) != 1 {
+			return nil
+		}
+
+		fields = append(fields, alternative.Value.Required[0])
+	}
+
+	return fields
+}
+
+// schemaErrorsOf returns the schema errors in err. Errors of allOf schemas are replaced with
+// the errors of their parts, so each field is reported on its own.
+func schemaErrorsOf(err error) []*openapi3.SchemaError {
+	var schemaErrors []*openapi3.SchemaError
+	for _, e := range flattenErrors(err) {
+		var schemaErr *openapi3.SchemaError
+		if !errors.As(e, &schemaErr) {
+			continue
+		}
+
+		if schemaErr.SchemaField == "allOf" && schemaErr.Origin != nil {
+			schemaErrors = append(schemaErrors, schemaErrorsOf(schemaErr.Origin)...)
+			continue
+		}
+
+		schemaErrors = append(schemaErrors, schemaErr)
+	}
+
+	return schemaErrors
+}
+
+// flattenErrors returns the errors collected in (possibly nested) openapi3.MultiError.
+func flattenErrors(err error) []error {
+	multiErr, ok := err.(openapi3.MultiError)
+	if !ok {
+		if err == nil {
+			return nil
+		}
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range multiErr {
+		errs = append(errs, flattenErrors(e)...)
+	}
+
+	return errs
+}
+
+// bufferedResponseWriter keeps the response in memory, so it can be validated before it's sent.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
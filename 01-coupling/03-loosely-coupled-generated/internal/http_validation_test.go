@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPIValidator_requests(t *testing.T) {
+	handler := newValidatedTestHandler(t, serverStub{})
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		ContentType        string
+		Body               string
+		ExpectedStatusCode int
+		ExpectedType       string
+		ExpectedFields     []string
+	}{
+		{
+			Name:               "valid",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			ContentType:        "application/json",
+			Body:               `{"first_name": "John", "last_name": "Doe", "email": "john@example.com"}`,
+			ExpectedStatusCode: http.StatusCreated,
+		},
+		{
+			Name:               "missing_fields",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			ContentType:        "application/json",
+			Body:               `{"first_name": "John"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeValidationFailed,
+			ExpectedFields:     []string{"email"},
+		},
+		{
+			Name:               "missing_name",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			ContentType:        "application/json",
+			Body:               `{"email": "john@example.com"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeValidationFailed,
+			ExpectedFields:     []string{"first_name", "last_name"},
+		},
+		{
+			Name:               "last_name_only",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			ContentType:        "application/json",
+			Body:               `{"last_name": "Doe", "email": "john@example.com"}`,
+			ExpectedStatusCode: http.StatusCreated,
+		},
+		{
+			Name:               "invalid_type",
+			Method:             http.MethodPatch,
+			Path:               "/users/1",
+			ContentType:        "application/json",
+			Body:               `{"first_name": 1}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeValidationFailed,
+			ExpectedFields:     []string{"first_name"},
+		},
+		{
+			Name:               "invalid_json",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			ContentType:        "application/json",
+			Body:               `{"first_name": `,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeInvalidRequestBody,
+		},
+		{
+			Name:               "invalid_content_type",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			ContentType:        "text/plain",
+			Body:               `{"first_name": "John", "last_name": "Doe", "email": "john@example.com"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeInvalidRequestBody,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Body))
+			req.Header.Set("Content-Type", tc.ContentType)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.ExpectedType == "" {
+				return
+			}
+
+			var problem Problem
+			err := json.NewDecoder(rec.Body).Decode(&problem)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if problem.Type != tc.ExpectedType {
+				t.Errorf("expected problem type %q, got %q", tc.ExpectedType, problem.Type)
+			}
+
+			var fields []string
+			if problem.Errors != nil {
+				for _, e := range *problem.Errors {
+					fields = append(fields, e.Field)
+				}
+			}
+
+			if strings.Join(fields, ",") != strings.Join(tc.ExpectedFields, ",") {
+				t.Errorf("expected invalid fields %v, got %v", tc.ExpectedFields, fields)
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidator_responses(t *testing.T) {
+	testCases := []struct {
+		Name               string
+		Body               string
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "valid",
+			Body:               `[{"id": 1, "first_name": "John", "last_name": "Doe", "display_name": "John Doe", "emails": []}]`,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "missing_fields",
+			Body:               `[{"id": 1}]`,
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			Name:               "null_instead_of_array",
+			Body:               `null`,
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			handler := newValidatedTestHandler(t, serverStub{getUsersBody: tc.Body})
+
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.ExpectedStatusCode == http.StatusOK && rec.Body.String() != tc.Body {
+				t.Errorf("expected body %s, got %s", tc.Body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func newValidatedTestHandler(t *testing.T, si ServerInterface) http.Handler {
+	t.Helper()
+
+	validator, err := NewOpenAPIValidator(OpenAPIValidatorOptions{
+		ValidateResponses: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return HandlerWithOptions(si, ChiServerOptions{
+		BaseRouter:  chi.NewRouter(),
+		Middlewares: []MiddlewareFunc{validator},
+	})
+}
+
+// serverStub responds with the status codes from the spec, so only the validator can reject requests.
+type serverStub struct {
+	getUsersBody string
+}
+
+func (s serverStub) GetUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(s.getUsersBody))
+}
+
+func (s serverStub) PostUser(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s serverStub) DeleteUser(w http.ResponseWriter, r *http.Request, userID UserID) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s serverStub) GetUser(w http.ResponseWriter, r *http.Request, userID UserID) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func (s serverStub) PatchUser(w http.ResponseWriter, r *http.Request, userID UserID, params PatchUserParams) {
+	w.WriteHeader(http.StatusNoContent)
+}
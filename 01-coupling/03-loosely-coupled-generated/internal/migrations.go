@@ -0,0 +1,15 @@
+package internal
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	return migrate.New(db, migrate.MySQL, migrationFiles, "migrations")
+}
@@ -1,6 +1,5 @@
-CREATE DATABASE loosely_coupled_generated;
-
-CREATE TABLE `loosely_coupled_generated`.`users` (
+-- IF NOT EXISTS keeps databases created by the old init.sql working.
+CREATE TABLE IF NOT EXISTS `users` (
   `id` bigint NOT NULL AUTO_INCREMENT,
   `first_name` longtext NOT NULL,
   `last_name` longtext NOT NULL,
@@ -8,10 +7,11 @@ CREATE TABLE `loosely_coupled_generated`.`users` (
   `last_ip` longtext,
   `created_at` datetime(3) DEFAULT NULL,
   `updated_at` datetime(3) DEFAULT NULL,
+  `version` bigint NOT NULL DEFAULT 1,
   PRIMARY KEY (`id`)
 );
 
-CREATE TABLE `loosely_coupled_generated`.`emails` (
+CREATE TABLE IF NOT EXISTS `emails` (
   `id` bigint NOT NULL AUTO_INCREMENT,
   `address` varchar(256) NOT NULL,
   `primary` tinyint(1) NOT NULL,
@@ -20,4 +20,4 @@ CREATE TABLE `loosely_coupled_generated`.`emails` (
   UNIQUE KEY `idx_emails_address` (`address`),
   KEY `fk_users_emails` (`user_id`),
   CONSTRAINT `fk_users_emails` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
-);  
+);
@@ -0,0 +1,2 @@
+-- The columns are part of 0001_create_users for new databases, so they're kept.
+DO 0;
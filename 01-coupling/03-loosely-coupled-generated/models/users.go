@@ -31,6 +31,7 @@ type User struct {
 	LastIP       null.String `boil:"last_ip" json:"last_ip,omitempty" toml:"last_ip" yaml:"last_ip,omitempty"`
 	CreatedAt    null.Time   `boil:"created_at" json:"created_at,omitempty" toml:"created_at" yaml:"created_at,omitempty"`
 	UpdatedAt    null.Time   `boil:"updated_at" json:"updated_at,omitempty" toml:"updated_at" yaml:"updated_at,omitempty"`
+	Version      int64       `boil:"version" json:"version" toml:"version" yaml:"version"`
 
 	R *userR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L userL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -44,6 +45,7 @@ var UserColumns = struct {
 	LastIP       string
 	CreatedAt    string
 	UpdatedAt    string
+	Version      string
 }{
 	ID:           "id",
 	FirstName:    "first_name",
@@ -52,6 +54,7 @@ var UserColumns = struct {
 	LastIP:       "last_ip",
 	CreatedAt:    "created_at",
 	UpdatedAt:    "updated_at",
+	Version:      "version",
 }
 
 var UserTableColumns = struct {
@@ -62,6 +65,7 @@ var UserTableColumns = struct {
 	LastIP       string
 	CreatedAt    string
 	UpdatedAt    string
+	Version      string
 }{
 	ID:           "users.id",
 	FirstName:    "users.first_name",
@@ -70,6 +74,7 @@ var UserTableColumns = struct {
 	LastIP:       "users.last_ip",
 	CreatedAt:    "users.created_at",
 	UpdatedAt:    "users.updated_at",
+	Version:      "users.version",
 }
 
 // Generated where
@@ -128,6 +133,7 @@ var UserWhere = struct {
 	LastIP       whereHelpernull_String
 	CreatedAt    whereHelpernull_Time
 	UpdatedAt    whereHelpernull_Time
+	Version      whereHelperint64
 }{
 	ID:           whereHelperint64{field: "`users`.`id`"},
 	FirstName:    whereHelperstring{field: "`users`.`first_name`"},
@@ -136,6 +142,7 @@ var UserWhere = struct {
 	LastIP:       whereHelpernull_String{field: "`users`.`last_ip`"},
 	CreatedAt:    whereHelpernull_Time{field: "`users`.`created_at`"},
 	UpdatedAt:    whereHelpernull_Time{field: "`users`.`updated_at`"},
+	Version:      whereHelperint64{field: "`users`.`version`"},
 }
 
 // UserRels is where relationship names are stored.
@@ -159,9 +166,9 @@ func (*userR) NewStruct() *userR {
 type userL struct{}
 
 var (
-	userAllColumns            = []string{"id", "first_name", "last_name", "password_hash", "last_ip", "created_at", "updated_at"}
+	userAllColumns            = []string{"id", "first_name", "last_name", "password_hash", "last_ip", "created_at", "updated_at", "version"}
 	userColumnsWithoutDefault = []string{"first_name", "last_name", "password_hash", "last_ip", "created_at", "updated_at"}
-	userColumnsWithDefault    = []string{"id"}
+	userColumnsWithDefault    = []string{"id", "version"}
 	userPrimaryKeyColumns     = []string{"id"}
 )
 
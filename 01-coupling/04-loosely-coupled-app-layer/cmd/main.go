@@ -1,28 +1,158 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"expvar"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/04-loosely-coupled-app-layer/internal"
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/04-loosely-coupled-app-layer/internal/userspb"
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate"
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	db, err := sql.Open("mysql", "root@tcp(mysql)/loosely_coupled_app_layer?parseTime=true")
+	dsn := os.Getenv("DATABASE_DSN")
+	if dsn == "" {
+		dsn = "root@tcp(mysql)/loosely_coupled_app_layer?parseTime=true&multiStatements=true"
+	}
+
+	storage, webhookStorage, migrator, err := newRepositories(dsn)
 	if err != nil {
 		log.Fatal("failed to connect to the database")
 	}
 
-	storage := internal.NewUserStorage(db)
-	h := internal.NewUserHandler(storage)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = migrator.RunCommand(context.Background(), os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err = migrator.Up(context.Background())
+	if err != nil {
+		log.Fatal("failed to apply migrations: ", err)
+	}
+
+	secret := []byte(os.Getenv("EMAIL_VERIFICATION_SECRET"))
+	if len(secret) == 0 {
+		// Tokens won't survive a restart, but it's enough for local runs.
+		log.Println("EMAIL_VERIFICATION_SECRET not set, using a random secret")
+
+		secret = make([]byte, 32)
+		_, err = rand.Read(secret)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	verificationTokens := internal.NewVerificationTokens(secret, 24*time.Hour)
+
+	// E-mails are written to MAILER_FILE, or to stdout if it's not set.
+	mailerOutput := os.Stdout
+	if path := os.Getenv("MAILER_FILE"); path != "" {
+		mailerOutput, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	mailer := internal.NewWriterMailer(mailerOutput)
+
+	publisher, err := newEventsPublisher(os.Getenv("REDIS_ADDR"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	eventBus, err := internal.NewEventBus(publisher)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The storage stores user lifecycle events as webhook deliveries with the changes, and the dispatcher sends them.
+	go internal.NewWebhookDispatcher(webhookStorage).Run(context.Background(), time.Second)
+
+	// Deleted users can be restored for DELETED_USERS_RETENTION (30 days by default), and are purged afterwards.
+	retention := 30 * 24 * time.Hour
+	if value := os.Getenv("DELETED_USERS_RETENTION"); value != "" {
+		retention, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("invalid DELETED_USERS_RETENTION: ", err)
+		}
+	}
+
+	go internal.NewUserPurger(storage, retention).Run(context.Background(), time.Hour)
+
+	// Users read by ID are cached for a minute, so changes made by other instances can be seen that late.
+	cache := internal.NewCachedUserRepository(storage, time.Minute, 10000)
+	expvar.Publish("user_cache", expvar.Func(func() interface{} {
+		return cache.Stats()
+	}))
+
+	// Debug endpoints are served on a separate listener, so they're not exposed with the API.
+	debugAddr := os.Getenv("DEBUG_ADDR")
+	if debugAddr == "" {
+		debugAddr = "localhost:6060"
+	}
+
+	debugMux := http.NewServeMux()
+	debugMux.Handle("/debug/vars", expvar.Handler())
+
+	go func() {
+		err := http.ListenAndServe(debugAddr, debugMux)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	h := internal.HTTPHandler{
+		UserHandler:    internal.NewUserHandler(cache, verificationTokens, mailer, eventBus),
+		WebhookHandler: internal.NewWebhookHandler(webhookStorage),
+	}
+
+	// The gRPC API is served alongside HTTP, for internal consumers.
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(internal.GRPCOrganizationInterceptor))
+	userspb.RegisterUsersServer(grpcServer, internal.NewUserGRPCServer(cache, verificationTokens, mailer, eventBus))
+
+	grpcListener, err := net.Listen("tcp", ":9090")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go func() {
+		err := grpcServer.Serve(grpcListener)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}()
 
 	r := chi.NewRouter()
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Logger)
+	// The HTTP and GraphQL APIs work on users of the organization from the X-Organization-ID header.
+	r.Use(internal.OrganizationMiddleware)
+
+	graphQLHandler, err := internal.NewGraphQLHandler(cache, verificationTokens, mailer, eventBus)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r.Handle("/graphql", graphQLHandler)
 
 	handler := internal.HandlerFromMux(h, r)
 
@@ -31,3 +161,43 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// newEventsPublisher publishes events to Redis Streams. Without redisAddr, events stay in memory,
+// and as nothing subscribes to them, they're dropped.
+func newEventsPublisher(redisAddr string) (message.Publisher, error) {
+	if redisAddr == "" {
+		log.Println("REDIS_ADDR not set, events won't be published")
+		return gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{}), nil
+	}
+
+	return internal.NewRedisPublisher(redisAddr)
+}
+
+// newRepositories picks the storage based on the DSN: PostgreSQL for postgres:// URLs, MySQL otherwise.
+func newRepositories(dsn string) (internal.UserRepository, internal.WebhookRepository, *migrate.Migrator, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		migrator, err := internal.NewPostgresMigrator(db)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return internal.NewPostgresUserStorage(db), internal.NewPostgresWebhookStorage(db), migrator, nil
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	migrator, err := internal.NewMySQLMigrator(db)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return internal.NewUserStorage(db), internal.NewMySQLWebhookStorage(db), migrator, nil
+}
@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheLoadTimeout limits loads of users, as they don't stop when the callers waiting for them give up.
+const cacheLoadTimeout = 10 * time.Second
+
+// CachedUserRepository is a read-through cache of ByID in front of another UserRepository.
+//
+// Users are kept for the TTL, and the least recently used ones are evicted when the cache is full.
+// They're cached per organization from the context, so a cached user is never returned to another organization.
+// Methods changing a user invalidate it, and concurrent misses of the same user load it only once.
+// All and PersonalData always read the storage, as lists can't be invalidated per user, and data exports must be fresh.
+//
+// The cache is kept in memory, so with more instances of the service, changes made by the others
+// can be seen only after the TTL.
+type CachedUserRepository struct {
+	storage UserRepository
+	ttl     time.Duration
+	size    int
+	now     func() time.Time
+
+	lock    sync.Mutex
+	entries map[cacheKey]*list.Element
+	// lru has the most recently used entries at the front.
+	lru *list.List
+	// generation is bumped on each invalidation, so users loaded before it aren't cached.
+	generation uint64
+	loads      singleflight.Group
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+type cacheKey struct {
+	organizationID int
+	userID         int
+}
+
+type cacheEntry struct {
+	key       cacheKey
+	user      User
+	expiresAt time.Time
+}
+
+// CacheStats are counted since the cache was created.
+type CacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	// Size is the current number of cached users.
+	Size int `json:"size"`
+}
+
+func NewCachedUserRepository(storage UserRepository, ttl time.Duration, size int) *CachedUserRepository {
+	return &CachedUserRepository{
+		storage: storage,
+		ttl:     ttl,
+		size:    size,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+		entries: map[cacheKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func (c *CachedUserRepository) All(ctx context.Context, filter UserFilter) ([]User, error) {
+	return c.storage.All(ctx, filter)
+}
+
+// ByID returns the cached user, or loads it from the storage. Errors aren't cached.
+func (c *CachedUserRepository) ByID(ctx context.Context, id int) (User, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return User{}, err
+	}
+
+	key := cacheKey{organizationID: organizationID, userID: id}
+
+	user, generation, ok := c.get(key)
+	if ok {
+		c.hits.Add(1)
+		return user, nil
+	}
+
+	c.misses.Add(1)
+
+	// Loads started before an invalidation aren't shared with the ones started after it.
+	loadKey := fmt.Sprintf("%d/%d/%d", organizationID, id, generation)
+
+	loads := c.loads.DoChan(loadKey, func() (interface{}, error) {
+		// The load is shared by all callers, so it can't be canceled with the context of the one that started it.
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+		defer cancel()
+
+		user, err := c.storage.ByID(loadCtx, id)
+		if err != nil {
+			return User{}, err
+		}
+
+		c.set(key, user, generation)
+
+		return user, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return User{}, ctx.Err()
+	case result := <-loads:
+		if result.Err != nil {
+			return User{}, result.Err
+		}
+
+		// The loaded user is shared by all callers, so each gets its own copy.
+		return copyUser(result.Val.(User)), nil
+	}
+}
+
+func (c *CachedUserRepository) Add(ctx context.Context, user User, events ...NewUserEvent) (int, error) {
+	return c.storage.Add(ctx, user, events...)
+}
+
+func (c *CachedUserRepository) AddBatch(ctx context.Context, users []User) ([]error, error) {
+	return c.storage.AddBatch(ctx, users)
+}
+
+// Update invalidates the user even if it fails, as ErrUserModified means the cached user is outdated.
+func (c *CachedUserRepository) Update(ctx context.Context, user User, events ...interface{}) error {
+	err := c.storage.Update(ctx, user, events...)
+	c.invalidate(ctx, user.ID())
+	return err
+}
+
+func (c *CachedUserRepository) AddEmail(ctx context.Context, userID int, email Email) error {
+	err := c.storage.AddEmail(ctx, userID, email)
+	c.invalidate(ctx, userID)
+	return err
+}
+
+func (c *CachedUserRepository) VerifyEmail(ctx context.Context, address string) error {
+	err := c.storage.VerifyEmail(ctx, address)
+	c.invalidateEmail(ctx, address)
+	return err
+}
+
+func (c *CachedUserRepository) Delete(ctx context.Context, id int, now time.Time, events ...interface{}) error {
+	err := c.storage.Delete(ctx, id, now, events...)
+	c.invalidate(ctx, id)
+	return err
+}
+
+func (c *CachedUserRepository) Restore(ctx context.Context, id int, now time.Time) error {
+	err := c.storage.Restore(ctx, id, now)
+	c.invalidate(ctx, id)
+	return err
+}
+
+// Purge doesn't invalidate anything, as only deleted users are purged, and they're never cached.
+func (c *CachedUserRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return c.storage.Purge(ctx, deletedBefore)
+}
+
+func (c *CachedUserRepository) PersonalData(ctx context.Context, id int) (PersonalData, error) {
+	return c.storage.PersonalData(ctx, id)
+}
+
+func (c *CachedUserRepository) Erase(ctx context.Context, id int, now time.Time, events ...interface{}) error {
+	err := c.storage.Erase(ctx, id, now, events...)
+	c.invalidate(ctx, id)
+	return err
+}
+
+func (c *CachedUserRepository) Stats() CacheStats {
+	c.lock.Lock()
+	size := c.lru.Len()
+	c.lock.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+	}
+}
+
+// get returns a copy of the cached user, and the current generation to load it with if it's missing.
+func (c *CachedUserRepository) get(key cacheKey) (User, uint64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return User{}, c.generation, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return User{}, c.generation, false
+	}
+
+	c.lru.MoveToFront(element)
+
+	return copyUser(entry.user), c.generation, true
+}
+
+// set caches the user, unless it was invalidated since the generation was read.
+func (c *CachedUserRepository) set(key cacheKey, user User, generation uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	entry := &cacheEntry{
+		key:       key,
+		user:      copyUser(user),
+		expiresAt: c.now().Add(c.ttl),
+	}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// invalidate removes the user of the context's organization. Without an organization, the storage
+// has changed nothing, so only the generation is bumped.
+func (c *CachedUserRepository) invalidate(ctx context.Context, id int) {
+	organizationID, _ := OrganizationIDFromContext(ctx)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+
+	if element, ok := c.entries[cacheKey{organizationID: organizationID, userID: id}]; ok {
+		c.remove(element)
+	}
+}
+
+// invalidateEmail removes the organization's user owning the address, as VerifyEmail doesn't know the user's ID.
+func (c *CachedUserRepository) invalidateEmail(ctx context.Context, address string) {
+	organizationID, _ := OrganizationIDFromContext(ctx)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+
+		entry := element.Value.(*cacheEntry)
+		for _, e := range entry.user.emails {
+			// Matches the case-insensitive lookup of the storages.
+			if entry.key.organizationID == organizationID && strings.EqualFold(e.Address(), address) {
+				c.remove(element)
+				break
+			}
+		}
+
+		element = next
+	}
+}
+
+// remove drops the entry. The caller must hold the lock.
+func (c *CachedUserRepository) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*cacheEntry)
+	delete(c.entries, entry.key)
+}
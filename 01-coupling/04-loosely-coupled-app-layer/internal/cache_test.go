@@ -0,0 +1,418 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedUserRepository_ByID(t *testing.T) {
+	ctx := testContext()
+
+	storage := &countingUserRepository{UserRepository: NewMemoryUserStorage()}
+	cache := NewCachedUserRepository(storage, time.Minute, 10)
+
+	user := addUser(t, storage.UserRepository, "John", "Doe", "john@example.com")
+
+	for i := 0; i < 3; i++ {
+		cached, err := cache.ByID(ctx, user.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cached.DisplayName() != "John Doe" {
+			t.Fatalf("expected display name %q, got %q", "John Doe", cached.DisplayName())
+		}
+	}
+
+	if calls := storage.calls(); calls != 1 {
+		t.Errorf("expected a single storage call, got %d", calls)
+	}
+
+	expectedStats := CacheStats{Hits: 2, Misses: 1, Size: 1}
+	if stats := cache.Stats(); stats != expectedStats {
+		t.Errorf("expected stats %+v, got %+v", expectedStats, stats)
+	}
+
+	_, err := cache.ByID(ctx, 100)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected %v, got %v", ErrUserNotFound, err)
+	}
+}
+
+func TestCachedUserRepository_ByID_returnsCopies(t *testing.T) {
+	ctx := testContext()
+	cache := NewCachedUserRepository(NewMemoryUserStorage(), time.Minute, 10)
+
+	user := addUser(t, cache, "John", "Doe", "john@example.com")
+
+	_, err := user.AddEmail("jack@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := cache.ByID(ctx, user.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cached.Emails()) != 1 {
+		t.Errorf("expected changes made by callers not to leak into the cache, got %d e-mails", len(cached.Emails()))
+	}
+}
+
+func TestCachedUserRepository_ByID_scopedToOrganization(t *testing.T) {
+	otherCtx := ContextWithOrganizationID(context.Background(), otherTestOrganizationID)
+	cache := NewCachedUserRepository(NewMemoryUserStorage(), time.Minute, 10)
+
+	user := addUser(t, cache, "John", "Doe", "john@example.com")
+
+	_, err := cache.ByID(testContext(), user.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cache.ByID(otherCtx, user.ID())
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected the cached user to be missing in another organization, got %v", err)
+	}
+
+	_, err = cache.ByID(context.Background(), user.ID())
+	if !errors.Is(err, ErrOrganizationRequired) {
+		t.Errorf("expected %v, got %v", ErrOrganizationRequired, err)
+	}
+}
+
+func TestCachedUserRepository_invalidation(t *testing.T) {
+	testCases := []struct {
+		Name string
+		// Change changes the user through the cache, and returns its expected display name and e-mails.
+		Change func(t *testing.T, cache *CachedUserRepository, user User) (string, []Email)
+		// ExpectedErr is returned by ByID after the change.
+		ExpectedErr error
+	}{
+		{
+			Name: "update",
+			Change: func(t *testing.T, cache *CachedUserRepository, user User) (string, []Email) {
+				newFirstName := "Jack"
+				err := user.ChangeName(&newFirstName, nil, testNow())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = cache.Update(testContext(), user)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return "Jack Doe", user.Emails()
+			},
+		},
+		{
+			Name: "add_email",
+			Change: func(t *testing.T, cache *CachedUserRepository, user User) (string, []Email) {
+				email, err := user.AddEmail("john.doe@example.com")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = cache.AddEmail(testContext(), user.ID(), email)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return "John Doe", user.Emails()
+			},
+		},
+		{
+			Name: "verify_email",
+			Change: func(t *testing.T, cache *CachedUserRepository, user User) (string, []Email) {
+				err := cache.VerifyEmail(testContext(), "JOHN@example.com")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return "John Doe", []Email{UnmarshalEmail("john@example.com", true, true)}
+			},
+		},
+		{
+			Name: "delete",
+			Change: func(t *testing.T, cache *CachedUserRepository, user User) (string, []Email) {
+				err := cache.Delete(testContext(), user.ID(), testNow())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return "", nil
+			},
+			ExpectedErr: ErrUserNotFound,
+		},
+		{
+			Name: "erase",
+			Change: func(t *testing.T, cache *CachedUserRepository, user User) (string, []Email) {
+				err := cache.Erase(testContext(), user.ID(), testNow())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return "", nil
+			},
+			ExpectedErr: ErrUserNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := testContext()
+			cache := NewCachedUserRepository(NewMemoryUserStorage(), time.Minute, 10)
+
+			user := addUser(t, cache, "John", "Doe", "john@example.com")
+
+			// Reads the user again, so it's cached before the change.
+			user, err := cache.ByID(ctx, user.ID())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedDisplayName, expectedEmails := tc.Change(t, cache, user)
+
+			changed, err := cache.ByID(ctx, user.ID())
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if tc.ExpectedErr != nil {
+				return
+			}
+
+			if changed.DisplayName() != expectedDisplayName {
+				t.Errorf("expected display name %q, got stale %q", expectedDisplayName, changed.DisplayName())
+			}
+
+			if len(changed.Emails()) != len(expectedEmails) {
+				t.Fatalf("expected %d e-mails, got stale %d", len(expectedEmails), len(changed.Emails()))
+			}
+
+			for i, e := range changed.Emails() {
+				if e != expectedEmails[i] {
+					t.Errorf("expected e-mail %+v, got stale %+v", expectedEmails[i], e)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedUserRepository_ttl(t *testing.T) {
+	ctx := testContext()
+
+	storage := &countingUserRepository{UserRepository: NewMemoryUserStorage()}
+	cache := NewCachedUserRepository(storage, time.Minute, 10)
+
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time {
+		return now
+	}
+
+	user := addUser(t, storage.UserRepository, "John", "Doe", "john@example.com")
+
+	for _, elapsed := range []time.Duration{0, 59 * time.Second, time.Minute} {
+		now = now.Add(elapsed)
+
+		_, err := cache.ByID(ctx, user.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first read loads the user, the second hits the cache, and the third is past the TTL.
+	if calls := storage.calls(); calls != 2 {
+		t.Errorf("expected 2 storage calls, got %d", calls)
+	}
+}
+
+func TestCachedUserRepository_evictsLeastRecentlyUsed(t *testing.T) {
+	ctx := testContext()
+
+	storage := &countingUserRepository{UserRepository: NewMemoryUserStorage()}
+	cache := NewCachedUserRepository(storage, time.Minute, 2)
+
+	var userIDs []int
+	for _, address := range []string{"john@example.com", "jane@example.com", "jack@example.com"} {
+		userIDs = append(userIDs, addUser(t, storage.UserRepository, "John", "Doe", address).ID())
+	}
+
+	// The second user is the least recently used one when the third is loaded.
+	for _, i := range []int{0, 1, 0, 2, 0, 1} {
+		_, err := cache.ByID(ctx, userIDs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedStats := CacheStats{Hits: 2, Misses: 4, Evictions: 2, Size: 2}
+	if stats := cache.Stats(); stats != expectedStats {
+		t.Errorf("expected stats %+v, got %+v", expectedStats, stats)
+	}
+}
+
+func TestCachedUserRepository_collapsesConcurrentMisses(t *testing.T) {
+	ctx := testContext()
+
+	storage := newBlockingUserRepository(NewMemoryUserStorage())
+	cache := NewCachedUserRepository(storage, time.Minute, 10)
+
+	user := addUser(t, storage.UserRepository, "John", "Doe", "john@example.com")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := cache.ByID(ctx, user.ID())
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	<-storage.loaded
+
+	// Gives the other readers time to wait for the same load.
+	time.Sleep(50 * time.Millisecond)
+	close(storage.release)
+
+	wg.Wait()
+
+	if calls := storage.calls(); calls != 1 {
+		t.Errorf("expected a single storage call, got %d", calls)
+	}
+}
+
+func TestCachedUserRepository_loadOutlivesCanceledCaller(t *testing.T) {
+	storage := newBlockingUserRepository(NewMemoryUserStorage())
+	cache := NewCachedUserRepository(storage, time.Minute, 10)
+
+	user := addUser(t, storage.UserRepository, "John", "Doe", "john@example.com")
+
+	firstCtx, cancel := context.WithCancel(testContext())
+
+	firstDone := make(chan error)
+	go func() {
+		_, err := cache.ByID(firstCtx, user.ID())
+		firstDone <- err
+	}()
+
+	// The first caller starts the load.
+	<-storage.loaded
+
+	secondDone := make(chan error)
+	go func() {
+		_, err := cache.ByID(testContext(), user.ID())
+		secondDone <- err
+	}()
+
+	// Gives the second caller time to wait for the same load.
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+
+	err := <-firstDone
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v for the canceled caller, got %v", context.Canceled, err)
+	}
+
+	close(storage.release)
+
+	err = <-secondDone
+	if err != nil {
+		t.Errorf("expected the load not to be canceled with the caller that started it, got %v", err)
+	}
+
+	if calls := storage.calls(); calls != 1 {
+		t.Errorf("expected a single storage call, got %d", calls)
+	}
+}
+
+func TestCachedUserRepository_doesntCacheLoadsStartedBeforeUpdate(t *testing.T) {
+	ctx := testContext()
+
+	storage := newBlockingUserRepository(NewMemoryUserStorage())
+	cache := NewCachedUserRepository(storage, time.Minute, 10)
+
+	user := addUser(t, storage.UserRepository, "John", "Doe", "john@example.com")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_, err := cache.ByID(ctx, user.ID())
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// The user is read from the storage, but not cached yet.
+	<-storage.loaded
+
+	newFirstName := "Jack"
+	err := user.ChangeName(&newFirstName, nil, testNow())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cache.Update(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	close(storage.release)
+	<-done
+
+	updated, err := cache.ByID(ctx, user.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.DisplayName() != "Jack Doe" {
+		t.Errorf("expected display name %q, got stale %q", "Jack Doe", updated.DisplayName())
+	}
+}
+
+// blockingUserRepository holds ByID after reading the user, until release is closed.
+type blockingUserRepository struct {
+	*countingUserRepository
+
+	// loaded is closed after the first user is read.
+	loaded     chan struct{}
+	loadedOnce sync.Once
+	release    chan struct{}
+}
+
+func newBlockingUserRepository(storage UserRepository) *blockingUserRepository {
+	return &blockingUserRepository{
+		countingUserRepository: &countingUserRepository{UserRepository: storage},
+		loaded:                 make(chan struct{}),
+		release:                make(chan struct{}),
+	}
+}
+
+func (r *blockingUserRepository) ByID(ctx context.Context, id int) (User, error) {
+	user, err := r.countingUserRepository.ByID(ctx, id)
+
+	r.loadedOnce.Do(func() {
+		close(r.loaded)
+	})
+
+	<-r.release
+
+	// Like a database query, it fails when the context is canceled meanwhile.
+	if ctx.Err() != nil {
+		return User{}, ctx.Err()
+	}
+
+	return user, err
+}
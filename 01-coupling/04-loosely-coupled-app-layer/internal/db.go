@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/04-loosely-coupled-app-layer/models"
 	"github.com/go-sql-driver/mysql"
@@ -13,23 +14,44 @@ import (
 	"github.com/volatiletech/sqlboiler/v4/queries/qm"
 )
 
-var (
-	ErrUserNotFound       = errors.New("user not found")
-	ErrEmailAlreadyExists = errors.New("email already exists")
-)
-
+// UserStorage is the MySQL implementation of UserRepository.
 type UserStorage struct {
 	db *sql.DB
+	// webhooks stores the deliveries of the events in the transactions of the changes.
+	webhooks SQLWebhookStorage
 }
 
 func NewUserStorage(db *sql.DB) UserStorage {
 	return UserStorage{
-		db: db,
+		db:       db,
+		webhooks: NewMySQLWebhookStorage(db),
 	}
 }
 
-func (s UserStorage) All(ctx context.Context) ([]User, error) {
-	dbUsers, err := models.Users(qm.Load(models.UserRels.Emails)).All(ctx, s.db)
+func (s UserStorage) All(ctx context.Context, filter UserFilter) ([]User, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mods := []qm.QueryMod{
+		qm.Load(models.UserRels.Emails),
+		models.UserWhere.OrganizationID.EQ(int64(organizationID)),
+		models.UserWhere.ErasedAt.IsNull(),
+		models.UserWhere.DeletedAt.IsNull(),
+		qm.OrderBy(models.UserColumns.ID),
+	}
+	if !filter.CreatedAfter.IsZero() {
+		mods = append(mods, models.UserWhere.CreatedAt.GT(null.TimeFrom(filter.CreatedAfter)))
+	}
+	if filter.AfterID > 0 {
+		mods = append(mods, models.UserWhere.ID.GT(int64(filter.AfterID)))
+	}
+	if filter.Limit > 0 {
+		mods = append(mods, qm.Limit(filter.Limit))
+	}
+
+	dbUsers, err := models.Users(mods...).All(ctx, s.db)
 	if err != nil {
 		return nil, err
 	}
@@ -43,21 +65,50 @@ func (s UserStorage) All(ctx context.Context) ([]User, error) {
 }
 
 func (s UserStorage) ByID(ctx context.Context, id int) (User, error) {
-	dbUser, err := models.Users(qm.Load(models.UserRels.Emails), qm.Where("id = ?", id)).One(ctx, s.db)
+	dbUser, err := s.byID(ctx, id, models.UserWhere.DeletedAt.IsNull())
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return User{}, ErrUserNotFound
-		}
 		return User{}, err
 	}
 
 	return dbUserToApp(dbUser), nil
 }
 
-func (s UserStorage) Add(ctx context.Context, user User) (err error) {
+// byID returns the user of the context's organization unless it's erased.
+// Deleted users are returned too, unless mods filter them out.
+func (s UserStorage) byID(ctx context.Context, id int, mods ...qm.QueryMod) (*models.User, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mods = append(
+		mods,
+		qm.Load(models.UserRels.Emails),
+		qm.Where("id = ?", id),
+		models.UserWhere.OrganizationID.EQ(int64(organizationID)),
+		models.UserWhere.ErasedAt.IsNull(),
+	)
+
+	dbUser, err := models.Users(mods...).One(ctx, s.db)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return dbUser, nil
+}
+
+func (s UserStorage) Add(ctx context.Context, user User, events ...NewUserEvent) (userID int, err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	defer func() {
@@ -66,22 +117,192 @@ func (s UserStorage) Add(ctx context.Context, user User) (err error) {
 		} else {
 			rollbackErr := tx.Rollback()
 			if rollbackErr != nil {
-				log.Println("Error while rolling back:", err)
+				log.Println("Error while rolling back:", rollbackErr)
 			}
 		}
 	}()
 
-	dbUser := dbUserFromApp(user)
+	dbUser := dbUserFromApp(organizationID, user)
 
 	err = dbUser.Insert(ctx, tx, boil.Infer())
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	dbEmail := dbEmailFromApp(user.Emails()[0])
+	dbEmail := dbEmailFromApp(organizationID, user.Emails()[0])
 	dbEmail.UserID = dbUser.ID
 
+	exists, err := emailExists(ctx, tx, dbEmail.OrganizationID, dbEmail.Address)
+	if err != nil {
+		return 0, err
+	}
+
+	if exists {
+		return 0, ErrEmailAlreadyExists
+	}
+
 	err = dbEmail.Insert(ctx, tx, boil.Infer())
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return 0, ErrEmailAlreadyExists
+		}
+		return 0, err
+	}
+
+	err = s.webhooks.addEventDeliveries(ctx, tx, newUserEvents(int(dbUser.ID), events), user.CreatedAt())
+	if err != nil {
+		return 0, err
+	}
+
+	return int(dbUser.ID), nil
+}
+
+func (s UserStorage) AddBatch(ctx context.Context, users []User) (userErrs []error, err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	userErrs = make([]error, len(users))
+
+	for i, user := range users {
+		dbEmail := dbEmailFromApp(organizationID, user.Emails()[0])
+
+		// Checked before inserting the user, as the transaction can't be rolled back for a single user.
+		exists, err := emailExists(ctx, tx, dbEmail.OrganizationID, dbEmail.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			userErrs[i] = ErrEmailAlreadyExists
+			continue
+		}
+
+		dbUser := dbUserFromApp(organizationID, user)
+
+		err = dbUser.Insert(ctx, tx, boil.Infer())
+		if err != nil {
+			return nil, err
+		}
+
+		dbEmail.UserID = dbUser.ID
+
+		err = dbEmail.Insert(ctx, tx, boil.Infer())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return userErrs, nil
+}
+
+// Update saves the user only if its version didn't change since it was read.
+func (s UserStorage) Update(ctx context.Context, user User, events ...interface{}) (err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	dbUser := dbUserFromApp(organizationID, user)
+
+	rowsAffected, err := models.Users(
+		qm.Where("id = ? AND version = ? AND erased_at IS NULL AND deleted_at IS NULL", dbUser.ID, dbUser.Version),
+		models.UserWhere.OrganizationID.EQ(dbUser.OrganizationID),
+	).UpdateAll(ctx, tx, models.M{
+		models.UserColumns.FirstName: dbUser.FirstName,
+		models.UserColumns.LastName:  dbUser.LastName,
+		models.UserColumns.Version:   dbUser.Version + 1,
+		models.UserColumns.UpdatedAt: dbUser.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserModified
+	}
+
+	for _, e := range user.Emails() {
+		_, err = models.Emails(qm.Where("user_id = ? AND address = ?", dbUser.ID, e.Address())).UpdateAll(ctx, tx, models.M{
+			models.EmailColumns.Primary: e.Primary(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.webhooks.addEventDeliveries(ctx, tx, events, user.UpdatedAt())
+}
+
+// AddEmail adds the e-mail address to an existing user.
+func (s UserStorage) AddEmail(ctx context.Context, userID int, email Email) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The foreign key doesn't know about organizations, so the user is checked first.
+	userExists, err := models.Users(
+		qm.Where("id = ?", userID),
+		models.UserWhere.OrganizationID.EQ(int64(organizationID)),
+		models.UserWhere.ErasedAt.IsNull(),
+		models.UserWhere.DeletedAt.IsNull(),
+	).Exists(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	if !userExists {
+		return ErrUserNotFound
+	}
+
+	dbEmail := dbEmailFromApp(organizationID, email)
+	dbEmail.UserID = int64(userID)
+
+	exists, err := emailExists(ctx, s.db, dbEmail.OrganizationID, dbEmail.Address)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrEmailAlreadyExists
+	}
+
+	err = dbEmail.Insert(ctx, s.db, boil.Infer())
 	if err != nil {
 		var mysqlErr *mysql.MySQLError
 		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
@@ -93,41 +314,229 @@ func (s UserStorage) Add(ctx context.Context, user User) (err error) {
 	return nil
 }
 
-func (s UserStorage) Update(ctx context.Context, user User) error {
-	dbUser := dbUserFromApp(user)
-	_, err := dbUser.Update(ctx, s.db, boil.Whitelist(models.UserColumns.FirstName, models.UserColumns.LastName))
+// VerifyEmail marks the e-mail address as verified.
+func (s UserStorage) VerifyEmail(ctx context.Context, address string) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	dbEmail, err := models.Emails(
+		qm.Where("address = ?", address),
+		models.EmailWhere.OrganizationID.EQ(int64(organizationID)),
+		qm.Where("user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)"),
+	).One(ctx, s.db)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEmailNotFound
+		}
+		return err
+	}
+
+	if dbEmail.Verified {
+		return nil
+	}
+
+	dbEmail.Verified = true
+
+	_, err = dbEmail.Update(ctx, s.db, boil.Whitelist(models.EmailColumns.Verified))
 	return err
 }
 
-func (s UserStorage) Delete(ctx context.Context, id int) error {
-	_, err := models.Users(qm.Where("id = ?", id)).DeleteAll(ctx, s.db)
-	return err
+func (s UserStorage) Delete(ctx context.Context, id int, now time.Time, events ...interface{}) (err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	rowsAffected, err := models.Users(
+		qm.Where("id = ?", id),
+		models.UserWhere.OrganizationID.EQ(int64(organizationID)),
+		models.UserWhere.ErasedAt.IsNull(),
+		models.UserWhere.DeletedAt.IsNull(),
+	).UpdateAll(ctx, tx, models.M{
+		models.UserColumns.DeletedAt: null.TimeFrom(now),
+	})
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return nil
+	}
+
+	return s.webhooks.addEventDeliveries(ctx, tx, events, now)
+}
+
+func (s UserStorage) Restore(ctx context.Context, id int, now time.Time) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := models.Users(
+		qm.Where("id = ?", id),
+		models.UserWhere.OrganizationID.EQ(int64(organizationID)),
+		models.UserWhere.ErasedAt.IsNull(),
+		models.UserWhere.DeletedAt.IsNotNull(),
+	).UpdateAll(ctx, s.db, models.M{
+		models.UserColumns.DeletedAt: null.Time{},
+		models.UserColumns.UpdatedAt: null.TimeFrom(now),
+	})
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Purge deletes the users' rows, and their e-mails with them. Erased users are skipped, as their audit records reference them.
+func (s UserStorage) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	rowsAffected, err := models.Users(
+		models.UserWhere.ErasedAt.IsNull(),
+		models.UserWhere.DeletedAt.LT(null.TimeFrom(deletedBefore)),
+	).DeleteAll(ctx, s.db)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
+
+func (s UserStorage) PersonalData(ctx context.Context, id int) (PersonalData, error) {
+	dbUser, err := s.byID(ctx, id)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	return PersonalData{
+		User:        dbUserToApp(dbUser),
+		LastIP:      dbUser.LastIP.String,
+		HasPassword: dbUser.PasswordHash.Valid,
+	}, nil
+}
+
+// Erase anonymizes the user and stores the audit record in a single transaction.
+func (s UserStorage) Erase(ctx context.Context, id int, now time.Time, events ...interface{}) (err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	rowsAffected, err := models.Users(
+		qm.Where("id = ?", id),
+		models.UserWhere.OrganizationID.EQ(int64(organizationID)),
+		models.UserWhere.ErasedAt.IsNull(),
+	).UpdateAll(ctx, tx, models.M{
+		models.UserColumns.FirstName:    "",
+		models.UserColumns.LastName:     "",
+		models.UserColumns.PasswordHash: null.String{},
+		models.UserColumns.LastIP:       null.String{},
+		models.UserColumns.UpdatedAt:    null.TimeFrom(now),
+		models.UserColumns.ErasedAt:     null.TimeFrom(now),
+	})
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	_, err = models.Emails(qm.Where("user_id = ?", id)).DeleteAll(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// Nothing in the service reads the audit records, so they're inserted with plain SQL.
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_erasures (user_id, erased_at) VALUES (?, ?)", id, now)
+	if err != nil {
+		return err
+	}
+
+	// Webhook payloads have the user's personal data too. Webhooks have no models, like the audit records.
+	_, err = tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE user_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return s.webhooks.addEventDeliveries(ctx, tx, events, now)
+}
+
+// emailExists checks if the address is taken in the organization, ignoring the case.
+// It doesn't depend on the column's collation, so the unique index is only the last line of defense.
+func emailExists(ctx context.Context, exec boil.ContextExecutor, organizationID int64, address string) (bool, error) {
+	return models.Emails(
+		models.EmailWhere.OrganizationID.EQ(organizationID),
+		qm.Where("LOWER(address) = LOWER(?)", address),
+	).Exists(ctx, exec)
 }
 
-func dbUserFromApp(u User) *models.User {
+func dbUserFromApp(organizationID int, u User) *models.User {
 	return &models.User{
-		ID:           int64(u.ID()),
-		FirstName:    u.FirstName(),
-		LastName:     u.LastName(),
-		PasswordHash: null.String{},
-		LastIP:       null.String{},
-		CreatedAt:    null.Time{},
-		UpdatedAt:    null.Time{},
+		ID:             int64(u.ID()),
+		OrganizationID: int64(organizationID),
+		FirstName:      u.FirstName(),
+		LastName:       u.LastName(),
+		PasswordHash:   null.String{},
+		LastIP:         null.String{},
+		CreatedAt:      null.TimeFrom(u.CreatedAt()),
+		UpdatedAt:      null.TimeFrom(u.UpdatedAt()),
+		Version:        int64(u.Version()),
 	}
 }
 
 func dbUserToApp(u *models.User) User {
 	var emails []Email
 	for _, e := range u.R.Emails {
-		emails = append(emails, UnmarshalEmail(e.Address, e.Primary))
+		emails = append(emails, UnmarshalEmail(e.Address, e.Primary, e.Verified))
 	}
 
-	return UnmarshalUser(int(u.ID), u.FirstName, u.LastName, emails)
+	// Users created before timestamps were populated have them empty.
+	return UnmarshalUser(int(u.ID), u.FirstName, u.LastName, emails, int(u.Version), u.CreatedAt.Time, u.UpdatedAt.Time)
 }
 
-func dbEmailFromApp(e Email) *models.Email {
+func dbEmailFromApp(organizationID int, e Email) *models.Email {
 	return &models.Email{
-		Address: e.Address(),
-		Primary: e.Primary(),
+		Address:        e.Address(),
+		Primary:        e.Primary(),
+		Verified:       e.Verified(),
+		OrganizationID: int64(organizationID),
 	}
 }
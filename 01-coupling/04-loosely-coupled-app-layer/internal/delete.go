@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// deleteUser marks the user as deleted and publishes UserDeleted. All APIs delete users with it, so they behave the same way.
+// Deleting a missing or already deleted user does nothing, and publishes no event.
+func deleteUser(ctx context.Context, storage UserRepository, eventPublisher EventPublisher, userID int, now time.Time) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = storage.ByID(ctx, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	event := UserDeleted{
+		ID:             strconv.Itoa(userID),
+		OrganizationID: organizationID,
+	}
+
+	err = storage.Delete(ctx, userID, now, event)
+	if err != nil {
+		return err
+	}
+
+	// Like in signUp, the event is lost if publishing fails.
+	return eventPublisher.Publish(ctx, event)
+}
+
+// eraseUser erases the user and publishes UserDeleted, also if the user was deleted before.
+func eraseUser(ctx context.Context, storage UserRepository, eventPublisher EventPublisher, userID int, now time.Time) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	event := UserDeleted{
+		ID:             strconv.Itoa(userID),
+		OrganizationID: organizationID,
+		Erased:         true,
+	}
+
+	err = storage.Erase(ctx, userID, now, event)
+	if err != nil {
+		return err
+	}
+
+	return eventPublisher.Publish(ctx, event)
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewEmailAddress(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		Raw             string
+		ExpectedAddress string
+		ExpectedErr     error
+	}{
+		{
+			Name:            "valid",
+			Raw:             "john@example.com",
+			ExpectedAddress: "john@example.com",
+		},
+		{
+			Name:            "lowercase_domain",
+			Raw:             "John.Doe@Example.COM",
+			ExpectedAddress: "John.Doe@example.com",
+		},
+		{
+			Name:            "surrounding_whitespace",
+			Raw:             "  john@example.com ",
+			ExpectedAddress: "john@example.com",
+		},
+		{
+			Name:            "subaddress",
+			Raw:             "john+newsletter@example.com",
+			ExpectedAddress: "john+newsletter@example.com",
+		},
+		{
+			Name:            "idn_domain",
+			Raw:             "john@Bücher.example",
+			ExpectedAddress: "john@xn--bcher-kva.example",
+		},
+		{
+			Name:            "punycode_domain",
+			Raw:             "john@XN--BCHER-KVA.example",
+			ExpectedAddress: "john@xn--bcher-kva.example",
+		},
+		{
+			Name:        "empty",
+			Raw:         "",
+			ExpectedErr: ErrEmailRequired,
+		},
+		{
+			Name:        "whitespace_only",
+			Raw:         "   ",
+			ExpectedErr: ErrEmailRequired,
+		},
+		{
+			Name:        "missing_at",
+			Raw:         "john.example.com",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:        "missing_domain",
+			Raw:         "john@",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:        "missing_local_part",
+			Raw:         "@example.com",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:        "display_name",
+			Raw:         "John <john@example.com>",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:        "angle_brackets",
+			Raw:         "<john@example.com>",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:        "two_addresses",
+			Raw:         "john@example.com, jane@example.com",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:        "local_part_too_long",
+			Raw:         strings.Repeat("a", 65) + "@example.com",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:            "local_part_max_length",
+			Raw:             strings.Repeat("a", 64) + "@example.com",
+			ExpectedAddress: strings.Repeat("a", 64) + "@example.com",
+		},
+		{
+			Name:        "domain_label_too_long",
+			Raw:         "john@" + strings.Repeat("a", 64) + ".com",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:        "domain_too_long",
+			Raw:         "john@" + strings.Repeat(strings.Repeat("a", 60)+".", 5) + "com",
+			ExpectedErr: ErrInvalidEmail,
+		},
+		{
+			Name:        "address_too_long",
+			Raw:         strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("b", 47)+".", 4) + "com",
+			ExpectedErr: ErrInvalidEmail,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			address, err := NewEmailAddress(tc.Raw)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if address.String() != tc.ExpectedAddress {
+				t.Errorf("expected address %q, got %q", tc.ExpectedAddress, address.String())
+			}
+		})
+	}
+}
+
+func TestEmailAddress_Equal(t *testing.T) {
+	a, err := NewEmailAddress("John@Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewEmailAddress("john@EXAMPLE.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewEmailAddress("jane@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.Equal(b) {
+		t.Errorf("expected %v to equal %v", a, b)
+	}
+
+	if a.Equal(c) {
+		t.Errorf("expected %v not to equal %v", a, c)
+	}
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+// UserSignedUp is published after a new user is created.
+// The newsletter service in 03-cohesion consumes it, so keep the JSON fields in sync with it.
+type UserSignedUp struct {
+	ID                 string `json:"id"`
+	OrganizationID     int    `json:"organization_id"`
+	Email              string `json:"email"`
+	ProductNewsConsent bool   `json:"product_news_consent"`
+}
+
+// UserRenamed is published after the user's name is changed.
+type UserRenamed struct {
+	ID             string `json:"id"`
+	OrganizationID int    `json:"organization_id"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	DisplayName    string `json:"display_name"`
+}
+
+// saveRenamedUser saves the user of the context's organization after its name is changed, and publishes UserRenamed.
+// All APIs rename users, so they save them and publish the same event.
+func saveRenamedUser(ctx context.Context, storage UserRepository, eventPublisher EventPublisher, u User) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	event := UserRenamed{
+		ID:             strconv.Itoa(u.ID()),
+		OrganizationID: organizationID,
+		FirstName:      u.FirstName(),
+		LastName:       u.LastName(),
+		DisplayName:    u.DisplayName(),
+	}
+
+	err = storage.Update(ctx, u, event)
+	if err != nil {
+		return err
+	}
+
+	// Like in signUp, the event is lost if publishing fails.
+	return eventPublisher.Publish(ctx, event)
+}
+
+// UserDeleted is published after the user is deleted, and again if it's erased later.
+type UserDeleted struct {
+	ID             string `json:"id"`
+	OrganizationID int    `json:"organization_id"`
+	// Erased tells that the user's personal data is gone for good, so consumers should remove their copies too.
+	Erased bool `json:"erased"`
+}
+
+// EventPublisher publishes events to other services. *cqrs.EventBus implements it.
+type EventPublisher interface {
+	Publish(ctx context.Context, event interface{}) error
+}
+
+func NewRedisPublisher(redisAddr string) (message.Publisher, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	logger := watermill.NewStdLogger(false, false)
+
+	return redisstream.NewPublisher(
+		redisstream.PublisherConfig{
+			Client: client,
+		},
+		logger,
+	)
+}
+
+// NewEventBus publishes JSON-encoded events to topics named after them (e.g. UserSignedUp).
+func NewEventBus(publisher message.Publisher) (*cqrs.EventBus, error) {
+	logger := watermill.NewStdLogger(false, false)
+
+	return cqrs.NewEventBusWithConfig(publisher, cqrs.EventBusConfig{
+		GeneratePublishTopic: func(params cqrs.GenerateEventPublishTopicParams) (string, error) {
+			return params.EventName, nil
+		},
+		Marshaler: cqrs.JSONMarshaler{
+			GenerateName: cqrs.StructName,
+		},
+		Logger: logger,
+	})
+}
@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+//go:embed schema.graphql
+var graphQLSchema string
+
+// NewGraphQLHandler returns the handler serving the GraphQL API from schema.graphql.
+// It works on the same domain and storage as UserHandler, only the transport is different.
+func NewGraphQLHandler(
+	storage UserRepository,
+	verificationTokens VerificationTokens,
+	mailer Mailer,
+	eventPublisher EventPublisher,
+) (http.Handler, error) {
+	resolver := GraphQLResolver{
+		storage:            storage,
+		verificationTokens: verificationTokens,
+		mailer:             mailer,
+		eventPublisher:     eventPublisher,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+
+	schema, err := graphql.ParseSchema(graphQLSchema, &resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &relay.Handler{Schema: schema}, nil
+}
+
+// GraphQLResolver resolves queries and mutations from schema.graphql.
+//
+// E-mails are resolved from the User aggregate. All repositories load e-mails of all returned users
+// in a single query, so listing users with their e-mails doesn't cause N+1 queries.
+type GraphQLResolver struct {
+	storage            UserRepository
+	verificationTokens VerificationTokens
+	mailer             Mailer
+	eventPublisher     EventPublisher
+	now                func() time.Time
+}
+
+func (r *GraphQLResolver) Users(ctx context.Context, args struct{ CreatedAfter *graphql.Time }) ([]*userResolver, error) {
+	var filter UserFilter
+	if args.CreatedAfter != nil {
+		filter.CreatedAfter = args.CreatedAfter.UTC()
+	}
+
+	users, err := r.storage.All(ctx, filter)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	resolvers := make([]*userResolver, 0, len(users))
+	for _, u := range users {
+		resolvers = append(resolvers, &userResolver{user: u})
+	}
+
+	return resolvers, nil
+}
+
+func (r *GraphQLResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	userID, err := parseGraphQLID(args.ID)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	user, err := r.storage.ByID(ctx, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+type createUserInput struct {
+	FirstName          *string
+	LastName           *string
+	Email              string
+	ProductNewsConsent *bool
+}
+
+func (r *GraphQLResolver) CreateUser(ctx context.Context, args struct{ Input createUserInput }) (*userResolver, error) {
+	input := args.Input
+
+	user, err := NewUser(stringOrEmpty(input.FirstName), stringOrEmpty(input.LastName), input.Email, r.now())
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	productNewsConsent := input.ProductNewsConsent != nil && *input.ProductNewsConsent
+
+	userID, err := signUp(ctx, r.storage, r.verificationTokens, r.mailer, r.eventPublisher, user, productNewsConsent)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	user, err = r.storage.ByID(ctx, userID)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+type renameUserInput struct {
+	ID        graphql.ID
+	Version   int32
+	FirstName *string
+	LastName  *string
+}
+
+func (r *GraphQLResolver) RenameUser(ctx context.Context, args struct{ Input renameUserInput }) (*userResolver, error) {
+	input := args.Input
+
+	userID, err := parseGraphQLID(input.ID)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	user, err := r.storage.ByID(ctx, userID)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	if user.Version() != int(input.Version) {
+		return nil, graphQLError(ErrUserModified)
+	}
+
+	if input.FirstName == nil && input.LastName == nil {
+		return &userResolver{user: user}, nil
+	}
+
+	err = user.ChangeName(input.FirstName, input.LastName, r.now())
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	err = saveRenamedUser(ctx, r.storage, r.eventPublisher, user)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	user, err = r.storage.ByID(ctx, userID)
+	if err != nil {
+		return nil, graphQLError(err)
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+func (r *GraphQLResolver) DeleteUser(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	userID, err := parseGraphQLID(args.ID)
+	if err != nil {
+		return false, graphQLError(err)
+	}
+
+	err = deleteUser(ctx, r.storage, r.eventPublisher, userID, r.now())
+	if err != nil {
+		return false, graphQLError(err)
+	}
+
+	return true, nil
+}
+
+type userResolver struct {
+	user User
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(r.user.ID()))
+}
+
+func (r *userResolver) FirstName() string {
+	return r.user.FirstName()
+}
+
+func (r *userResolver) LastName() string {
+	return r.user.LastName()
+}
+
+func (r *userResolver) DisplayName() string {
+	return r.user.DisplayName()
+}
+
+func (r *userResolver) Emails() []*emailResolver {
+	emails := make([]*emailResolver, 0, len(r.user.Emails()))
+	for _, e := range r.user.Emails() {
+		emails = append(emails, &emailResolver{email: e})
+	}
+
+	return emails
+}
+
+func (r *userResolver) Version() int32 {
+	return int32(r.user.Version())
+}
+
+func (r *userResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.user.CreatedAt()}
+}
+
+func (r *userResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.user.UpdatedAt()}
+}
+
+type emailResolver struct {
+	email Email
+}
+
+func (r *emailResolver) Address() string {
+	return r.email.Address()
+}
+
+func (r *emailResolver) Primary() bool {
+	return r.email.Primary()
+}
+
+func (r *emailResolver) Verified() bool {
+	return r.email.Verified()
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
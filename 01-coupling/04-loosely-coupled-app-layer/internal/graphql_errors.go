@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// Error codes returned in the "code" extension of GraphQL errors.
+const (
+	graphQLCodeBadUserInput = "BAD_USER_INPUT"
+	graphQLCodeNotFound     = "NOT_FOUND"
+	graphQLCodeConflict     = "CONFLICT"
+	graphQLCodeInternal     = "INTERNAL"
+)
+
+// graphQLCodeError is a resolver error with a code in its extensions, so clients don't have to parse messages.
+type graphQLCodeError struct {
+	message string
+	code    string
+}
+
+func (e graphQLCodeError) Error() string {
+	return e.message
+}
+
+func (e graphQLCodeError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": e.code,
+	}
+}
+
+// graphQLError logs the error and adds the matching code, like writeError does for HTTP.
+// Errors not known to the GraphQL layer become INTERNAL without details.
+func graphQLError(err error) error {
+	log.Println(err)
+
+	code := graphQLCodeFromError(err)
+	if code == graphQLCodeInternal {
+		return graphQLCodeError{message: "internal error", code: code}
+	}
+
+	return graphQLCodeError{message: err.Error(), code: code}
+}
+
+func graphQLCodeFromError(err error) string {
+	switch {
+	case errors.Is(err, errInvalidUserID),
+		errors.Is(err, ErrOrganizationRequired),
+		errors.Is(err, ErrNameRequired),
+		errors.Is(err, ErrEmailRequired),
+		errors.Is(err, ErrInvalidEmail):
+		return graphQLCodeBadUserInput
+	case errors.Is(err, ErrUserNotFound):
+		return graphQLCodeNotFound
+	case errors.Is(err, ErrEmailAlreadyExists), errors.Is(err, ErrUserModified):
+		return graphQLCodeConflict
+	default:
+		return graphQLCodeInternal
+	}
+}
+
+func parseGraphQLID(id graphql.ID) (int, error) {
+	userID, err := strconv.Atoi(string(id))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidUserID, err)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,327 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGraphQLHandler_CreateUser(t *testing.T) {
+	handler, publisher := newTestGraphQLHandler(t, NewMemoryUserStorage())
+
+	query := `mutation ($input: CreateUserInput!) {
+		createUser(input: $input) { id displayName emails { address primary verified } }
+	}`
+
+	testCases := []struct {
+		Name         string
+		Input        map[string]interface{}
+		ExpectedCode string
+	}{
+		{
+			Name:  "valid",
+			Input: map[string]interface{}{"firstName": "John", "lastName": "Doe", "email": "john@example.com", "productNewsConsent": true},
+		},
+		{
+			Name:         "existing_email_other_case",
+			Input:        map[string]interface{}{"firstName": "Jane", "email": "JOHN@example.com"},
+			ExpectedCode: graphQLCodeConflict,
+		},
+		{
+			Name:         "missing_name",
+			Input:        map[string]interface{}{"email": "jane@example.com"},
+			ExpectedCode: graphQLCodeBadUserInput,
+		},
+		{
+			Name:         "invalid_email",
+			Input:        map[string]interface{}{"firstName": "Jane", "email": "jane"},
+			ExpectedCode: graphQLCodeBadUserInput,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			var data struct {
+				CreateUser struct {
+					ID          string `json:"id"`
+					DisplayName string `json:"displayName"`
+					Emails      []struct {
+						Address  string `json:"address"`
+						Primary  bool   `json:"primary"`
+						Verified bool   `json:"verified"`
+					} `json:"emails"`
+				} `json:"createUser"`
+			}
+
+			code := graphQLRequest(t, handler, query, map[string]interface{}{"input": tc.Input}, &data)
+			if code != tc.ExpectedCode {
+				t.Fatalf("expected error code %q, got %q", tc.ExpectedCode, code)
+			}
+
+			if tc.ExpectedCode != "" {
+				return
+			}
+
+			if data.CreateUser.DisplayName != "John Doe" {
+				t.Errorf("expected display name %q, got %q", "John Doe", data.CreateUser.DisplayName)
+			}
+
+			if len(data.CreateUser.Emails) != 1 || !data.CreateUser.Emails[0].Primary || data.CreateUser.Emails[0].Verified {
+				t.Errorf("expected a primary, unverified e-mail, got %+v", data.CreateUser.Emails)
+			}
+
+			expectedEvent := UserSignedUp{ID: data.CreateUser.ID, OrganizationID: testOrganizationID, Email: "john@example.com", ProductNewsConsent: true}
+			if event := publisher.lastEvent(); event != expectedEvent {
+				t.Errorf("expected event %+v, got %+v", expectedEvent, event)
+			}
+		})
+	}
+}
+
+func TestGraphQLHandler_RenameUser(t *testing.T) {
+	storage := NewMemoryUserStorage()
+	handler, _ := newTestGraphQLHandler(t, storage)
+
+	user := addUser(t, storage, "John", "Doe", "john@example.com")
+	userID := strconv.Itoa(user.ID())
+
+	query := `mutation ($input: RenameUserInput!) {
+		renameUser(input: $input) { displayName version }
+	}`
+
+	testCases := []struct {
+		Name                string
+		Input               map[string]interface{}
+		ExpectedCode        string
+		ExpectedDisplayName string
+	}{
+		{
+			Name:         "outdated_version",
+			Input:        map[string]interface{}{"id": userID, "version": user.Version() + 1, "firstName": "Jack"},
+			ExpectedCode: graphQLCodeConflict,
+		},
+		{
+			Name:         "not_found",
+			Input:        map[string]interface{}{"id": "100", "version": 1, "firstName": "Jack"},
+			ExpectedCode: graphQLCodeNotFound,
+		},
+		{
+			Name:         "invalid_id",
+			Input:        map[string]interface{}{"id": "john", "version": 1, "firstName": "Jack"},
+			ExpectedCode: graphQLCodeBadUserInput,
+		},
+		{
+			Name:         "delete_first_and_last_name",
+			Input:        map[string]interface{}{"id": userID, "version": user.Version(), "firstName": "", "lastName": ""},
+			ExpectedCode: graphQLCodeBadUserInput,
+		},
+		{
+			Name:                "change_first_name",
+			Input:               map[string]interface{}{"id": userID, "version": user.Version(), "firstName": "Jack"},
+			ExpectedDisplayName: "Jack Doe",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			var data struct {
+				RenameUser struct {
+					DisplayName string `json:"displayName"`
+					Version     int    `json:"version"`
+				} `json:"renameUser"`
+			}
+
+			code := graphQLRequest(t, handler, query, map[string]interface{}{"input": tc.Input}, &data)
+			if code != tc.ExpectedCode {
+				t.Fatalf("expected error code %q, got %q", tc.ExpectedCode, code)
+			}
+
+			if tc.ExpectedCode != "" {
+				return
+			}
+
+			if data.RenameUser.DisplayName != tc.ExpectedDisplayName {
+				t.Errorf("expected display name %q, got %q", tc.ExpectedDisplayName, data.RenameUser.DisplayName)
+			}
+
+			if data.RenameUser.Version != user.Version()+1 {
+				t.Errorf("expected version %d, got %d", user.Version()+1, data.RenameUser.Version)
+			}
+		})
+	}
+}
+
+func TestGraphQLHandler_DeleteUser(t *testing.T) {
+	storage := NewMemoryUserStorage()
+	handler, _ := newTestGraphQLHandler(t, storage)
+
+	user := addUser(t, storage, "John", "Doe", "john@example.com")
+	variables := map[string]interface{}{"id": strconv.Itoa(user.ID())}
+
+	code := graphQLRequest(t, handler, `mutation ($id: ID!) { deleteUser(id: $id) }`, variables, nil)
+	if code != "" {
+		t.Fatalf("expected no error, got %q", code)
+	}
+
+	var data struct {
+		User *struct{} `json:"user"`
+	}
+
+	code = graphQLRequest(t, handler, `query ($id: ID!) { user(id: $id) { id } }`, variables, &data)
+	if code != "" {
+		t.Fatalf("expected no error, got %q", code)
+	}
+
+	if data.User != nil {
+		t.Errorf("expected the user to be deleted")
+	}
+}
+
+func TestGraphQLHandler_Users_loadsEmailsWithUsers(t *testing.T) {
+	memoryStorage := NewMemoryUserStorage()
+	for _, address := range []string{"john@example.com", "jane@example.com", "jack@example.com"} {
+		addUser(t, memoryStorage, "John", "Doe", address)
+	}
+
+	storage := &countingUserRepository{UserRepository: memoryStorage}
+	handler, _ := newTestGraphQLHandler(t, storage)
+
+	var data struct {
+		Users []struct {
+			Emails []struct {
+				Address string `json:"address"`
+			} `json:"emails"`
+		} `json:"users"`
+	}
+
+	code := graphQLRequest(t, handler, `{ users { id emails { address } } }`, nil, &data)
+	if code != "" {
+		t.Fatalf("expected no error, got %q", code)
+	}
+
+	if len(data.Users) != 3 {
+		t.Fatalf("expected 3 users, got %d", len(data.Users))
+	}
+
+	for _, u := range data.Users {
+		if len(u.Emails) != 1 {
+			t.Errorf("expected 1 e-mail, got %d", len(u.Emails))
+		}
+	}
+
+	if calls := storage.calls(); calls != 1 {
+		t.Errorf("expected a single repository call, got %d", calls)
+	}
+}
+
+func newTestGraphQLHandler(t *testing.T, storage UserRepository) (http.Handler, *eventPublisherStub) {
+	t.Helper()
+
+	publisher := &eventPublisherStub{}
+
+	handler, err := NewGraphQLHandler(
+		storage,
+		NewVerificationTokens([]byte("secret"), time.Hour),
+		NewWriterMailer(io.Discard),
+		publisher,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return withTestOrganization(OrganizationMiddleware(handler)), publisher
+}
+
+// graphQLRequest sends the query and decodes its data into v. It returns the code of the first error, if any.
+func graphQLRequest(t *testing.T, handler http.Handler, query string, variables map[string]interface{}, v interface{}) string {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+
+	err = json.NewDecoder(rec.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Errors) > 0 {
+		if resp.Errors[0].Extensions.Code == "" {
+			t.Fatalf("expected an error with a code, got %q", resp.Errors[0].Message)
+		}
+		return resp.Errors[0].Extensions.Code
+	}
+
+	if v != nil {
+		err = json.Unmarshal(resp.Data, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return ""
+}
+
+// countingUserRepository counts reads, to check how many queries resolving a GraphQL query takes.
+type countingUserRepository struct {
+	UserRepository
+
+	lock  sync.Mutex
+	reads int
+}
+
+func (r *countingUserRepository) All(ctx context.Context, filter UserFilter) ([]User, error) {
+	r.count()
+	return r.UserRepository.All(ctx, filter)
+}
+
+func (r *countingUserRepository) ByID(ctx context.Context, id int) (User, error) {
+	r.count()
+	return r.UserRepository.ByID(ctx, id)
+}
+
+func (r *countingUserRepository) count() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.reads++
+}
+
+func (r *countingUserRepository) calls() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.reads
+}
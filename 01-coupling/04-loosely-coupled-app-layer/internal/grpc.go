@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/04-loosely-coupled-app-layer/internal/userspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// organizationIDMetadataKey is the gRPC counterpart of the X-Organization-ID header. Metadata keys are lowercase.
+const organizationIDMetadataKey = "x-organization-id"
+
+// UserGRPCServer implements the Users service from users.proto.
+// It works on the same domain and storage as UserHandler, only the transport is different.
+type UserGRPCServer struct {
+	userspb.UnimplementedUsersServer
+
+	storage            UserRepository
+	verificationTokens VerificationTokens
+	mailer             Mailer
+	eventPublisher     EventPublisher
+	now                func() time.Time
+}
+
+func NewUserGRPCServer(
+	storage UserRepository,
+	verificationTokens VerificationTokens,
+	mailer Mailer,
+	eventPublisher EventPublisher,
+) UserGRPCServer {
+	return UserGRPCServer{
+		storage:            storage,
+		verificationTokens: verificationTokens,
+		mailer:             mailer,
+		eventPublisher:     eventPublisher,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// GRPCOrganizationInterceptor scopes the call's context to the organization from the x-organization-id metadata,
+// like OrganizationMiddleware does for HTTP.
+func GRPCOrganizationInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get(organizationIDMetadataKey)
+	if len(values) == 0 {
+		return handler(ctx, req)
+	}
+
+	organizationID, err := parseOrganizationID(values[0])
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return handler(ContextWithOrganizationID(ctx, organizationID), req)
+}
+
+func (s UserGRPCServer) GetUser(ctx context.Context, req *userspb.GetUserRequest) (*userspb.User, error) {
+	user, err := s.storage.ByID(ctx, int(req.Id))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newUserProto(user), nil
+}
+
+func (s UserGRPCServer) ListUsers(ctx context.Context, req *userspb.ListUsersRequest) (*userspb.ListUsersResponse, error) {
+	var filter UserFilter
+	if req.CreatedAfter != nil {
+		err := req.CreatedAfter.CheckValid()
+		if err != nil {
+			return nil, grpcError(errInvalidCreatedAfter)
+		}
+
+		filter.CreatedAfter = req.CreatedAfter.AsTime()
+	}
+
+	users, err := s.storage.All(ctx, filter)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &userspb.ListUsersResponse{}
+	for _, u := range users {
+		resp.Users = append(resp.Users, newUserProto(u))
+	}
+
+	return resp, nil
+}
+
+func (s UserGRPCServer) CreateUser(ctx context.Context, req *userspb.CreateUserRequest) (*userspb.User, error) {
+	user, err := NewUser(req.FirstName, req.LastName, req.Email, s.now())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	userID, err := signUp(ctx, s.storage, s.verificationTokens, s.mailer, s.eventPublisher, user, req.ProductNewsConsent)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return s.GetUser(ctx, &userspb.GetUserRequest{Id: int64(userID)})
+}
+
+// UpdateUser changes the user's name. The version from the request works like If-Match in the HTTP API.
+func (s UserGRPCServer) UpdateUser(ctx context.Context, req *userspb.UpdateUserRequest) (*userspb.User, error) {
+	if req.Version == 0 {
+		return nil, grpcError(errVersionRequired)
+	}
+
+	user, err := s.storage.ByID(ctx, int(req.Id))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	if int64(user.Version()) != req.Version {
+		return nil, grpcError(ErrUserModified)
+	}
+
+	if req.FirstName == nil && req.LastName == nil {
+		return newUserProto(user), nil
+	}
+
+	err = user.ChangeName(req.FirstName, req.LastName, s.now())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	err = saveRenamedUser(ctx, s.storage, s.eventPublisher, user)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return s.GetUser(ctx, &userspb.GetUserRequest{Id: req.Id})
+}
+
+func (s UserGRPCServer) DeleteUser(ctx context.Context, req *userspb.DeleteUserRequest) (*userspb.DeleteUserResponse, error) {
+	err := deleteUser(ctx, s.storage, s.eventPublisher, int(req.Id), s.now())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &userspb.DeleteUserResponse{}, nil
+}
+
+func newUserProto(u User) *userspb.User {
+	var emails []*userspb.Email
+	for _, e := range u.Emails() {
+		emails = append(emails, &userspb.Email{
+			Address:  e.Address(),
+			Primary:  e.Primary(),
+			Verified: e.Verified(),
+		})
+	}
+
+	return &userspb.User{
+		Id:          int64(u.ID()),
+		FirstName:   u.FirstName(),
+		LastName:    u.LastName(),
+		DisplayName: u.DisplayName(),
+		Emails:      emails,
+		Version:     int64(u.Version()),
+		CreatedAt:   timestamppb.New(u.CreatedAt()),
+		UpdatedAt:   timestamppb.New(u.UpdatedAt()),
+	}
+}
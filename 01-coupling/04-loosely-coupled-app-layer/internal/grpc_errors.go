@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"errors"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errVersionRequired = errors.New("the version of the user is required")
+
+// grpcError logs the error and translates it to a gRPC status, like writeError does for HTTP.
+// Errors not known to the gRPC layer become Internal without details.
+func grpcError(err error) error {
+	log.Println(err)
+
+	code := grpcCodeFromError(err)
+	if code == codes.Internal {
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func grpcCodeFromError(err error) codes.Code {
+	switch {
+	case errors.Is(err, errInvalidCreatedAfter),
+		errors.Is(err, ErrOrganizationRequired),
+		errors.Is(err, errInvalidOrganizationID),
+		errors.Is(err, ErrNameRequired),
+		errors.Is(err, ErrEmailRequired),
+		errors.Is(err, ErrInvalidEmail):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrEmailNotFound):
+		return codes.NotFound
+	case errors.Is(err, ErrEmailAlreadyExists):
+		return codes.AlreadyExists
+	case errors.Is(err, errVersionRequired), errors.Is(err, ErrEmailNotVerified):
+		return codes.FailedPrecondition
+	case errors.Is(err, ErrUserModified):
+		return codes.Aborted
+	default:
+		return codes.Internal
+	}
+}
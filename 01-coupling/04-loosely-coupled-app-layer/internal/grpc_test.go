@@ -0,0 +1,324 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/01-coupling/04-loosely-coupled-app-layer/internal/userspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestUserGRPCServer_CreateUser(t *testing.T) {
+	client, publisher := newTestGRPCClient(t)
+	ctx := testGRPCContext(testOrganizationID)
+
+	testCases := []struct {
+		Name         string
+		Request      *userspb.CreateUserRequest
+		ExpectedCode codes.Code
+	}{
+		{
+			Name: "valid",
+			Request: &userspb.CreateUserRequest{
+				FirstName:          "John",
+				LastName:           "Doe",
+				Email:              "john@example.com",
+				ProductNewsConsent: true,
+			},
+			ExpectedCode: codes.OK,
+		},
+		{
+			Name: "existing_email_other_case",
+			Request: &userspb.CreateUserRequest{
+				FirstName: "Jane",
+				Email:     "JOHN@example.com",
+			},
+			ExpectedCode: codes.AlreadyExists,
+		},
+		{
+			Name: "missing_name",
+			Request: &userspb.CreateUserRequest{
+				Email: "jane@example.com",
+			},
+			ExpectedCode: codes.InvalidArgument,
+		},
+		{
+			Name: "invalid_email",
+			Request: &userspb.CreateUserRequest{
+				FirstName: "Jane",
+				Email:     "jane",
+			},
+			ExpectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			user, err := client.CreateUser(ctx, tc.Request)
+			if status.Code(err) != tc.ExpectedCode {
+				t.Fatalf("expected code %v, got %v", tc.ExpectedCode, err)
+			}
+
+			if tc.ExpectedCode != codes.OK {
+				return
+			}
+
+			if user.DisplayName != "John Doe" {
+				t.Errorf("expected display name %q, got %q", "John Doe", user.DisplayName)
+			}
+
+			expectedEvent := UserSignedUp{ID: "1", OrganizationID: testOrganizationID, Email: "john@example.com", ProductNewsConsent: true}
+			if event := publisher.lastEvent(); event != expectedEvent {
+				t.Errorf("expected event %+v, got %+v", expectedEvent, event)
+			}
+		})
+	}
+}
+
+func TestUserGRPCServer_UpdateUser(t *testing.T) {
+	client, _ := newTestGRPCClient(t)
+	ctx := testGRPCContext(testOrganizationID)
+
+	user, err := client.CreateUser(ctx, &userspb.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newFirstName := "Jack"
+	emptyName := ""
+
+	testCases := []struct {
+		Name                string
+		Request             *userspb.UpdateUserRequest
+		ExpectedCode        codes.Code
+		ExpectedDisplayName string
+	}{
+		{
+			Name:         "missing_version",
+			Request:      &userspb.UpdateUserRequest{Id: user.Id, FirstName: &newFirstName},
+			ExpectedCode: codes.FailedPrecondition,
+		},
+		{
+			Name:         "outdated_version",
+			Request:      &userspb.UpdateUserRequest{Id: user.Id, Version: user.Version + 1, FirstName: &newFirstName},
+			ExpectedCode: codes.Aborted,
+		},
+		{
+			Name:         "not_found",
+			Request:      &userspb.UpdateUserRequest{Id: 100, Version: 1, FirstName: &newFirstName},
+			ExpectedCode: codes.NotFound,
+		},
+		{
+			Name:         "delete_first_and_last_name",
+			Request:      &userspb.UpdateUserRequest{Id: user.Id, Version: user.Version, FirstName: &emptyName, LastName: &emptyName},
+			ExpectedCode: codes.InvalidArgument,
+		},
+		{
+			Name:                "change_first_name",
+			Request:             &userspb.UpdateUserRequest{Id: user.Id, Version: user.Version, FirstName: &newFirstName},
+			ExpectedCode:        codes.OK,
+			ExpectedDisplayName: "Jack Doe",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			updatedUser, err := client.UpdateUser(ctx, tc.Request)
+			if status.Code(err) != tc.ExpectedCode {
+				t.Fatalf("expected code %v, got %v", tc.ExpectedCode, err)
+			}
+
+			if tc.ExpectedCode != codes.OK {
+				return
+			}
+
+			if updatedUser.DisplayName != tc.ExpectedDisplayName {
+				t.Errorf("expected display name %q, got %q", tc.ExpectedDisplayName, updatedUser.DisplayName)
+			}
+
+			if updatedUser.Version != user.Version+1 {
+				t.Errorf("expected version %d, got %d", user.Version+1, updatedUser.Version)
+			}
+		})
+	}
+}
+
+func TestUserGRPCServer_ListUsers(t *testing.T) {
+	client, _ := newTestGRPCClient(t)
+	ctx := testGRPCContext(testOrganizationID)
+
+	user, err := client.CreateUser(ctx, &userspb.CreateUserRequest{
+		FirstName: "John",
+		Email:     "john@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Name          string
+		CreatedAfter  *timestamppb.Timestamp
+		ExpectedUsers int
+	}{
+		{
+			Name:          "no_filter",
+			ExpectedUsers: 1,
+		},
+		{
+			Name:          "created_before",
+			CreatedAfter:  timestamppb.New(user.CreatedAt.AsTime().Add(-time.Second)),
+			ExpectedUsers: 1,
+		},
+		{
+			Name:          "created_after",
+			CreatedAfter:  user.CreatedAt,
+			ExpectedUsers: 0,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			resp, err := client.ListUsers(ctx, &userspb.ListUsersRequest{CreatedAfter: tc.CreatedAfter})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(resp.Users) != tc.ExpectedUsers {
+				t.Errorf("expected %d users, got %d", tc.ExpectedUsers, len(resp.Users))
+			}
+		})
+	}
+}
+
+func TestUserGRPCServer_DeleteUser(t *testing.T) {
+	client, _ := newTestGRPCClient(t)
+	ctx := testGRPCContext(testOrganizationID)
+
+	user, err := client.CreateUser(ctx, &userspb.CreateUserRequest{
+		FirstName: "John",
+		Email:     "john@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.DeleteUser(ctx, &userspb.DeleteUserRequest{Id: user.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetUser(ctx, &userspb.GetUserRequest{Id: user.Id})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected code %v, got %v", codes.NotFound, err)
+	}
+}
+
+func TestUserGRPCServer_organizations(t *testing.T) {
+	client, _ := newTestGRPCClient(t)
+
+	user, err := client.CreateUser(testGRPCContext(testOrganizationID), &userspb.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Name         string
+		Context      context.Context
+		ExpectedCode codes.Code
+	}{
+		{
+			Name:         "same_organization",
+			Context:      testGRPCContext(testOrganizationID),
+			ExpectedCode: codes.OK,
+		},
+		{
+			Name:         "other_organization",
+			Context:      testGRPCContext(otherTestOrganizationID),
+			ExpectedCode: codes.NotFound,
+		},
+		{
+			Name:         "missing_organization",
+			Context:      context.Background(),
+			ExpectedCode: codes.InvalidArgument,
+		},
+		{
+			Name:         "invalid_organization",
+			Context:      metadata.AppendToOutgoingContext(context.Background(), organizationIDMetadataKey, "abc"),
+			ExpectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := client.GetUser(tc.Context, &userspb.GetUserRequest{Id: user.Id})
+			if status.Code(err) != tc.ExpectedCode {
+				t.Errorf("expected code %v, got %v", tc.ExpectedCode, err)
+			}
+		})
+	}
+}
+
+// testGRPCContext sends the organization in the call's metadata.
+func testGRPCContext(organizationID int) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), organizationIDMetadataKey, strconv.Itoa(organizationID))
+}
+
+// newTestGRPCClient serves the Users service over an in-memory connection, backed by the in-memory storage,
+// so it runs without network and a database.
+func newTestGRPCClient(t *testing.T) (userspb.UsersClient, *eventPublisherStub) {
+	t.Helper()
+
+	publisher := &eventPublisherStub{}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(GRPCOrganizationInterceptor))
+	userspb.RegisterUsersServer(server, NewUserGRPCServer(
+		NewMemoryUserStorage(),
+		NewVerificationTokens([]byte("secret"), time.Hour),
+		NewWriterMailer(io.Discard),
+		publisher,
+	))
+
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return userspb.NewUsersClient(conn), publisher
+}
@@ -2,27 +2,47 @@ package internal
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"strconv"
+	"time"
 )
 
 type UserHandler struct {
-	storage UserStorage
+	storage            UserRepository
+	verificationTokens VerificationTokens
+	mailer             Mailer
+	eventPublisher     EventPublisher
+	now                func() time.Time
 }
 
-func NewUserHandler(storage UserStorage) UserHandler {
+func NewUserHandler(
+	storage UserRepository,
+	verificationTokens VerificationTokens,
+	mailer Mailer,
+	eventPublisher EventPublisher,
+) UserHandler {
 	return UserHandler{
-		storage: storage,
+		storage:            storage,
+		verificationTokens: verificationTokens,
+		mailer:             mailer,
+		eventPublisher:     eventPublisher,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
 	}
 }
 
-func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.storage.All(r.Context())
+func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request, params GetUsersParams) {
+	filter, err := userFilterFromParams(params)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
+		return
+	}
+
+	users, err := h.storage.All(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -40,26 +60,22 @@ func (h UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h UserHandler) GetUser(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
-	userID, err := strconv.Atoi(string(rawUserID))
+	userID, err := parseUserID(rawUserID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	user, err := h.storage.ByID(r.Context(), userID)
 	if err != nil {
-		log.Println(err)
-		if errors.Is(err, ErrUserNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
 	userResponse := newUserResponse(user)
 
+	w.Header().Set("ETag", userETag(user.Version()))
+
 	err = json.NewEncoder(w).Encode(userResponse)
 	if err != nil {
 		log.Println(err)
@@ -70,47 +86,40 @@ func (h UserHandler) GetUser(w http.ResponseWriter, r *http.Request, rawUserID U
 
 func (h UserHandler) PostUser(w http.ResponseWriter, r *http.Request) {
 	var postUserRequest PostUserRequest
-	err := json.NewDecoder(r.Body).Decode(&postUserRequest)
+	err := decodeRequestBody(r, &postUserRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	user, err := NewUser(postUserRequest.FirstName, postUserRequest.LastName, postUserRequest.Email)
+	user, err := NewUser(postUserRequest.FirstName, postUserRequest.LastName, postUserRequest.Email, h.now())
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
-	err = h.storage.Add(r.Context(), user)
+	productNewsConsent := postUserRequest.ProductNewsConsent != nil && *postUserRequest.ProductNewsConsent
+
+	_, err = signUp(r.Context(), h.storage, h.verificationTokens, h.mailer, h.eventPublisher, user, productNewsConsent)
 	if err != nil {
-		log.Println(err)
-		if errors.Is(err, ErrEmailAlreadyExists) {
-			w.WriteHeader(http.StatusBadRequest)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
-func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
-	userID, err := strconv.Atoi(string(rawUserID))
+func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request, rawUserID UserID, params PatchUserParams) {
+	userID, err := parseUserID(rawUserID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
 	var patchUserRequest PatchUserRequest
-	err = json.NewDecoder(r.Body).Decode(&patchUserRequest)
+	err = decodeRequestBody(r, &patchUserRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -122,46 +131,219 @@ func (h UserHandler) PatchUser(w http.ResponseWriter, r *http.Request, rawUserID
 
 	user, err := h.storage.ByID(r.Context(), userID)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	err = user.ChangeName(patchUserRequest.FirstName, patchUserRequest.LastName)
+	err = checkIfMatch(ifMatchFromParams(params), userETag(user.Version()))
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
+		return
+	}
+
+	err = user.ChangeName(patchUserRequest.FirstName, patchUserRequest.LastName, h.now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = saveRenamedUser(r.Context(), h.storage, h.eventPublisher, user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h UserHandler) AddUserEmail(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
+	userID, err := parseUserID(rawUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var addEmailRequest AddEmailRequest
+	err = decodeRequestBody(r, &addEmailRequest)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := h.storage.ByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	email, err := user.AddEmail(addEmailRequest.Address)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = h.storage.AddEmail(r.Context(), userID, email)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// The e-mail is already saved, so failing here would make retries fail with ErrEmailAlreadyExists.
+	err = sendVerificationEmail(r.Context(), h.verificationTokens, h.mailer, email)
+	if err != nil {
+		log.Println("Error while sending the verification e-mail:", err)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h UserHandler) ChangePrimaryEmail(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
+	userID, err := parseUserID(rawUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var changePrimaryEmailRequest ChangePrimaryEmailRequest
+	err = decodeRequestBody(r, &changePrimaryEmailRequest)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := h.storage.ByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = user.ChangePrimaryEmail(changePrimaryEmailRequest.Address, h.now())
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
 	err = h.storage.Update(r.Context(), user)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
-	userID, err := strconv.Atoi(string(rawUserID))
+func (h UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var verifyEmailRequest VerifyEmailRequest
+	err := decodeRequestBody(r, &verifyEmailRequest)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
-	err = h.storage.Delete(r.Context(), userID)
+	organizationID, address, err := h.verificationTokens.Verify(verifyEmailRequest.Token)
 	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
+		return
+	}
+
+	// The token is signed, so its organization is used even if the request has none.
+	ctx := ContextWithOrganizationID(r.Context(), organizationID)
+
+	err = h.storage.VerifyEmail(ctx, address)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+const (
+	deleteModeDelete DeleteUserParamsMode = "delete"
+	deleteModeErase  DeleteUserParamsMode = "erase"
+)
+
+// DeleteUser marks the user as deleted, or erases it with mode=erase, keeping an anonymized row and an audit record.
+func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request, rawUserID UserID, params DeleteUserParams) {
+	userID, err := parseUserID(rawUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	mode := deleteModeDelete
+	if params.Mode != nil {
+		mode = *params.Mode
+	}
+
+	switch mode {
+	case deleteModeDelete:
+		err = deleteUser(r.Context(), h.storage, h.eventPublisher, userID, h.now())
+	case deleteModeErase:
+		err = eraseUser(r.Context(), h.storage, h.eventPublisher, userID, h.now())
+	default:
+		err = fmt.Errorf("%w: %q", errInvalidDeleteMode, mode)
+	}
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RestoreUser undoes DeleteUser, until the user is purged.
+func (h UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
+	userID, err := parseUserID(rawUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = h.storage.Restore(r.Context(), userID, h.now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserDataExport returns all personal data stored about the user, as a file to download.
+func (h UserHandler) GetUserDataExport(w http.ResponseWriter, r *http.Request, rawUserID UserID) {
+	userID, err := parseUserID(rawUserID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	data, err := h.storage.PersonalData(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	export := UserDataExport{
+		ExportedAt:  h.now(),
+		User:        newUserResponse(data.User),
+		PasswordSet: data.HasPassword,
+	}
+
+	if data.LastIP != "" {
+		export.LastIp = &data.LastIP
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-data-export.json"`, userID))
+
+	err = json.NewEncoder(w).Encode(export)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
 func newUserResponse(u User) UserResponse {
 	var emails []EmailResponse
 	for _, e := range u.Emails() {
@@ -174,12 +356,15 @@ func newUserResponse(u User) UserResponse {
 		LastName:    u.LastName(),
 		DisplayName: u.DisplayName(),
 		Emails:      emails,
+		CreatedAt:   u.CreatedAt(),
+		UpdatedAt:   u.UpdatedAt(),
 	}
 }
 
 func newEmailResponse(e Email) EmailResponse {
 	return EmailResponse{
-		Address: e.Address(),
-		Primary: e.Primary(),
+		Address:  e.Address(),
+		Primary:  e.Primary(),
+		Verified: e.Verified(),
 	}
 }
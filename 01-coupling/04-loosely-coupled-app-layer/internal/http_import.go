@@ -0,0 +1,422 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// importBatchSize is the number of users added in a single transaction.
+	importBatchSize = 100
+	// exportBatchSize is the number of users read from the storage at once.
+	exportBatchSize = 500
+	// maxNDJSONLineSize limits the memory used for a single line.
+	maxNDJSONLineSize = 64 * 1024
+)
+
+const (
+	contentTypeCSV    = "text/csv"
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+const (
+	exportFormatNDJSON ExportUsersParamsFormat = "ndjson"
+	exportFormatCSV    ExportUsersParamsFormat = "csv"
+)
+
+// csvColumns are written by ExportUsers. ImportUsers reads first_name, last_name and email, so exports can be imported back.
+var csvColumns = []string{"id", "first_name", "last_name", "email", "email_verified", "created_at", "updated_at"}
+
+// ImportUsers creates users from a CSV or NDJSON stream, in batches. Rows that fail don't stop the import,
+// and are returned in the report with their problem details.
+//
+// Batches are committed as the body is read, so when it can't be read to the end, the rows read so far
+// are still added, and the report is returned with the error, instead of a problem hiding them.
+//
+// Unlike PostUser, it doesn't send verification e-mails or publish UserSignedUp.
+func (h UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	rows, err := newImportRowReader(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	report := ImportReport{
+		Failed: []ImportFailure{},
+	}
+
+	var batch []importRow
+	addBatch := func() {
+		h.addImportBatch(r, batch, &report)
+		batch = batch[:0]
+	}
+
+	for {
+		row, err := rows.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Println("Error while reading the users to import:", err)
+
+			problem := problemFromError(err)
+			report.Error = &problem
+			break
+		}
+
+		if row.err == nil {
+			row.user, row.err = NewUser(row.firstName, row.lastName, row.email, h.now())
+		}
+
+		if row.err != nil {
+			report.Failed = append(report.Failed, newImportFailure(row.number, row.err))
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == importBatchSize {
+			addBatch()
+		}
+	}
+
+	if len(batch) > 0 {
+		addBatch()
+	}
+
+	// Rows failing validation are reported before the rest of their batch is added.
+	sort.SliceStable(report.Failed, func(i, j int) bool {
+		return report.Failed[i].Row < report.Failed[j].Row
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// addImportBatch adds the rows' users and records the results in the report.
+// If the whole batch fails, all its rows are reported with the same error.
+func (h UserHandler) addImportBatch(r *http.Request, batch []importRow, report *ImportReport) {
+	users := make([]User, 0, len(batch))
+	for _, row := range batch {
+		users = append(users, row.user)
+	}
+
+	userErrs, err := h.storage.AddBatch(r.Context(), users)
+	if err != nil {
+		log.Println("Error while importing users:", err)
+
+		for _, row := range batch {
+			report.Failed = append(report.Failed, newImportFailure(row.number, err))
+		}
+		return
+	}
+
+	for i, row := range batch {
+		if userErrs[i] != nil {
+			report.Failed = append(report.Failed, newImportFailure(row.number, userErrs[i]))
+			continue
+		}
+
+		report.Imported++
+	}
+}
+
+// ExportUsers streams all users as NDJSON or CSV. Users are read in pages, so the whole list is never kept in memory.
+func (h UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request, params ExportUsersParams) {
+	format := exportFormatNDJSON
+	if params.Format != nil {
+		format = *params.Format
+	}
+
+	var writer userExportWriter
+	switch format {
+	case exportFormatNDJSON:
+		writer = newNDJSONExportWriter(w)
+	case exportFormatCSV:
+		writer = newCSVExportWriter(w)
+	default:
+		writeError(w, fmt.Errorf("%w: %q", errInvalidExportFormat, format))
+		return
+	}
+
+	// The first page is read before writing anything, so the client still gets a proper error if it fails.
+	users, err := h.storage.All(r.Context(), UserFilter{Limit: exportBatchSize})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", writer.ContentType())
+
+	err = writer.WriteHeader()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for {
+		for _, u := range users {
+			err = writer.Write(u)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+		}
+
+		err = writer.Flush()
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if len(users) < exportBatchSize {
+			return
+		}
+
+		// The status is already sent, so a failure can only cut the stream short.
+		users, err = h.storage.All(r.Context(), UserFilter{
+			AfterID: users[len(users)-1].ID(),
+			Limit:   exportBatchSize,
+		})
+		if err != nil {
+			log.Println("Error while exporting users:", err)
+			return
+		}
+	}
+}
+
+func newImportFailure(rowNumber int, err error) ImportFailure {
+	return ImportFailure{
+		Row:     rowNumber,
+		Problem: problemFromError(err),
+	}
+}
+
+type importRow struct {
+	number    int
+	firstName string
+	lastName  string
+	email     string
+	user      User
+	// err is set for rows that can't be parsed or validated. It doesn't stop the import.
+	err error
+}
+
+// importRowReader returns io.EOF after the last row. Other errors stop the import.
+type importRowReader interface {
+	Read() (importRow, error)
+}
+
+func newImportRowReader(r *http.Request) (importRowReader, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnsupportedMediaType, err)
+	}
+
+	switch mediaType {
+	case contentTypeCSV:
+		return newCSVImportReader(r.Body)
+	case contentTypeNDJSON:
+		return newNDJSONImportReader(r.Body), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedMediaType, mediaType)
+	}
+}
+
+type csvImportReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	number  int
+}
+
+func newCSVImportReader(body io.Reader) (*csvImportReader, error) {
+	r := csv.NewReader(body)
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading the CSV header: %v", errInvalidRequestBody, err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[column] = i
+	}
+
+	if _, ok := columns["email"]; !ok {
+		return nil, fmt.Errorf("%w: the CSV header must contain the email column", errInvalidRequestBody)
+	}
+
+	return &csvImportReader{
+		r:       r,
+		columns: columns,
+	}, nil
+}
+
+func (c *csvImportReader) Read() (importRow, error) {
+	record, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return importRow{}, io.EOF
+	}
+
+	c.number++
+	row := importRow{number: c.number}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		row.err = fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+		return row, nil
+	}
+	if err != nil {
+		return importRow{}, err
+	}
+
+	row.firstName = c.field(record, "first_name")
+	row.lastName = c.field(record, "last_name")
+	row.email = c.field(record, "email")
+
+	return row, nil
+}
+
+func (c *csvImportReader) field(record []string, column string) string {
+	i, ok := c.columns[column]
+	if !ok {
+		return ""
+	}
+
+	return record[i]
+}
+
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	number  int
+}
+
+func newNDJSONImportReader(body io.Reader) *ndjsonImportReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxNDJSONLineSize)
+
+	return &ndjsonImportReader{
+		scanner: scanner,
+	}
+}
+
+func (n *ndjsonImportReader) Read() (importRow, error) {
+	for n.scanner.Scan() {
+		n.number++
+
+		line := n.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		row := importRow{number: n.number}
+
+		var req PostUserRequest
+		err := json.Unmarshal(line, &req)
+		if err != nil {
+			row.err = fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+			return row, nil
+		}
+
+		row.firstName = req.FirstName
+		row.lastName = req.LastName
+		row.email = req.Email
+
+		return row, nil
+	}
+
+	err := n.scanner.Err()
+	if err != nil {
+		return importRow{}, fmt.Errorf("%w: line %d: %v", errInvalidRequestBody, n.number+1, err)
+	}
+
+	return importRow{}, io.EOF
+}
+
+type userExportWriter interface {
+	ContentType() string
+	WriteHeader() error
+	Write(u User) error
+	Flush() error
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONExportWriter(w io.Writer) ndjsonExportWriter {
+	return ndjsonExportWriter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (n ndjsonExportWriter) ContentType() string {
+	return contentTypeNDJSON
+}
+
+func (n ndjsonExportWriter) WriteHeader() error {
+	return nil
+}
+
+func (n ndjsonExportWriter) Write(u User) error {
+	return n.encoder.Encode(newUserResponse(u))
+}
+
+func (n ndjsonExportWriter) Flush() error {
+	return nil
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) csvExportWriter {
+	return csvExportWriter{
+		w: csv.NewWriter(w),
+	}
+}
+
+func (c csvExportWriter) ContentType() string {
+	return contentTypeCSV
+}
+
+func (c csvExportWriter) WriteHeader() error {
+	return c.w.Write(csvColumns)
+}
+
+func (c csvExportWriter) Write(u User) error {
+	email := u.PrimaryEmail()
+
+	return c.w.Write([]string{
+		strconv.Itoa(u.ID()),
+		u.FirstName(),
+		u.LastName(),
+		email.Address(),
+		strconv.FormatBool(email.Verified()),
+		u.CreatedAt().Format(time.RFC3339),
+		u.UpdatedAt().Format(time.RFC3339),
+	})
+}
+
+func (c csvExportWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,359 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserHandler_ImportUsers(t *testing.T) {
+	testCases := []struct {
+		Name               string
+		ContentType        string
+		Body               string
+		ExpectedStatusCode int
+		ExpectedType       string
+		ExpectedImported   int
+		ExpectedFailed     map[int]string
+		// ExpectedError is the problem type of the report's error.
+		ExpectedError string
+	}{
+		{
+			Name:        "csv",
+			ContentType: "text/csv; charset=utf-8",
+			Body: "email,first_name,last_name,ignored\n" +
+				"john@example.com,John,Doe,x\n" +
+				"JOHN@example.com,Jack,Doe,x\n" +
+				"jane@example.com,,,x\n" +
+				"jane,Jane,Doe,x\n" +
+				"jack@example.com,Jack\n" +
+				"jill@example.com,Jill,,x\n",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedImported:   2,
+			ExpectedFailed: map[int]string{
+				2: problemTypeEmailAlreadyExists,
+				3: problemTypeValidationFailed,
+				4: problemTypeValidationFailed,
+				5: problemTypeInvalidRequestBody,
+			},
+		},
+		{
+			Name:        "ndjson",
+			ContentType: "application/x-ndjson",
+			Body: `{"first_name": "John", "last_name": "Doe", "email": "john@example.com"}` + "\n" +
+				`{"first_name": "Jack", "email": "john@example.com"}` + "\n" +
+				"\n" +
+				`{"first_name": "Jane", ` + "\n" +
+				`{"email": "jane@example.com"}` + "\n" +
+				`{"first_name": "Jill", "email": "jill@example.com"}`,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedImported:   2,
+			ExpectedFailed: map[int]string{
+				2: problemTypeEmailAlreadyExists,
+				4: problemTypeInvalidRequestBody,
+				5: problemTypeValidationFailed,
+			},
+		},
+		{
+			Name:               "more_rows_than_a_batch",
+			ContentType:        "text/csv",
+			Body:               csvImportBody(importBatchSize*2 + 1),
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedImported:   importBatchSize*2 + 1,
+		},
+		{
+			Name:        "ndjson_line_too_long",
+			ContentType: "application/x-ndjson",
+			Body: ndjsonImportBody(importBatchSize+1) +
+				`{"first_name": "` + strings.Repeat("x", maxNDJSONLineSize) + `", "email": "jill@example.com"}` + "\n" +
+				`{"first_name": "Jack", "email": "jack@example.com"}` + "\n",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedImported:   importBatchSize + 1,
+			ExpectedError:      problemTypeInvalidRequestBody,
+		},
+		{
+			Name:               "csv_without_email_column",
+			ContentType:        "text/csv",
+			Body:               "first_name,last_name\nJohn,Doe\n",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeInvalidRequestBody,
+		},
+		{
+			Name:               "unsupported_content_type",
+			ContentType:        "application/json",
+			Body:               `[{"first_name": "John", "email": "john@example.com"}]`,
+			ExpectedStatusCode: http.StatusUnsupportedMediaType,
+			ExpectedType:       problemTypeUnsupportedMediaType,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			handler, publisher := newTestHandler()
+
+			req := httptest.NewRequest(http.MethodPost, "/users:import", strings.NewReader(tc.Body))
+			req.Header.Set("Content-Type", tc.ContentType)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.ExpectedType != "" {
+				var problem Problem
+				err := json.NewDecoder(rec.Body).Decode(&problem)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if problem.Type != tc.ExpectedType {
+					t.Errorf("expected problem type %q, got %q", tc.ExpectedType, problem.Type)
+				}
+				return
+			}
+
+			var report ImportReport
+			err := json.NewDecoder(rec.Body).Decode(&report)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var errorType string
+			if report.Error != nil {
+				errorType = report.Error.Type
+			}
+
+			if errorType != tc.ExpectedError {
+				t.Errorf("expected error %q, got %q", tc.ExpectedError, errorType)
+			}
+
+			if report.Imported != tc.ExpectedImported {
+				t.Errorf("expected %d users imported, got %d", tc.ExpectedImported, report.Imported)
+			}
+
+			if len(report.Failed) != len(tc.ExpectedFailed) {
+				t.Fatalf("expected %d failed rows, got %+v", len(tc.ExpectedFailed), report.Failed)
+			}
+
+			for i, f := range report.Failed {
+				if i > 0 && report.Failed[i-1].Row >= f.Row {
+					t.Errorf("expected failed rows in order, got %d after %d", f.Row, report.Failed[i-1].Row)
+				}
+
+				if f.Problem.Type != tc.ExpectedFailed[f.Row] {
+					t.Errorf("expected problem type %q for row %d, got %q", tc.ExpectedFailed[f.Row], f.Row, f.Problem.Type)
+				}
+			}
+
+			if event := publisher.lastEvent(); event != nil {
+				t.Errorf("expected no events for imported users, got %#v", event)
+			}
+		})
+	}
+}
+
+func TestUserHandler_ExportUsers(t *testing.T) {
+	handler, _ := newTestHandler()
+
+	// More than a single page, to check the users are paged through.
+	userCount := exportBatchSize*2 + 1
+	importUsers(t, handler, csvImportBody(userCount))
+
+	testCases := []struct {
+		Name                string
+		Query               string
+		ExpectedStatusCode  int
+		ExpectedContentType string
+	}{
+		{
+			Name:                "default_format",
+			Query:               "",
+			ExpectedStatusCode:  http.StatusOK,
+			ExpectedContentType: contentTypeNDJSON,
+		},
+		{
+			Name:                "ndjson",
+			Query:               "?format=ndjson",
+			ExpectedStatusCode:  http.StatusOK,
+			ExpectedContentType: contentTypeNDJSON,
+		},
+		{
+			Name:                "csv",
+			Query:               "?format=csv",
+			ExpectedStatusCode:  http.StatusOK,
+			ExpectedContentType: contentTypeCSV,
+		},
+		{
+			Name:               "invalid_format",
+			Query:              "?format=xml",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users:export"+tc.Query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.ExpectedStatusCode != http.StatusOK {
+				var problem Problem
+				err := json.NewDecoder(rec.Body).Decode(&problem)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if problem.Type != problemTypeInvalidExportFormat {
+					t.Errorf("expected problem type %q, got %q", problemTypeInvalidExportFormat, problem.Type)
+				}
+				return
+			}
+
+			if contentType := rec.Header().Get("Content-Type"); contentType != tc.ExpectedContentType {
+				t.Fatalf("expected content type %q, got %q", tc.ExpectedContentType, contentType)
+			}
+
+			var addresses []string
+			if tc.ExpectedContentType == contentTypeCSV {
+				addresses = csvExportAddresses(t, rec.Body.String())
+			} else {
+				addresses = ndjsonExportAddresses(t, rec.Body.String())
+			}
+
+			if len(addresses) != userCount {
+				t.Fatalf("expected %d users, got %d", userCount, len(addresses))
+			}
+
+			for i, a := range addresses {
+				expected := fmt.Sprintf("user-%d@example.com", i)
+				if a != expected {
+					t.Fatalf("expected e-mail %q at position %d, got %q", expected, i, a)
+				}
+			}
+		})
+	}
+}
+
+func TestUserHandler_ExportUsers_csvCanBeImported(t *testing.T) {
+	source, _ := newTestHandler()
+	importUsers(t, source, csvImportBody(3))
+
+	req := httptest.NewRequest(http.MethodGet, "/users:export?format=csv", nil)
+	rec := httptest.NewRecorder()
+	source.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	target, _ := newTestHandler()
+	report := importUsers(t, target, rec.Body.String())
+
+	if report.Imported != 3 || len(report.Failed) != 0 {
+		t.Errorf("expected 3 users imported without failures, got %+v", report)
+	}
+}
+
+// csvImportBody returns a CSV with n valid users, with e-mails numbered from 0.
+func csvImportBody(n int) string {
+	var b strings.Builder
+	b.WriteString("first_name,last_name,email\n")
+
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "John,Doe,user-%d@example.com\n", i)
+	}
+
+	return b.String()
+}
+
+func ndjsonImportBody(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `{"first_name": "John", "last_name": "Doe", "email": "user-%d@example.com"}`+"\n", i)
+	}
+
+	return b.String()
+}
+
+func importUsers(t *testing.T, handler http.Handler, body string) ImportReport {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/users:import", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentTypeCSV)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var report ImportReport
+	err := json.NewDecoder(rec.Body).Decode(&report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return report
+}
+
+func ndjsonExportAddresses(t *testing.T, body string) []string {
+	t.Helper()
+
+	var addresses []string
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var user UserResponse
+		err := json.Unmarshal(scanner.Bytes(), &user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(user.Emails) != 1 {
+			t.Fatalf("expected 1 e-mail, got %d", len(user.Emails))
+		}
+
+		addresses = append(addresses, user.Emails[0].Address)
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return addresses
+}
+
+func csvExportAddresses(t *testing.T, body string) []string {
+	t.Helper()
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvColumns, ",") {
+		t.Fatalf("expected the header %v, got %v", csvColumns, records)
+	}
+
+	var addresses []string
+	for _, record := range records[1:] {
+		addresses = append(addresses, record[3])
+	}
+
+	return addresses
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// OrganizationIDHeader selects the organization the request works on. The service trusts it,
+// so it must be set by a gateway authenticating the caller, not by end users.
+const OrganizationIDHeader = "X-Organization-ID"
+
+// OrganizationMiddleware scopes the request's context to the organization from the X-Organization-ID header.
+//
+// Requests without the header pass through unscoped, as POST /emails/verify takes the organization from the token.
+// Other endpoints, for users and webhooks alike, fail then with ErrOrganizationRequired, as the repositories require it.
+func OrganizationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawOrganizationID := r.Header.Get(OrganizationIDHeader)
+		if rawOrganizationID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		organizationID, err := parseOrganizationID(rawOrganizationID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithOrganizationID(r.Context(), organizationID)))
+	})
+}
+
+func parseOrganizationID(rawOrganizationID string) (int, error) {
+	organizationID, err := strconv.Atoi(rawOrganizationID)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidOrganizationID, err)
+	}
+
+	if organizationID <= 0 {
+		return 0, fmt.Errorf("%w: %d", errInvalidOrganizationID, organizationID)
+	}
+
+	return organizationID, nil
+}
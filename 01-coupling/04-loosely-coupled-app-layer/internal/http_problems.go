@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Problem types returned in the "type" field of problem details (RFC 7807).
+const (
+	problemTypeInvalidRequestBody    = "/problems/invalid-request-body"
+	problemTypeInvalidUserID         = "/problems/invalid-user-id"
+	problemTypeInvalidCreatedAfter   = "/problems/invalid-created-after"
+	problemTypeValidationFailed      = "/problems/validation-failed"
+	problemTypeUserNotFound          = "/problems/user-not-found"
+	problemTypeEmailAlreadyExists    = "/problems/email-already-exists"
+	problemTypePreconditionRequired  = "/problems/precondition-required"
+	problemTypePreconditionFailed    = "/problems/precondition-failed"
+	problemTypeEmailNotFound         = "/problems/email-not-found"
+	problemTypeEmailNotVerified      = "/problems/email-not-verified"
+	problemTypeInvalidToken          = "/problems/invalid-verification-token"
+	problemTypeUnsupportedMediaType  = "/problems/unsupported-media-type"
+	problemTypeInvalidExportFormat   = "/problems/invalid-export-format"
+	problemTypeInvalidDeleteMode     = "/problems/invalid-delete-mode"
+	problemTypeInvalidWebhookID      = "/problems/invalid-webhook-id"
+	problemTypeWebhookNotFound       = "/problems/webhook-not-found"
+	problemTypeOrganizationRequired  = "/problems/organization-required"
+	problemTypeInvalidOrganizationID = "/problems/invalid-organization-id"
+)
+
+var (
+	errInvalidRequestBody    = errors.New("invalid request body")
+	errInvalidUserID         = errors.New("invalid user ID")
+	errInvalidCreatedAfter   = errors.New("created_after must be an RFC 3339 date-time")
+	errPreconditionRequired  = errors.New("the If-Match header with the user's ETag is required")
+	errPreconditionFailed    = errors.New("the user has been modified since it was fetched")
+	errUnsupportedMediaType  = errors.New("the Content-Type must be text/csv or application/x-ndjson")
+	errInvalidExportFormat   = errors.New("the format must be ndjson or csv")
+	errInvalidDeleteMode     = errors.New("the mode must be delete or erase")
+	errInvalidWebhookID      = errors.New("invalid webhook ID")
+	errInvalidOrganizationID = errors.New("the organization ID must be a positive integer")
+)
+
+func decodeRequestBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+	}
+
+	return nil
+}
+
+func parseUserID(rawUserID UserID) (int, error) {
+	userID, err := strconv.Atoi(string(rawUserID))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidUserID, err)
+	}
+
+	return userID, nil
+}
+
+// userFilterFromParams parses the query parameters. created_after is parsed here, not by the generated code,
+// so an invalid value gets problem details instead of a plain text error.
+func userFilterFromParams(params GetUsersParams) (UserFilter, error) {
+	if params.CreatedAfter == nil {
+		return UserFilter{}, nil
+	}
+
+	createdAfter, err := time.Parse(time.RFC3339, string(*params.CreatedAfter))
+	if err != nil {
+		return UserFilter{}, fmt.Errorf("%w: %v", errInvalidCreatedAfter, err)
+	}
+
+	return UserFilter{CreatedAfter: createdAfter.UTC()}, nil
+}
+
+// writeError logs the error and responds with the matching problem details.
+// Errors not known to the HTTP layer become an Internal Server Error without details.
+func writeError(w http.ResponseWriter, err error) {
+	log.Println(err)
+
+	problem := problemFromError(err)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+
+	err = json.NewEncoder(w).Encode(problem)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func problemFromError(err error) Problem {
+	switch {
+	case errors.Is(err, errInvalidRequestBody):
+		return newProblem(problemTypeInvalidRequestBody, "Invalid request body", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errInvalidUserID):
+		return newProblem(problemTypeInvalidUserID, "Invalid user ID", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errInvalidCreatedAfter):
+		return newProblem(problemTypeInvalidCreatedAfter, "Invalid created_after", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errUnsupportedMediaType):
+		return newProblem(problemTypeUnsupportedMediaType, "Unsupported media type", http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, errInvalidExportFormat):
+		return newProblem(problemTypeInvalidExportFormat, "Invalid export format", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errInvalidDeleteMode):
+		return newProblem(problemTypeInvalidDeleteMode, "Invalid delete mode", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errInvalidWebhookID):
+		return newProblem(problemTypeInvalidWebhookID, "Invalid webhook ID", http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrOrganizationRequired):
+		return newProblem(problemTypeOrganizationRequired, "Organization required", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errInvalidOrganizationID):
+		return newProblem(problemTypeInvalidOrganizationID, "Invalid organization ID", http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrNameRequired):
+		return newValidationProblem(err, "first_name", "last_name")
+	case errors.Is(err, ErrEmailRequired), errors.Is(err, ErrInvalidEmail):
+		return newValidationProblem(err, "email")
+	case errors.Is(err, ErrInvalidWebhookURL):
+		return newValidationProblem(err, "url")
+	case errors.Is(err, ErrWebhookSecretRequired):
+		return newValidationProblem(err, "secret")
+	case errors.Is(err, ErrInvalidWebhookEventType):
+		return newValidationProblem(err, "event_types")
+	case errors.Is(err, ErrUserNotFound):
+		return newProblem(problemTypeUserNotFound, "User not found", http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrWebhookNotFound):
+		return newProblem(problemTypeWebhookNotFound, "Webhook not found", http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrEmailAlreadyExists):
+		return newProblem(problemTypeEmailAlreadyExists, "E-mail already exists", http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrEmailNotFound):
+		return newProblem(problemTypeEmailNotFound, "E-mail not found", http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrEmailNotVerified):
+		return newProblem(problemTypeEmailNotVerified, "E-mail not verified", http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidVerificationToken), errors.Is(err, ErrVerificationTokenExpired):
+		return newProblem(problemTypeInvalidToken, "Invalid verification token", http.StatusBadRequest, err.Error())
+	case errors.Is(err, errPreconditionRequired):
+		return newProblem(problemTypePreconditionRequired, "Precondition required", http.StatusPreconditionRequired, err.Error())
+	case errors.Is(err, errPreconditionFailed), errors.Is(err, ErrUserModified):
+		return newProblem(problemTypePreconditionFailed, "Precondition failed", http.StatusPreconditionFailed, err.Error())
+	default:
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+		}
+	}
+}
+
+func newProblem(problemType string, title string, status int, detail string) Problem {
+	return Problem{
+		Type:   problemType,
+		Title:  title,
+		Status: status,
+		Detail: &detail,
+	}
+}
+
+// newValidationProblem reports the domain error on all fields it's related to.
+func newValidationProblem(err error, fields ...string) Problem {
+	problem := newProblem(problemTypeValidationFailed, "Validation failed", http.StatusBadRequest, err.Error())
+
+	var fieldErrors []FieldError
+	for _, f := range fields {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   f,
+			Message: err.Error(),
+		})
+	}
+
+	problem.Errors = &fieldErrors
+
+	return problem
+}
@@ -0,0 +1,703 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestUserHandler_PostUser(t *testing.T) {
+	handler, _ := newTestHandler()
+
+	testCases := []struct {
+		Name               string
+		Body               string
+		ExpectedStatusCode int
+		ExpectedType       string
+	}{
+		{
+			Name:               "valid",
+			Body:               `{"first_name": "John", "last_name": "Doe", "email": "john@example.com"}`,
+			ExpectedStatusCode: http.StatusCreated,
+		},
+		{
+			Name:               "existing_email_other_case",
+			Body:               `{"first_name": "Jane", "email": "JOHN@example.com"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeEmailAlreadyExists,
+		},
+		{
+			Name:               "missing_name",
+			Body:               `{"email": "jane@example.com"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeValidationFailed,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tc.Body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.ExpectedType == "" {
+				return
+			}
+
+			var problem Problem
+			err := json.NewDecoder(rec.Body).Decode(&problem)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if problem.Type != tc.ExpectedType {
+				t.Errorf("expected problem type %q, got %q", tc.ExpectedType, problem.Type)
+			}
+		})
+	}
+}
+
+func TestUserHandler_PostUser_publishesUserSignedUp(t *testing.T) {
+	handler, publisher := newTestHandler()
+
+	testCases := []struct {
+		Name          string
+		Body          string
+		ExpectedEvent UserSignedUp
+	}{
+		{
+			Name: "consent",
+			Body: `{"first_name": "John", "email": "John@example.com", "product_news_consent": true}`,
+			ExpectedEvent: UserSignedUp{
+				ID:                 "1",
+				OrganizationID:     testOrganizationID,
+				Email:              "John@example.com",
+				ProductNewsConsent: true,
+			},
+		},
+		{
+			Name: "no_consent",
+			Body: `{"first_name": "Jane", "email": "jane@example.com"}`,
+			ExpectedEvent: UserSignedUp{
+				ID:             "2",
+				OrganizationID: testOrganizationID,
+				Email:          "jane@example.com",
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tc.Body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+			}
+
+			event, ok := publisher.lastEvent().(UserSignedUp)
+			if !ok {
+				t.Fatalf("expected UserSignedUp to be published, got %#v", publisher.lastEvent())
+			}
+
+			if event != tc.ExpectedEvent {
+				t.Errorf("expected %#v, got %#v", tc.ExpectedEvent, event)
+			}
+		})
+	}
+}
+
+func TestUserHandler_mailerFailure(t *testing.T) {
+	publisher := &eventPublisherStub{}
+
+	h := HTTPHandler{
+		UserHandler: NewUserHandler(
+			NewMemoryUserStorage(),
+			NewVerificationTokens([]byte("secret"), time.Hour),
+			failingMailer{},
+			publisher,
+		),
+		WebhookHandler: NewWebhookHandler(NewMemoryWebhookStorage()),
+	}
+
+	r := chi.NewRouter()
+	r.Use(OrganizationMiddleware)
+	handler := withTestOrganization(HandlerFromMux(h, r))
+
+	userID := postTestUser(t, handler, publisher, `{"first_name": "John", "email": "john@example.com"}`)
+
+	rec := serveTestRequest(handler, http.MethodPost, "/users/"+userID+"/emails", `{"address": "jack@example.com"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+}
+
+func TestUserHandler_GetUsers(t *testing.T) {
+	handler, _ := newTestHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"first_name": "John", "email": "john@example.com"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	testCases := []struct {
+		Name               string
+		Query              string
+		ExpectedStatusCode int
+		ExpectedUsers      int
+	}{
+		{
+			Name:               "no_filter",
+			Query:              "",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedUsers:      1,
+		},
+		{
+			Name:               "created_after_past",
+			Query:              "?created_after=2000-01-01T00:00:00Z",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedUsers:      1,
+		},
+		{
+			Name:               "created_after_future",
+			Query:              "?created_after=2100-01-01T00:00:00%2B02:00",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedUsers:      0,
+		},
+		{
+			Name:               "invalid_created_after",
+			Query:              "?created_after=yesterday",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users"+tc.Query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.ExpectedStatusCode != http.StatusOK {
+				var problem Problem
+				err := json.NewDecoder(rec.Body).Decode(&problem)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if problem.Type != problemTypeInvalidCreatedAfter {
+					t.Errorf("expected problem type %q, got %q", problemTypeInvalidCreatedAfter, problem.Type)
+				}
+				return
+			}
+
+			var users []UserResponse
+			err := json.NewDecoder(rec.Body).Decode(&users)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(users) != tc.ExpectedUsers {
+				t.Fatalf("expected %d users, got %d", tc.ExpectedUsers, len(users))
+			}
+
+			for _, u := range users {
+				if u.CreatedAt.IsZero() || !u.UpdatedAt.Equal(u.CreatedAt) {
+					t.Errorf("expected matching non-zero timestamps, got %v and %v", u.CreatedAt, u.UpdatedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestUserHandler_DeleteUser(t *testing.T) {
+	handler, publisher := newTestHandler()
+
+	testCases := []struct {
+		Name               string
+		Query              string
+		UserExists         bool
+		ExpectedStatusCode int
+		ExpectedType       string
+	}{
+		{
+			Name:               "delete",
+			Query:              "",
+			UserExists:         true,
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "delete_missing_user",
+			Query:              "?mode=delete",
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "erase",
+			Query:              "?mode=erase",
+			UserExists:         true,
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "erase_missing_user",
+			Query:              "?mode=erase",
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedType:       problemTypeUserNotFound,
+		},
+		{
+			Name:               "invalid_mode",
+			Query:              "?mode=archive",
+			UserExists:         true,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeInvalidDeleteMode,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			userID := "100"
+			if tc.UserExists {
+				userID = postTestUser(t, handler, publisher, fmt.Sprintf(`{"first_name": "John", "email": "%s@example.com"}`, tc.Name))
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, "/users/"+userID+tc.Query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.ExpectedType != "" {
+				var problem Problem
+				err := json.NewDecoder(rec.Body).Decode(&problem)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if problem.Type != tc.ExpectedType {
+					t.Errorf("expected problem type %q, got %q", tc.ExpectedType, problem.Type)
+				}
+				return
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/users/"+userID, nil)
+			rec = httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("expected the user to be gone, got status %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestUserHandler_RestoreUser(t *testing.T) {
+	handler, publisher := newTestHandler()
+
+	userID := postTestUser(t, handler, publisher, `{"first_name": "John", "email": "john@example.com"}`)
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "restore_not_deleted",
+			Method:             http.MethodPost,
+			Path:               "/users/" + userID + "/restore",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "delete",
+			Method:             http.MethodDelete,
+			Path:               "/users/" + userID,
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "get_deleted",
+			Method:             http.MethodGet,
+			Path:               "/users/" + userID,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "restore",
+			Method:             http.MethodPost,
+			Path:               "/users/" + userID + "/restore",
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "get_restored",
+			Method:             http.MethodGet,
+			Path:               "/users/" + userID,
+			ExpectedStatusCode: http.StatusOK,
+		},
+	}
+
+	// The steps depend on each other, so they run in order.
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.Method, tc.Path, nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tc.ExpectedStatusCode {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.Name, tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestUserHandler_GetUserDataExport(t *testing.T) {
+	handler, publisher := newTestHandler()
+
+	userID := postTestUser(t, handler, publisher, `{"first_name": "John", "last_name": "Doe", "email": "john@example.com"}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+userID+"/data-export", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	expectedDisposition := fmt.Sprintf(`attachment; filename="user-%s-data-export.json"`, userID)
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != expectedDisposition {
+		t.Errorf("expected Content-Disposition %q, got %q", expectedDisposition, disposition)
+	}
+
+	var export UserDataExport
+	err := json.NewDecoder(rec.Body).Decode(&export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if export.ExportedAt.IsZero() {
+		t.Errorf("expected the export time to be set")
+	}
+
+	if export.User.DisplayName != "John Doe" {
+		t.Errorf("expected display name %q, got %q", "John Doe", export.User.DisplayName)
+	}
+
+	if len(export.User.Emails) != 1 || export.User.Emails[0].Address != "john@example.com" {
+		t.Errorf("expected the e-mail john@example.com, got %+v", export.User.Emails)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/users/"+userID+"?mode=erase", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/users/"+userID+"/data-export", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected no data to export after erasure, got status %d", rec.Code)
+	}
+}
+
+func TestUserHandler_organizations(t *testing.T) {
+	handler, publisher := newUnscopedTestHandler()
+
+	organizationHeaders := func(organizationID int) map[string]string {
+		return map[string]string{OrganizationIDHeader: strconv.Itoa(organizationID)}
+	}
+
+	body := `{"first_name": "John", "email": "john@example.com"}`
+
+	rec := serveTestRequest(handler, http.MethodPost, "/users", body, organizationHeaders(testOrganizationID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	userID := publisher.lastEvent().(UserSignedUp).ID
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		Body               string
+		Headers            map[string]string
+		ExpectedStatusCode int
+		ExpectedType       string
+	}{
+		{
+			Name:               "get_in_same_organization",
+			Method:             http.MethodGet,
+			Path:               "/users/" + userID,
+			Headers:            organizationHeaders(testOrganizationID),
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "get_in_other_organization",
+			Method:             http.MethodGet,
+			Path:               "/users/" + userID,
+			Headers:            organizationHeaders(otherTestOrganizationID),
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedType:       problemTypeUserNotFound,
+		},
+		{
+			Name:               "delete_in_other_organization",
+			Method:             http.MethodDelete,
+			Path:               "/users/" + userID,
+			Headers:            organizationHeaders(otherTestOrganizationID),
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "still_exists_after_delete_in_other_organization",
+			Method:             http.MethodGet,
+			Path:               "/users/" + userID,
+			Headers:            organizationHeaders(testOrganizationID),
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "same_email_in_other_organization",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			Body:               body,
+			Headers:            organizationHeaders(otherTestOrganizationID),
+			ExpectedStatusCode: http.StatusCreated,
+		},
+		{
+			Name:               "same_email_twice_in_other_organization",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			Body:               body,
+			Headers:            organizationHeaders(otherTestOrganizationID),
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeEmailAlreadyExists,
+		},
+		{
+			Name:               "missing_organization",
+			Method:             http.MethodGet,
+			Path:               "/users/" + userID,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeOrganizationRequired,
+		},
+		{
+			Name:               "invalid_organization",
+			Method:             http.MethodGet,
+			Path:               "/users/" + userID,
+			Headers:            map[string]string{OrganizationIDHeader: "abc"},
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeInvalidOrganizationID,
+		},
+		{
+			Name:               "non_positive_organization",
+			Method:             http.MethodGet,
+			Path:               "/users/" + userID,
+			Headers:            map[string]string{OrganizationIDHeader: "0"},
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeInvalidOrganizationID,
+		},
+	}
+
+	// The steps depend on each other, so they run in order.
+	for _, tc := range testCases {
+		rec := serveTestRequest(handler, tc.Method, tc.Path, tc.Body, tc.Headers)
+
+		if rec.Code != tc.ExpectedStatusCode {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.Name, tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+		}
+
+		if tc.ExpectedType == "" {
+			continue
+		}
+
+		var problem Problem
+		err := json.NewDecoder(rec.Body).Decode(&problem)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if problem.Type != tc.ExpectedType {
+			t.Errorf("%s: expected problem type %q, got %q", tc.Name, tc.ExpectedType, problem.Type)
+		}
+	}
+
+	for _, organizationID := range []int{testOrganizationID, otherTestOrganizationID} {
+		rec := serveTestRequest(handler, http.MethodGet, "/users", "", organizationHeaders(organizationID))
+
+		var users []UserResponse
+		err := json.NewDecoder(rec.Body).Decode(&users)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(users) != 1 {
+			t.Errorf("expected 1 user listed in organization %d, got %d", organizationID, len(users))
+		}
+	}
+}
+
+func TestUserHandler_VerifyEmail_usesOrganizationFromToken(t *testing.T) {
+	handler, _ := newUnscopedTestHandler()
+
+	body := `{"first_name": "John", "email": "john@example.com"}`
+
+	for _, organizationID := range []int{testOrganizationID, otherTestOrganizationID} {
+		rec := serveTestRequest(handler, http.MethodPost, "/users", body, map[string]string{OrganizationIDHeader: strconv.Itoa(organizationID)})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Signed with the secret of newTestHandler's tokens.
+	token, err := NewVerificationTokens([]byte("secret"), time.Hour).New(otherTestOrganizationID, "john@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sent without X-Organization-ID, like when following the link from the e-mail.
+	rec := serveTestRequest(handler, http.MethodPost, "/emails/verify", fmt.Sprintf(`{"token": %q}`, token), nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	expectedVerified := map[int]bool{
+		testOrganizationID:      false,
+		otherTestOrganizationID: true,
+	}
+
+	for organizationID, expected := range expectedVerified {
+		rec := serveTestRequest(handler, http.MethodGet, "/users", "", map[string]string{OrganizationIDHeader: strconv.Itoa(organizationID)})
+
+		var users []UserResponse
+		err := json.NewDecoder(rec.Body).Decode(&users)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(users) != 1 || users[0].Emails[0].Verified != expected {
+			t.Errorf("expected the e-mail in organization %d to be verified: %v, got %+v", organizationID, expected, users)
+		}
+	}
+}
+
+// postTestUser adds a user through the API and returns its ID, taken from the published UserSignedUp.
+func postTestUser(t *testing.T, handler http.Handler, publisher *eventPublisherStub, body string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	event, ok := publisher.lastEvent().(UserSignedUp)
+	if !ok {
+		t.Fatalf("expected UserSignedUp to be published, got %#v", publisher.lastEvent())
+	}
+
+	return event.ID
+}
+
+// newTestHandler returns the users API backed by the in-memory storage, so it runs without a database.
+// Requests without the X-Organization-ID header work on testOrganizationID's users.
+func newTestHandler() (http.Handler, *eventPublisherStub) {
+	handler, publisher := newUnscopedTestHandler()
+	return withTestOrganization(handler), publisher
+}
+
+// newUnscopedTestHandler is newTestHandler leaving requests without X-Organization-ID as they are.
+func newUnscopedTestHandler() (http.Handler, *eventPublisherStub) {
+	publisher := &eventPublisherStub{}
+
+	h := HTTPHandler{
+		UserHandler: NewUserHandler(
+			NewMemoryUserStorage(),
+			NewVerificationTokens([]byte("secret"), time.Hour),
+			NewWriterMailer(io.Discard),
+			publisher,
+		),
+		WebhookHandler: NewWebhookHandler(NewMemoryWebhookStorage()),
+	}
+
+	r := chi.NewRouter()
+	r.Use(OrganizationMiddleware)
+
+	return HandlerFromMux(h, r), publisher
+}
+
+// withTestOrganization sets X-Organization-ID to testOrganizationID on requests without it.
+func withTestOrganization(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(OrganizationIDHeader) == "" {
+			r.Header.Set(OrganizationIDHeader, strconv.Itoa(testOrganizationID))
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// eventPublisherStub keeps published events in memory.
+type eventPublisherStub struct {
+	lock   sync.Mutex
+	events []interface{}
+}
+
+func (p *eventPublisherStub) Publish(ctx context.Context, event interface{}) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+func (p *eventPublisherStub) lastEvent() interface{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.events) == 0 {
+		return nil
+	}
+
+	return p.events[len(p.events)-1]
+}
+
+// failingMailer fails to send any e-mail.
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, mail Mail) error {
+	return errors.New("mail server unavailable")
+}
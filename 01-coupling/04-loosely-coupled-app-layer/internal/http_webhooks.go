@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookDeliveryLogLimit is how many latest deliveries GET /webhooks/{id}/deliveries returns.
+const webhookDeliveryLogLimit = 100
+
+// HTTPHandler serves the whole HTTP API. Users and webhooks are handled separately, as they don't share dependencies.
+type HTTPHandler struct {
+	UserHandler
+	WebhookHandler
+}
+
+type WebhookHandler struct {
+	storage WebhookRepository
+	now     func() time.Time
+}
+
+func NewWebhookHandler(storage WebhookRepository) WebhookHandler {
+	return WebhookHandler{
+		storage: storage,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+func (h WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.storage.Subscriptions(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	webhooksResponse := WebhooksResponse{}
+	for _, s := range subscriptions {
+		webhooksResponse = append(webhooksResponse, newWebhookResponse(s))
+	}
+
+	err = json.NewEncoder(w).Encode(webhooksResponse)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h WebhookHandler) PostWebhook(w http.ResponseWriter, r *http.Request) {
+	var postWebhookRequest PostWebhookRequest
+	err := decodeRequestBody(r, &postWebhookRequest)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	eventTypes := make([]string, 0, len(postWebhookRequest.EventTypes))
+	for _, t := range postWebhookRequest.EventTypes {
+		eventTypes = append(eventTypes, string(t))
+	}
+
+	subscription, err := NewWebhookSubscription(postWebhookRequest.Url, postWebhookRequest.Secret, eventTypes, h.now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	subscription.ID, err = h.storage.AddSubscription(r.Context(), subscription)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(newWebhookResponse(subscription))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (h WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request, rawWebhookID WebhookID) {
+	webhookID, err := parseWebhookID(rawWebhookID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = h.storage.DeleteSubscription(r.Context(), webhookID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request, rawWebhookID WebhookID) {
+	webhookID, err := parseWebhookID(rawWebhookID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	deliveries, err := h.storage.Deliveries(r.Context(), webhookID, webhookDeliveryLogLimit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	deliveriesResponse := WebhookDeliveriesResponse{}
+	for _, d := range deliveries {
+		deliveryResponse, err := newWebhookDeliveryResponse(d)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		deliveriesResponse = append(deliveriesResponse, deliveryResponse)
+	}
+
+	err = json.NewEncoder(w).Encode(deliveriesResponse)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func parseWebhookID(rawWebhookID WebhookID) (int, error) {
+	webhookID, err := strconv.Atoi(string(rawWebhookID))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidWebhookID, err)
+	}
+
+	return webhookID, nil
+}
+
+// newWebhookResponse leaves out the secret, as only receivers need it.
+func newWebhookResponse(s WebhookSubscription) WebhookResponse {
+	eventTypes := make([]WebhookEventType, 0, len(s.EventTypes))
+	for _, t := range s.EventTypes {
+		eventTypes = append(eventTypes, WebhookEventType(t))
+	}
+
+	return WebhookResponse{
+		Id:         s.ID,
+		Url:        s.URL,
+		EventTypes: eventTypes,
+		CreatedAt:  s.CreatedAt,
+	}
+}
+
+func newWebhookDeliveryResponse(d WebhookDelivery) (WebhookDeliveryResponse, error) {
+	var payload map[string]interface{}
+	err := json.Unmarshal(d.Payload, &payload)
+	if err != nil {
+		return WebhookDeliveryResponse{}, fmt.Errorf("could not unmarshal payload of webhook delivery %d: %w", d.ID, err)
+	}
+
+	response := WebhookDeliveryResponse{
+		Id:        d.ID,
+		EventType: WebhookEventType(d.EventType),
+		Payload:   payload,
+		Status:    WebhookDeliveryResponseStatus(d.Status),
+		Attempts:  d.Attempts,
+		CreatedAt: d.CreatedAt,
+	}
+
+	if d.Status == WebhookDeliveryPending {
+		response.NextAttemptAt = &d.NextAttemptAt
+	}
+
+	if d.LastStatusCode != 0 {
+		response.LastStatusCode = &d.LastStatusCode
+	}
+
+	if d.LastError != "" {
+		response.LastError = &d.LastError
+	}
+
+	if !d.DeliveredAt.IsZero() {
+		response.DeliveredAt = &d.DeliveredAt
+	}
+
+	return response, nil
+}
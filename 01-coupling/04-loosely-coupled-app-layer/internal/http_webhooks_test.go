@@ -0,0 +1,453 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWebhookHandler_PostWebhook(t *testing.T) {
+	handler, _ := newTestWebhookHandler()
+
+	testCases := []struct {
+		Name               string
+		Body               string
+		ExpectedStatusCode int
+		ExpectedType       string
+		ExpectedField      string
+	}{
+		{
+			Name:               "valid",
+			Body:               `{"url": "https://example.com/webhooks", "secret": "secret", "event_types": ["user.created"]}`,
+			ExpectedStatusCode: http.StatusCreated,
+		},
+		{
+			Name:               "invalid_url",
+			Body:               `{"url": "example.com", "secret": "secret", "event_types": ["user.created"]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeValidationFailed,
+			ExpectedField:      "url",
+		},
+		{
+			Name:               "missing_secret",
+			Body:               `{"url": "https://example.com/webhooks", "event_types": ["user.created"]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeValidationFailed,
+			ExpectedField:      "secret",
+		},
+		{
+			Name:               "unknown_event_type",
+			Body:               `{"url": "https://example.com/webhooks", "secret": "secret", "event_types": ["user.updated"]}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeValidationFailed,
+			ExpectedField:      "event_types",
+		},
+		{
+			Name:               "invalid_body",
+			Body:               `{"url":`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeInvalidRequestBody,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(tc.Body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.ExpectedType == "" {
+				var webhook WebhookResponse
+				err := json.NewDecoder(rec.Body).Decode(&webhook)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if webhook.Id == 0 || webhook.Url != "https://example.com/webhooks" {
+					t.Errorf("expected the created webhook, got %+v", webhook)
+				}
+				return
+			}
+
+			var problem Problem
+			err := json.NewDecoder(rec.Body).Decode(&problem)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if problem.Type != tc.ExpectedType {
+				t.Errorf("expected problem type %q, got %q", tc.ExpectedType, problem.Type)
+			}
+
+			if tc.ExpectedField != "" && (problem.Errors == nil || (*problem.Errors)[0].Field != tc.ExpectedField) {
+				t.Errorf("expected an error of field %s, got %+v", tc.ExpectedField, problem.Errors)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_userLifecycle(t *testing.T) {
+	handler, dispatcher := newTestWebhookHandler()
+
+	receiver := newWebhookReceiver(t, http.StatusOK)
+
+	rec := serveTestRequest(handler, http.MethodPost, "/webhooks", fmt.Sprintf(
+		`{"url": "%s", "secret": "secret", "event_types": ["user.created", "user.renamed", "user.deleted"]}`,
+		receiver.URL,
+	), nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var webhook WebhookResponse
+	err := json.NewDecoder(rec.Body).Decode(&webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec = serveTestRequest(handler, http.MethodPost, "/users", `{"first_name": "John", "last_name": "Doe", "email": "john@example.com"}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	rec = serveTestRequest(handler, http.MethodGet, "/users/1", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	rec = serveTestRequest(handler, http.MethodPatch, "/users/1", `{"first_name": "Jack"}`, map[string]string{
+		"If-Match": rec.Header().Get("ETag"),
+	})
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	rec = serveTestRequest(handler, http.MethodDelete, "/users/1", "", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	// Deleting the user again does nothing, so nothing is sent.
+	rec = serveTestRequest(handler, http.MethodDelete, "/users/1", "", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	_, err = dispatcher.Dispatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRequests := []struct {
+		EventType string
+		Data      string
+	}{
+		{
+			EventType: WebhookEventUserCreated,
+			Data:      `{"id":"1","organization_id":1,"email":"john@example.com","product_news_consent":false}`,
+		},
+		{
+			EventType: WebhookEventUserRenamed,
+			Data:      `{"id":"1","organization_id":1,"first_name":"Jack","last_name":"Doe","display_name":"Jack Doe"}`,
+		},
+		{
+			EventType: WebhookEventUserDeleted,
+			Data:      `{"id":"1","organization_id":1,"erased":false}`,
+		},
+	}
+
+	requests := receiver.requests()
+	if len(requests) != len(expectedRequests) {
+		t.Fatalf("expected %d requests, got %d", len(expectedRequests), len(requests))
+	}
+
+	for i, expected := range expectedRequests {
+		if eventType := requests[i].Header.Get("Webhook-Event"); eventType != expected.EventType {
+			t.Errorf("expected event %s, got %s", expected.EventType, eventType)
+		}
+
+		var payload struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+
+		err = json.Unmarshal([]byte(requests[i].Body), &payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if payload.Type != expected.EventType || string(payload.Data) != expected.Data {
+			t.Errorf("expected %s with data %s, got %s", expected.EventType, expected.Data, requests[i].Body)
+		}
+	}
+
+	deliveriesPath := fmt.Sprintf("/webhooks/%d/deliveries", webhook.Id)
+
+	rec = serveTestRequest(handler, http.MethodGet, deliveriesPath, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var deliveries WebhookDeliveriesResponse
+	err = json.NewDecoder(rec.Body).Decode(&deliveries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deliveries) != len(expectedRequests) {
+		t.Fatalf("expected %d deliveries, got %d", len(expectedRequests), len(deliveries))
+	}
+
+	for i, d := range deliveries {
+		// The latest delivery comes first.
+		expected := expectedRequests[len(expectedRequests)-1-i]
+
+		if string(d.EventType) != expected.EventType || d.Status != WebhookDeliveryResponseStatusDelivered || d.Attempts != 1 {
+			t.Errorf("expected %s delivered at the first attempt, got %+v", expected.EventType, d)
+		}
+
+		if d.LastStatusCode == nil || *d.LastStatusCode != http.StatusOK || d.DeliveredAt == nil || d.NextAttemptAt != nil {
+			t.Errorf("expected the delivery to be answered with %d, got %+v", http.StatusOK, d)
+		}
+	}
+
+	rec = serveTestRequest(handler, http.MethodDelete, fmt.Sprintf("/webhooks/%d", webhook.Id), "", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	rec = serveTestRequest(handler, http.MethodGet, deliveriesPath, "", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected the deleted webhook's deliveries to be gone, got status %d", rec.Code)
+	}
+}
+
+func TestWebhookHandler_erasedUserDeliveries(t *testing.T) {
+	handler, _ := newTestWebhookHandler()
+
+	rec := serveTestRequest(handler, http.MethodPost, "/webhooks", fmt.Sprintf(
+		`{"url": "%s", "secret": "secret", "event_types": ["user.created", "user.deleted"]}`,
+		testWebhookURL,
+	), nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var webhook WebhookResponse
+	err := json.NewDecoder(rec.Body).Decode(&webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, address := range []string{"john@example.com", "jane@example.com"} {
+		rec = serveTestRequest(handler, http.MethodPost, "/users", fmt.Sprintf(`{"first_name": "John", "email": "%s"}`, address), nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = serveTestRequest(handler, http.MethodDelete, "/users/1?mode=erase", "", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	rec = serveTestRequest(handler, http.MethodGet, fmt.Sprintf("/webhooks/%d/deliveries", webhook.Id), "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var deliveries WebhookDeliveriesResponse
+	err = json.NewDecoder(rec.Body).Decode(&deliveries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the erasure, without personal data, is left of the erased user's deliveries.
+	expectedData := []string{
+		`{"erased":true,"id":"1","organization_id":1}`,
+		`{"email":"jane@example.com","id":"2","organization_id":1,"product_news_consent":false}`,
+	}
+
+	if len(deliveries) != len(expectedData) {
+		t.Fatalf("expected %d deliveries, got %+v", len(expectedData), deliveries)
+	}
+
+	for i, d := range deliveries {
+		data, err := json.Marshal(d.Payload["data"])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != expectedData[i] {
+			t.Errorf("expected delivery with data %s, got %s", expectedData[i], data)
+		}
+	}
+}
+
+func TestWebhookHandler_GetWebhooks(t *testing.T) {
+	handler, _ := newTestWebhookHandler()
+
+	rec := serveTestRequest(handler, http.MethodGet, "/webhooks", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	if body := strings.TrimSpace(rec.Body.String()); body != "[]" {
+		t.Errorf("expected no webhooks, got %s", body)
+	}
+
+	rec = serveTestRequest(handler, http.MethodPost, "/webhooks", `{"url": "https://example.com/webhooks", "secret": "secret", "event_types": ["user.deleted"]}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	rec = serveTestRequest(handler, http.MethodGet, "/webhooks", "", nil)
+
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("expected the secret not to be returned, got %s", rec.Body.String())
+	}
+
+	var webhooks WebhooksResponse
+	err := json.NewDecoder(rec.Body).Decode(&webhooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(webhooks) != 1 || len(webhooks[0].EventTypes) != 1 || webhooks[0].EventTypes[0] != WebhookEventTypeUserDeleted {
+		t.Errorf("expected the webhook subscribed to %s, got %+v", WebhookEventTypeUserDeleted, webhooks)
+	}
+}
+
+func TestWebhookHandler_scopedToOrganization(t *testing.T) {
+	handler, _ := newTestWebhookHandler()
+
+	rec := serveTestRequest(handler, http.MethodPost, "/webhooks", `{"url": "https://example.com/webhooks", "secret": "secret", "event_types": ["user.deleted"]}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	otherOrganization := map[string]string{
+		OrganizationIDHeader: strconv.Itoa(otherTestOrganizationID),
+	}
+
+	rec = serveTestRequest(handler, http.MethodGet, "/webhooks", "", otherOrganization)
+	if body := strings.TrimSpace(rec.Body.String()); rec.Code != http.StatusOK || body != "[]" {
+		t.Errorf("expected no webhooks in another organization, got %d: %s", rec.Code, body)
+	}
+
+	for _, method := range []string{http.MethodDelete, http.MethodGet} {
+		path := "/webhooks/1"
+		if method == http.MethodGet {
+			path += "/deliveries"
+		}
+
+		rec = serveTestRequest(handler, method, path, "", otherOrganization)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected %s %s to be %d in another organization, got %d", method, path, http.StatusNotFound, rec.Code)
+		}
+	}
+}
+
+func TestWebhookHandler_notFound(t *testing.T) {
+	handler, _ := newTestWebhookHandler()
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		ExpectedStatusCode int
+		ExpectedType       string
+	}{
+		{
+			Name:               "delete",
+			Method:             http.MethodDelete,
+			Path:               "/webhooks/100",
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedType:       problemTypeWebhookNotFound,
+		},
+		{
+			Name:               "deliveries",
+			Method:             http.MethodGet,
+			Path:               "/webhooks/100/deliveries",
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedType:       problemTypeWebhookNotFound,
+		},
+		{
+			Name:               "invalid_id",
+			Method:             http.MethodGet,
+			Path:               "/webhooks/abc/deliveries",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       problemTypeInvalidWebhookID,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			rec := serveTestRequest(handler, tc.Method, tc.Path, "", nil)
+
+			if rec.Code != tc.ExpectedStatusCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.ExpectedStatusCode, rec.Code, rec.Body.String())
+			}
+
+			var problem Problem
+			err := json.NewDecoder(rec.Body).Decode(&problem)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if problem.Type != tc.ExpectedType {
+				t.Errorf("expected problem type %q, got %q", tc.ExpectedType, problem.Type)
+			}
+		})
+	}
+}
+
+// newTestWebhookHandler returns the whole API storing webhook deliveries with the users,
+// and the dispatcher sending them.
+func newTestWebhookHandler() (http.Handler, WebhookDispatcher) {
+	userStorage, webhookStorage := NewMemoryStorages()
+
+	h := HTTPHandler{
+		UserHandler: NewUserHandler(
+			userStorage,
+			NewVerificationTokens([]byte("secret"), time.Hour),
+			NewWriterMailer(io.Discard),
+			&eventPublisherStub{},
+		),
+		WebhookHandler: NewWebhookHandler(webhookStorage),
+	}
+
+	r := chi.NewRouter()
+	r.Use(OrganizationMiddleware)
+
+	return withTestOrganization(HandlerFromMux(h, r)), NewWebhookDispatcher(webhookStorage)
+}
+
+func serveTestRequest(handler http.Handler, method string, path string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
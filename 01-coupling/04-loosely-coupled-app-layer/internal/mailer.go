@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends e-mails. Swap it for an SMTP or API-based implementation in production.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+// WriterMailer writes e-mails to w (e.g., stdout or a file) instead of sending them.
+// It's meant for local runs.
+type WriterMailer struct {
+	lock *sync.Mutex
+	w    io.Writer
+}
+
+func NewWriterMailer(w io.Writer) WriterMailer {
+	return WriterMailer{
+		lock: &sync.Mutex{},
+		w:    w,
+	}
+}
+
+func (m WriterMailer) Send(ctx context.Context, mail Mail) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "To: %s\nSubject: %s\n\n%s\n\n", mail.To, mail.Subject, mail.Body)
+	return err
+}
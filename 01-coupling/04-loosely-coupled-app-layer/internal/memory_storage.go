@@ -0,0 +1,419 @@
+package internal
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryUserStorage is an in-memory implementation of UserRepository.
+// It's meant for tests and local runs, as it loses all data on restart.
+type MemoryUserStorage struct {
+	lock  *sync.RWMutex
+	users map[int]User
+	// organizations keeps the organization of each user in users.
+	organizations map[int]int
+	// deleted keeps the deletion times of users that are still in users, until they're purged.
+	deleted map[int]time.Time
+	// erasures keeps the audit records of erased users, which are removed from users.
+	erasures map[int]time.Time
+	lastID   *int
+	// webhooks is set by NewMemoryStorages, so the events of the changes are stored as deliveries,
+	// and Erase deletes the user's deliveries, like the SQL storages do. Events are ignored without it.
+	webhooks *MemoryWebhookStorage
+}
+
+func NewMemoryUserStorage() MemoryUserStorage {
+	return MemoryUserStorage{
+		lock:          &sync.RWMutex{},
+		users:         map[int]User{},
+		organizations: map[int]int{},
+		deleted:       map[int]time.Time{},
+		erasures:      map[int]time.Time{},
+		lastID:        new(int),
+	}
+}
+
+// NewMemoryStorages returns the user and webhook storages sharing their data, like tables of the same database.
+func NewMemoryStorages() (MemoryUserStorage, MemoryWebhookStorage) {
+	webhooks := NewMemoryWebhookStorage()
+
+	users := NewMemoryUserStorage()
+	users.webhooks = &webhooks
+
+	return users, webhooks
+}
+
+func (s MemoryUserStorage) All(ctx context.Context, filter UserFilter) ([]User, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var users []User
+	for _, u := range s.users {
+		if _, ok := s.deleted[u.id]; ok {
+			continue
+		}
+
+		if s.organizations[u.id] != organizationID {
+			continue
+		}
+
+		if !filter.CreatedAfter.IsZero() && !u.createdAt.After(filter.CreatedAfter) {
+			continue
+		}
+
+		if u.id <= filter.AfterID {
+			continue
+		}
+
+		users = append(users, copyUser(u))
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].id < users[j].id
+	})
+
+	if filter.Limit > 0 && len(users) > filter.Limit {
+		users = users[:filter.Limit]
+	}
+
+	return users, nil
+}
+
+func (s MemoryUserStorage) ByID(ctx context.Context, id int) (User, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return User{}, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	user, ok := s.user(organizationID, id)
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	return copyUser(user), nil
+}
+
+// user returns the user unless it's deleted or belongs to another organization. The caller must hold the lock.
+func (s MemoryUserStorage) user(organizationID int, id int) (User, bool) {
+	if _, ok := s.deleted[id]; ok {
+		return User{}, false
+	}
+
+	return s.storedUser(organizationID, id)
+}
+
+// storedUser returns the user, including deleted ones, unless it belongs to another organization.
+// The caller must hold the lock.
+func (s MemoryUserStorage) storedUser(organizationID int, id int) (User, bool) {
+	if s.organizations[id] != organizationID {
+		return User{}, false
+	}
+
+	user, ok := s.users[id]
+	return user, ok
+}
+
+func (s MemoryUserStorage) Add(ctx context.Context, user User, events ...NewUserEvent) (int, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, e := range user.emails {
+		if s.emailExists(organizationID, e.address) {
+			return 0, ErrEmailAlreadyExists
+		}
+	}
+
+	userID := s.add(organizationID, user)
+
+	err = s.addEventDeliveries(newUserEvents(userID, events), user.createdAt)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (s MemoryUserStorage) AddBatch(ctx context.Context, users []User) ([]error, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	userErrs := make([]error, len(users))
+
+	for i, user := range users {
+		for _, e := range user.emails {
+			if s.emailExists(organizationID, e.address) {
+				userErrs[i] = ErrEmailAlreadyExists
+			}
+		}
+
+		if userErrs[i] == nil {
+			s.add(organizationID, user)
+		}
+	}
+
+	return userErrs, nil
+}
+
+// add saves the new user. The caller must hold the write lock.
+func (s MemoryUserStorage) add(organizationID int, user User) int {
+	*s.lastID++
+
+	user = copyUser(user)
+	user.id = *s.lastID
+	user.version = 1
+
+	s.users[user.id] = user
+	s.organizations[user.id] = organizationID
+
+	return user.id
+}
+
+// Update saves the user only if its version didn't change since it was read.
+func (s MemoryUserStorage) Update(ctx context.Context, user User, events ...interface{}) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stored, ok := s.user(organizationID, user.id)
+	if !ok || stored.version != user.version {
+		return ErrUserModified
+	}
+
+	stored = copyUser(stored)
+	stored.firstName = user.firstName
+	stored.lastName = user.lastName
+	stored.updatedAt = user.updatedAt
+	stored.version++
+
+	for i := range stored.emails {
+		for _, e := range user.emails {
+			if stored.emails[i].address == e.address {
+				stored.emails[i].primary = e.primary
+			}
+		}
+	}
+
+	s.users[stored.id] = stored
+
+	return s.addEventDeliveries(events, user.updatedAt)
+}
+
+// AddEmail adds the e-mail address to an existing user.
+func (s MemoryUserStorage) AddEmail(ctx context.Context, userID int, email Email) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	user, ok := s.user(organizationID, userID)
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if s.emailExists(organizationID, email.address) {
+		return ErrEmailAlreadyExists
+	}
+
+	user = copyUser(user)
+	user.emails = append(user.emails, email)
+
+	s.users[userID] = user
+
+	return nil
+}
+
+// VerifyEmail marks the e-mail address as verified.
+func (s MemoryUserStorage) VerifyEmail(ctx context.Context, address string) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, u := range s.users {
+		if _, ok := s.user(organizationID, id); !ok {
+			continue
+		}
+
+		for i, e := range u.emails {
+			// Matches the case-insensitive collation of the MySQL column.
+			if !strings.EqualFold(e.Address(), address) {
+				continue
+			}
+
+			u = copyUser(u)
+			u.emails[i].verified = true
+			s.users[id] = u
+
+			return nil
+		}
+	}
+
+	return ErrEmailNotFound
+}
+
+func (s MemoryUserStorage) Delete(ctx context.Context, id int, now time.Time, events ...interface{}) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.user(organizationID, id); !ok {
+		return nil
+	}
+
+	s.deleted[id] = now
+
+	return s.addEventDeliveries(events, now)
+}
+
+func (s MemoryUserStorage) Restore(ctx context.Context, id int, now time.Time) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.deleted[id]; !ok || s.organizations[id] != organizationID {
+		return ErrUserNotFound
+	}
+
+	delete(s.deleted, id)
+
+	user := copyUser(s.users[id])
+	user.updatedAt = now
+	s.users[id] = user
+
+	return nil
+}
+
+func (s MemoryUserStorage) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	purged := 0
+	for id, deletedAt := range s.deleted {
+		if !deletedAt.Before(deletedBefore) {
+			continue
+		}
+
+		delete(s.users, id)
+		delete(s.organizations, id)
+		delete(s.deleted, id)
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (s MemoryUserStorage) PersonalData(ctx context.Context, id int) (PersonalData, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	user, ok := s.storedUser(organizationID, id)
+	if !ok {
+		return PersonalData{}, ErrUserNotFound
+	}
+
+	// Passwords and IP addresses aren't kept in memory.
+	return PersonalData{User: copyUser(user)}, nil
+}
+
+func (s MemoryUserStorage) Erase(ctx context.Context, id int, now time.Time, events ...interface{}) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.storedUser(organizationID, id); !ok {
+		return ErrUserNotFound
+	}
+
+	// Nothing references users in memory, so there's no row to keep, only the audit record.
+	delete(s.users, id)
+	delete(s.organizations, id)
+	delete(s.deleted, id)
+	s.erasures[id] = now
+
+	if s.webhooks != nil {
+		s.webhooks.deleteUserDeliveries(id)
+	}
+
+	return s.addEventDeliveries(events, now)
+}
+
+// addEventDeliveries stores the deliveries of the events, if the storage has webhooks. The caller must hold the write lock.
+func (s MemoryUserStorage) addEventDeliveries(events []interface{}, now time.Time) error {
+	if s.webhooks == nil {
+		return nil
+	}
+
+	return s.webhooks.addEventDeliveries(events, now)
+}
+
+// emailExists checks if the address is taken in the organization. The caller must hold the lock.
+func (s MemoryUserStorage) emailExists(organizationID int, address EmailAddress) bool {
+	for id, u := range s.users {
+		if s.organizations[id] != organizationID {
+			continue
+		}
+
+		for _, e := range u.emails {
+			if e.address.Equal(address) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// copyUser returns a user that doesn't share the e-mails slice with the original,
+// so changes made by callers don't leak into the storage.
+func copyUser(u User) User {
+	u.emails = append([]Email(nil), u.emails...)
+	return u
+}
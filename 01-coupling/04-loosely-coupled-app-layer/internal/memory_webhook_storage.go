@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryWebhookStorage is an in-memory implementation of WebhookRepository, meant for tests.
+type MemoryWebhookStorage struct {
+	lock          *sync.Mutex
+	subscriptions map[int]WebhookSubscription
+	// organizations keeps the organization of each subscription in subscriptions.
+	organizations      map[int]int
+	deliveries         map[int]WebhookDelivery
+	lastSubscriptionID *int
+	lastDeliveryID     *int
+}
+
+func NewMemoryWebhookStorage() MemoryWebhookStorage {
+	return MemoryWebhookStorage{
+		lock:               &sync.Mutex{},
+		subscriptions:      map[int]WebhookSubscription{},
+		organizations:      map[int]int{},
+		deliveries:         map[int]WebhookDelivery{},
+		lastSubscriptionID: new(int),
+		lastDeliveryID:     new(int),
+	}
+}
+
+func (s MemoryWebhookStorage) AddSubscription(ctx context.Context, subscription WebhookSubscription) (int, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	*s.lastSubscriptionID++
+	subscription.ID = *s.lastSubscriptionID
+	subscription.EventTypes = append([]string(nil), subscription.EventTypes...)
+
+	s.subscriptions[subscription.ID] = subscription
+	s.organizations[subscription.ID] = organizationID
+
+	return subscription.ID, nil
+}
+
+func (s MemoryWebhookStorage) Subscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.organizationSubscriptions(organizationID), nil
+}
+
+// organizationSubscriptions returns copies of the organization's subscriptions. The caller must hold the lock.
+func (s MemoryWebhookStorage) organizationSubscriptions(organizationID int) []WebhookSubscription {
+	var subscriptions []WebhookSubscription
+	for _, sub := range s.subscriptions {
+		if s.organizations[sub.ID] != organizationID {
+			continue
+		}
+
+		sub.EventTypes = append([]string(nil), sub.EventTypes...)
+		subscriptions = append(subscriptions, sub)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].ID < subscriptions[j].ID
+	})
+
+	return subscriptions
+}
+
+func (s MemoryWebhookStorage) DeleteSubscription(ctx context.Context, id int) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.subscriptionExists(organizationID, id) {
+		return ErrWebhookNotFound
+	}
+
+	delete(s.subscriptions, id)
+	delete(s.organizations, id)
+
+	for deliveryID, d := range s.deliveries {
+		if d.SubscriptionID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+
+	return nil
+}
+
+func (s MemoryWebhookStorage) AddDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.insertDeliveries(organizationID, deliveries)
+
+	return nil
+}
+
+// addEventDeliveries is called by MemoryUserStorage with its lock held, like the SQL storages call it in their transactions.
+func (s MemoryWebhookStorage) addEventDeliveries(events []interface{}, now time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, event := range events {
+		eventType, organizationID, userID, ok := webhookEvent(event)
+		if !ok {
+			continue
+		}
+
+		deliveries, err := newWebhookDeliveries(event, eventType, userID, s.organizationSubscriptions(organizationID), now)
+		if err != nil {
+			return err
+		}
+
+		s.insertDeliveries(organizationID, deliveries)
+	}
+
+	return nil
+}
+
+// insertDeliveries assigns the deliveries their IDs. The caller must hold the lock.
+func (s MemoryWebhookStorage) insertDeliveries(organizationID int, deliveries []WebhookDelivery) {
+	for _, d := range deliveries {
+		*s.lastDeliveryID++
+		d.ID = *s.lastDeliveryID
+		d.OrganizationID = organizationID
+
+		s.deliveries[d.ID] = d
+	}
+}
+
+// deleteUserDeliveries is called by MemoryUserStorage.Erase.
+func (s MemoryWebhookStorage) deleteUserDeliveries(userID int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for deliveryID, d := range s.deliveries {
+		if d.UserID == userID {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+}
+
+func (s MemoryWebhookStorage) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var deliveries []WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.Status == WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	for i := range deliveries {
+		d := s.deliveries[deliveries[i].ID]
+		d.NextAttemptAt = now.Add(lease)
+		s.deliveries[d.ID] = d
+	}
+
+	return deliveries, nil
+}
+
+func (s MemoryWebhookStorage) UpdateDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	d, ok := s.deliveries[delivery.ID]
+	if !ok {
+		// Like an UPDATE matching no rows, as the subscription could be deleted in the meantime.
+		return nil
+	}
+
+	d.Status = delivery.Status
+	d.Attempts = delivery.Attempts
+	d.NextAttemptAt = delivery.NextAttemptAt
+	d.LastStatusCode = delivery.LastStatusCode
+	d.LastError = delivery.LastError
+	d.DeliveredAt = delivery.DeliveredAt
+
+	s.deliveries[d.ID] = d
+
+	return nil
+}
+
+func (s MemoryWebhookStorage) Deliveries(ctx context.Context, subscriptionID int, limit int) ([]WebhookDelivery, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.subscriptionExists(organizationID, subscriptionID) {
+		return nil, ErrWebhookNotFound
+	}
+
+	var deliveries []WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID > deliveries[j].ID
+	})
+
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	return deliveries, nil
+}
+
+// subscriptionExists checks if the subscription belongs to the organization. The caller must hold the lock.
+func (s MemoryWebhookStorage) subscriptionExists(organizationID int, id int) bool {
+	_, ok := s.subscriptions[id]
+	return ok && s.organizations[id] == organizationID
+}
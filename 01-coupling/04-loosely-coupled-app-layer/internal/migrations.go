@@ -0,0 +1,21 @@
+package internal
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// NewMySQLMigrator returns a Migrator for UserStorage.
+func NewMySQLMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	return migrate.New(db, migrate.MySQL, migrationFiles, "migrations/mysql")
+}
+
+// NewPostgresMigrator returns a Migrator for PostgresUserStorage.
+func NewPostgresMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	return migrate.New(db, migrate.Postgres, migrationFiles, "migrations/postgres")
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `user_erasures`;
+
+ALTER TABLE `users` DROP COLUMN `erased_at`;
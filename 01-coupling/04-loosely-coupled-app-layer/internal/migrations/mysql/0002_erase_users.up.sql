@@ -0,0 +1,11 @@
+ALTER TABLE `users` ADD COLUMN `erased_at` datetime(3) DEFAULT NULL;
+
+-- Audit record of erasures. It outlives the personal data, so it keeps only the user ID and the time.
+CREATE TABLE `user_erasures` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` bigint NOT NULL,
+  `erased_at` datetime(3) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `fk_users_user_erasures` (`user_id`),
+  CONSTRAINT `fk_users_user_erasures` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
+);
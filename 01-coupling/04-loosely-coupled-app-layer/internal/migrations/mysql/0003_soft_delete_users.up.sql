@@ -0,0 +1,4 @@
+ALTER TABLE `users` ADD COLUMN `deleted_at` datetime(3) DEFAULT NULL;
+
+-- Used by the purge job to find users deleted before the retention period.
+CREATE INDEX `idx_users_deleted_at` ON `users` (`deleted_at`);
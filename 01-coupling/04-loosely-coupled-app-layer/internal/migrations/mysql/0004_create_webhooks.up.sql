@@ -0,0 +1,29 @@
+-- event_types is a comma-separated list, e.g. "user.created,user.deleted".
+CREATE TABLE `webhook_subscriptions` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `url` text NOT NULL,
+  `secret` text NOT NULL,
+  `event_types` text NOT NULL,
+  `created_at` datetime(3) NOT NULL,
+  PRIMARY KEY (`id`)
+);
+
+-- The outbox of webhook deliveries, which is also their log.
+CREATE TABLE `webhook_deliveries` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `subscription_id` bigint NOT NULL,
+  `event_type` varchar(64) NOT NULL,
+  `payload` blob NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime(3) NOT NULL,
+  `last_status_code` int DEFAULT NULL,
+  `last_error` text,
+  `created_at` datetime(3) NOT NULL,
+  `delivered_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `fk_webhook_subscriptions_webhook_deliveries` (`subscription_id`),
+  -- Used by the dispatcher to find deliveries due to be sent.
+  KEY `idx_webhook_deliveries_status_next_attempt_at` (`status`, `next_attempt_at`),
+  CONSTRAINT `fk_webhook_subscriptions_webhook_deliveries` FOREIGN KEY (`subscription_id`) REFERENCES `webhook_subscriptions` (`id`) ON DELETE CASCADE
+);
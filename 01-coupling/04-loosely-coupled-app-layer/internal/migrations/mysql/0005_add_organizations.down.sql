@@ -0,0 +1,9 @@
+-- Fails if the same address is used in more than one organization.
+CREATE UNIQUE INDEX `idx_emails_address` ON `emails` (`address`);
+DROP INDEX `idx_emails_organization_id_address` ON `emails`;
+
+ALTER TABLE `emails` DROP COLUMN `organization_id`;
+
+DROP INDEX `idx_users_organization_id_id` ON `users`;
+
+ALTER TABLE `users` DROP COLUMN `organization_id`;
@@ -0,0 +1,14 @@
+-- Users created before organizations existed belong to organization 1. The default is dropped afterwards,
+-- so new rows can't be inserted without an organization.
+ALTER TABLE `users` ADD COLUMN `organization_id` bigint NOT NULL DEFAULT 1;
+ALTER TABLE `users` ALTER COLUMN `organization_id` DROP DEFAULT;
+
+-- Every query filters by the organization, and lists users ordered by ID.
+CREATE INDEX `idx_users_organization_id_id` ON `users` (`organization_id`, `id`);
+
+-- E-mails are unique per organization, so the organization is copied from the user to make the unique index possible.
+ALTER TABLE `emails` ADD COLUMN `organization_id` bigint NOT NULL DEFAULT 1;
+ALTER TABLE `emails` ALTER COLUMN `organization_id` DROP DEFAULT;
+
+CREATE UNIQUE INDEX `idx_emails_organization_id_address` ON `emails` (`organization_id`, `address`);
+DROP INDEX `idx_emails_address` ON `emails`;
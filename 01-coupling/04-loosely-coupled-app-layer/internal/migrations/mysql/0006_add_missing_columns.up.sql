@@ -0,0 +1,27 @@
+-- 0001_create_users keeps tables created by the old init.sql, which miss the columns added since.
+-- MySQL has no ADD COLUMN IF NOT EXISTS, so each column is added only if information_schema doesn't list it.
+
+SET @add_users_version = IF(
+  EXISTS (
+    SELECT 1 FROM information_schema.COLUMNS
+    WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'users' AND COLUMN_NAME = 'version'
+  ),
+  'DO 0',
+  'ALTER TABLE `users` ADD COLUMN `version` bigint NOT NULL DEFAULT 1 AFTER `updated_at`'
+);
+PREPARE add_column FROM @add_users_version;
+EXECUTE add_column;
+DEALLOCATE PREPARE add_column;
+
+-- Addresses saved before e-mail verification start unverified.
+SET @add_emails_verified = IF(
+  EXISTS (
+    SELECT 1 FROM information_schema.COLUMNS
+    WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'emails' AND COLUMN_NAME = 'verified'
+  ),
+  'DO 0',
+  'ALTER TABLE `emails` ADD COLUMN `verified` tinyint(1) NOT NULL DEFAULT 0 AFTER `primary`'
+);
+PREPARE add_column FROM @add_emails_verified;
+EXECUTE add_column;
+DEALLOCATE PREPARE add_column;
@@ -0,0 +1,2 @@
+DROP INDEX `idx_webhook_deliveries_user_id` ON `webhook_deliveries`;
+ALTER TABLE `webhook_deliveries` DROP COLUMN `user_id`;
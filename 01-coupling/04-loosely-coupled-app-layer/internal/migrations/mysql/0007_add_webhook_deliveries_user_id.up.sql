@@ -0,0 +1,10 @@
+-- Payloads have the user's personal data, so deliveries are deleted when the user is erased.
+ALTER TABLE `webhook_deliveries` ADD COLUMN `user_id` bigint DEFAULT NULL AFTER `subscription_id`;
+
+-- Deliveries stored before have the user's ID only in the payload.
+UPDATE `webhook_deliveries`
+SET `user_id` = CAST(JSON_UNQUOTE(JSON_EXTRACT(CONVERT(`payload` USING utf8mb4), '$.data.id')) AS UNSIGNED);
+
+ALTER TABLE `webhook_deliveries` MODIFY `user_id` bigint NOT NULL;
+
+CREATE INDEX `idx_webhook_deliveries_user_id` ON `webhook_deliveries` (`user_id`);
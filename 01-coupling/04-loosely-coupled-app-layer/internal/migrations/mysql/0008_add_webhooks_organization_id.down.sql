@@ -0,0 +1,5 @@
+ALTER TABLE `webhook_deliveries` DROP COLUMN `organization_id`;
+
+DROP INDEX `idx_webhook_subscriptions_organization_id_id` ON `webhook_subscriptions`;
+
+ALTER TABLE `webhook_subscriptions` DROP COLUMN `organization_id`;
@@ -0,0 +1,11 @@
+-- Subscriptions created before webhooks were scoped belong to organization 1, like the users.
+-- The default is dropped afterwards, so new rows can't be inserted without an organization.
+ALTER TABLE `webhook_subscriptions` ADD COLUMN `organization_id` bigint NOT NULL DEFAULT 1;
+ALTER TABLE `webhook_subscriptions` ALTER COLUMN `organization_id` DROP DEFAULT;
+
+-- Subscriptions are listed per organization, ordered by ID.
+CREATE INDEX `idx_webhook_subscriptions_organization_id_id` ON `webhook_subscriptions` (`organization_id`, `id`);
+
+-- The organization is copied from the subscription, so the delivery log is filtered by it too.
+ALTER TABLE `webhook_deliveries` ADD COLUMN `organization_id` bigint NOT NULL DEFAULT 1;
+ALTER TABLE `webhook_deliveries` ALTER COLUMN `organization_id` DROP DEFAULT;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS users (
+  id bigserial PRIMARY KEY,
+  first_name text NOT NULL,
+  last_name text NOT NULL,
+  password_hash text,
+  last_ip text,
+  created_at timestamp(3),
+  updated_at timestamp(3),
+  version bigint NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS emails (
+  id bigserial PRIMARY KEY,
+  address varchar(256) NOT NULL,
+  "primary" boolean NOT NULL,
+  verified boolean NOT NULL,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Postgres compares text case-sensitively, unlike the MySQL collation.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_emails_address ON emails (LOWER(address));
+CREATE INDEX IF NOT EXISTS fk_users_emails ON emails (user_id);
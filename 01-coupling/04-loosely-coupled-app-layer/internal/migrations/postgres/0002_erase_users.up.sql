@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN erased_at timestamp(3);
+
+-- Audit record of erasures. It outlives the personal data, so it keeps only the user ID and the time.
+CREATE TABLE user_erasures (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users (id),
+  erased_at timestamp(3) NOT NULL
+);
+
+CREATE INDEX fk_users_user_erasures ON user_erasures (user_id);
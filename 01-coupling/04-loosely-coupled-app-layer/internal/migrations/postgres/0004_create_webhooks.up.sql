@@ -0,0 +1,28 @@
+-- event_types is a comma-separated list, e.g. "user.created,user.deleted".
+CREATE TABLE webhook_subscriptions (
+  id bigserial PRIMARY KEY,
+  url text NOT NULL,
+  secret text NOT NULL,
+  event_types text NOT NULL,
+  created_at timestamp(3) NOT NULL
+);
+
+-- The outbox of webhook deliveries, which is also their log.
+CREATE TABLE webhook_deliveries (
+  id bigserial PRIMARY KEY,
+  subscription_id bigint NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+  event_type varchar(64) NOT NULL,
+  payload bytea NOT NULL,
+  status varchar(16) NOT NULL,
+  attempts int NOT NULL DEFAULT 0,
+  next_attempt_at timestamp(3) NOT NULL,
+  last_status_code int,
+  last_error text,
+  created_at timestamp(3) NOT NULL,
+  delivered_at timestamp(3)
+);
+
+CREATE INDEX fk_webhook_subscriptions_webhook_deliveries ON webhook_deliveries (subscription_id);
+
+-- Used by the dispatcher to find deliveries due to be sent.
+CREATE INDEX idx_webhook_deliveries_status_next_attempt_at ON webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1 @@
+-- 0006 adds nothing in PostgreSQL.
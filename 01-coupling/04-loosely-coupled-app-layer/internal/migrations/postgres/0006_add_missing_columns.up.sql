@@ -0,0 +1,2 @@
+-- Only MySQL databases created by the old init.sql miss columns, so this adds nothing.
+-- It keeps the versions the same in both dialects, as 0007 was added to PostgreSQL without it.
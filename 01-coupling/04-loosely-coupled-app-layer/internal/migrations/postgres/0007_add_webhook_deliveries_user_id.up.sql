@@ -0,0 +1,11 @@
+-- 0006 adds columns missing in MySQL databases created by init.sql, so there's no PostgreSQL version of it.
+
+-- Payloads have the user's personal data, so deliveries are deleted when the user is erased.
+ALTER TABLE webhook_deliveries ADD COLUMN user_id bigint;
+
+-- Deliveries stored before have the user's ID only in the payload.
+UPDATE webhook_deliveries SET user_id = (convert_from(payload, 'UTF8')::jsonb #>> '{data,id}')::bigint;
+
+ALTER TABLE webhook_deliveries ALTER COLUMN user_id SET NOT NULL;
+
+CREATE INDEX idx_webhook_deliveries_user_id ON webhook_deliveries (user_id);
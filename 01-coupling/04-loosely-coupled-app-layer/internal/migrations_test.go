@@ -0,0 +1,17 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	_, err := NewMySQLMigrator(nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = NewPostgresMigrator(nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
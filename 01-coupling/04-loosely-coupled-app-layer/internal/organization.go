@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrOrganizationRequired = errors.New("organization is required")
+
+type organizationIDKey struct{}
+
+// ContextWithOrganizationID scopes all UserRepository calls made with the context to the organization.
+func ContextWithOrganizationID(ctx context.Context, organizationID int) context.Context {
+	return context.WithValue(ctx, organizationIDKey{}, organizationID)
+}
+
+// OrganizationIDFromContext returns ErrOrganizationRequired if the context isn't scoped to an organization.
+// Repositories call it for every query, so a missing organization fails instead of reading users of all of them.
+func OrganizationIDFromContext(ctx context.Context) (int, error) {
+	organizationID, ok := ctx.Value(organizationIDKey{}).(int)
+	if !ok || organizationID <= 0 {
+		return 0, ErrOrganizationRequired
+	}
+
+	return organizationID, nil
+}
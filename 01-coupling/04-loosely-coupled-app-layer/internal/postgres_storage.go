@@ -0,0 +1,588 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresUserStorage is the PostgreSQL implementation of UserRepository.
+//
+// The sqlboiler models are generated for MySQL, so it uses plain SQL instead.
+type PostgresUserStorage struct {
+	db *sql.DB
+	// webhooks stores the deliveries of the events in the transactions of the changes.
+	webhooks SQLWebhookStorage
+}
+
+func NewPostgresUserStorage(db *sql.DB) PostgresUserStorage {
+	return PostgresUserStorage{
+		db:       db,
+		webhooks: NewPostgresWebhookStorage(db),
+	}
+}
+
+func (s PostgresUserStorage) All(ctx context.Context, filter UserFilter) ([]User, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{`organization_id = $1`, `erased_at IS NULL`, `deleted_at IS NULL`}
+	args := []interface{}{organizationID}
+
+	if !filter.CreatedAfter.IsZero() {
+		args = append(args, filter.CreatedAfter)
+		conditions = append(conditions, fmt.Sprintf(`created_at > $%d`, len(args)))
+	}
+
+	if filter.AfterID > 0 {
+		args = append(args, filter.AfterID)
+		conditions = append(conditions, fmt.Sprintf(`id > $%d`, len(args)))
+	}
+
+	query := `SELECT id, first_name, last_name, version, created_at, updated_at FROM users WHERE ` +
+		strings.Join(conditions, ` AND `) + ` ORDER BY id`
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var id, version int
+		var firstName, lastName string
+		var createdAt, updatedAt sql.NullTime
+
+		err = rows.Scan(&id, &firstName, &lastName, &version, &createdAt, &updatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, UnmarshalUser(id, firstName, lastName, nil, version, createdAt.Time, updatedAt.Time))
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, 0, len(users))
+	for _, u := range users {
+		userIDs = append(userIDs, int64(u.id))
+	}
+
+	emails, err := s.emails(ctx, `SELECT user_id, address, "primary", verified FROM emails WHERE user_id = ANY($1) ORDER BY id`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range users {
+		users[i].emails = emails[users[i].id]
+	}
+
+	return users, nil
+}
+
+func (s PostgresUserStorage) ByID(ctx context.Context, id int) (User, error) {
+	data, err := s.personalData(ctx, id, `AND deleted_at IS NULL`)
+	if err != nil {
+		return User{}, err
+	}
+
+	return data.User, nil
+}
+
+func (s PostgresUserStorage) Add(ctx context.Context, user User, events ...NewUserEvent) (userID int, err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	email := user.Emails()[0]
+
+	exists, err := postgresEmailExists(ctx, tx, organizationID, email.Address())
+	if err != nil {
+		return 0, err
+	}
+
+	if exists {
+		return 0, ErrEmailAlreadyExists
+	}
+
+	userID, err = insertPostgresUser(ctx, tx, organizationID, user)
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertPostgresEmail(ctx, tx, organizationID, userID, email)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.webhooks.addEventDeliveries(ctx, tx, newUserEvents(userID, events), user.CreatedAt())
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (s PostgresUserStorage) AddBatch(ctx context.Context, users []User) (userErrs []error, err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	userErrs = make([]error, len(users))
+
+	for i, user := range users {
+		email := user.Emails()[0]
+
+		// A failed statement aborts the whole transaction in PostgreSQL, so the e-mail is checked first.
+		exists, err := postgresEmailExists(ctx, tx, organizationID, email.Address())
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			userErrs[i] = ErrEmailAlreadyExists
+			continue
+		}
+
+		userID, err := insertPostgresUser(ctx, tx, organizationID, user)
+		if err != nil {
+			return nil, err
+		}
+
+		err = insertPostgresEmail(ctx, tx, organizationID, userID, email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return userErrs, nil
+}
+
+// Update saves the user only if its version didn't change since it was read.
+func (s PostgresUserStorage) Update(ctx context.Context, user User, events ...interface{}) (err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE users SET first_name = $1, last_name = $2, version = version + 1, updated_at = $3 `+
+			`WHERE id = $4 AND version = $5 AND organization_id = $6 AND erased_at IS NULL AND deleted_at IS NULL`,
+		user.FirstName(), user.LastName(), user.UpdatedAt(), user.ID(), user.Version(), organizationID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserModified
+	}
+
+	for _, e := range user.Emails() {
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE emails SET "primary" = $1 WHERE user_id = $2 AND address = $3`,
+			e.Primary(), user.ID(), e.Address(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.webhooks.addEventDeliveries(ctx, tx, events, user.UpdatedAt())
+}
+
+// AddEmail adds the e-mail address to an existing user.
+func (s PostgresUserStorage) AddEmail(ctx context.Context, userID int, email Email) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The foreign key doesn't know about organizations, so the user is checked first.
+	var userExists bool
+	err = s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND organization_id = $2 AND erased_at IS NULL AND deleted_at IS NULL)`,
+		userID, organizationID,
+	).Scan(&userExists)
+	if err != nil {
+		return err
+	}
+
+	if !userExists {
+		return ErrUserNotFound
+	}
+
+	exists, err := postgresEmailExists(ctx, s.db, organizationID, email.Address())
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrEmailAlreadyExists
+	}
+
+	return insertPostgresEmail(ctx, s.db, organizationID, userID, email)
+}
+
+// VerifyEmail marks the e-mail address as verified.
+func (s PostgresUserStorage) VerifyEmail(ctx context.Context, address string) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE emails SET verified = true WHERE LOWER(address) = LOWER($1) AND organization_id = $2 `+
+			`AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)`,
+		address, organizationID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEmailNotFound
+	}
+
+	return nil
+}
+
+func (s PostgresUserStorage) Delete(ctx context.Context, id int, now time.Time, events ...interface{}) (err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE users SET deleted_at = $1 WHERE id = $2 AND organization_id = $3 AND erased_at IS NULL AND deleted_at IS NULL`,
+		now, id, organizationID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return nil
+	}
+
+	return s.webhooks.addEventDeliveries(ctx, tx, events, now)
+}
+
+func (s PostgresUserStorage) Restore(ctx context.Context, id int, now time.Time) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE users SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND organization_id = $3 AND erased_at IS NULL AND deleted_at IS NOT NULL`,
+		now, id, organizationID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Purge deletes the users' rows, and their e-mails with them. Erased users are skipped, as their audit records reference them.
+func (s PostgresUserStorage) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE erased_at IS NULL AND deleted_at < $1`, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
+
+func (s PostgresUserStorage) PersonalData(ctx context.Context, id int) (PersonalData, error) {
+	return s.personalData(ctx, id, ``)
+}
+
+// personalData returns the user of the context's organization unless it's erased.
+// The extra condition is appended to the WHERE clause.
+func (s PostgresUserStorage) personalData(ctx context.Context, id int, condition string) (PersonalData, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	var version int
+	var firstName, lastName string
+	var lastIP sql.NullString
+	var hasPassword bool
+	var createdAt, updatedAt sql.NullTime
+
+	err = s.db.QueryRowContext(
+		ctx,
+		`SELECT first_name, last_name, last_ip, password_hash IS NOT NULL, version, created_at, updated_at FROM users `+
+			`WHERE id = $1 AND organization_id = $2 AND erased_at IS NULL `+condition,
+		id, organizationID,
+	).Scan(&firstName, &lastName, &lastIP, &hasPassword, &version, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PersonalData{}, ErrUserNotFound
+		}
+		return PersonalData{}, err
+	}
+
+	emails, err := s.emails(ctx, `SELECT user_id, address, "primary", verified FROM emails WHERE user_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return PersonalData{}, err
+	}
+
+	return PersonalData{
+		User:        UnmarshalUser(id, firstName, lastName, emails[id], version, createdAt.Time, updatedAt.Time),
+		LastIP:      lastIP.String,
+		HasPassword: hasPassword,
+	}, nil
+}
+
+// Erase anonymizes the user and stores the audit record in a single transaction.
+func (s PostgresUserStorage) Erase(ctx context.Context, id int, now time.Time, events ...interface{}) (err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE users SET first_name = '', last_name = '', password_hash = NULL, last_ip = NULL, updated_at = $1, erased_at = $1 `+
+			`WHERE id = $2 AND organization_id = $3 AND erased_at IS NULL`,
+		now, id, organizationID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM emails WHERE user_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO user_erasures (user_id, erased_at) VALUES ($1, $2)`, id, now)
+	if err != nil {
+		return err
+	}
+
+	// Webhook payloads have the user's personal data too.
+	_, err = tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE user_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return s.webhooks.addEventDeliveries(ctx, tx, events, now)
+}
+
+// emails returns e-mails grouped by the user ID. The query must select user_id, address, primary and verified.
+func (s PostgresUserStorage) emails(ctx context.Context, query string, args ...interface{}) (map[int][]Email, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	emails := map[int][]Email{}
+	for rows.Next() {
+		var userID int
+		var address string
+		var primary, verified bool
+
+		err = rows.Scan(&userID, &address, &primary, &verified)
+		if err != nil {
+			return nil, err
+		}
+
+		emails[userID] = append(emails[userID], UnmarshalEmail(address, primary, verified))
+	}
+
+	return emails, rows.Err()
+}
+
+type postgresExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// postgresEmailExists checks if the address is taken in the organization, ignoring the case, like emailExists does for MySQL.
+func postgresEmailExists(ctx context.Context, exec postgresExecutor, organizationID int, address string) (bool, error) {
+	var exists bool
+	err := exec.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM emails WHERE organization_id = $1 AND LOWER(address) = LOWER($2))`,
+		organizationID, address,
+	).Scan(&exists)
+	return exists, err
+}
+
+func insertPostgresUser(ctx context.Context, exec postgresExecutor, organizationID int, user User) (int, error) {
+	var userID int
+	err := exec.QueryRowContext(
+		ctx,
+		`INSERT INTO users (organization_id, first_name, last_name, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		organizationID, user.FirstName(), user.LastName(), user.CreatedAt(), user.UpdatedAt(),
+	).Scan(&userID)
+	return userID, err
+}
+
+func insertPostgresEmail(ctx context.Context, exec postgresExecutor, organizationID int, userID int, email Email) error {
+	_, err := exec.ExecContext(
+		ctx,
+		`INSERT INTO emails (organization_id, address, "primary", verified, user_id) VALUES ($1, $2, $3, $4, $5)`,
+		organizationID, email.Address(), email.Primary(), email.Verified(), userID,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrEmailAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
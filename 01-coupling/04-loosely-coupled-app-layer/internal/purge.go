@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// UserPurger removes users deleted longer than the retention period ago, so they can't be restored anymore
+// and their e-mails can be used again.
+type UserPurger struct {
+	storage   UserRepository
+	retention time.Duration
+	now       func() time.Time
+}
+
+func NewUserPurger(storage UserRepository, retention time.Duration) UserPurger {
+	return UserPurger{
+		storage:   storage,
+		retention: retention,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// Purge removes the users once, and returns how many were removed.
+func (p UserPurger) Purge(ctx context.Context) (int, error) {
+	return p.storage.Purge(ctx, p.now().Add(-p.retention))
+}
+
+// Run purges users every interval, until the context is canceled. Failures are logged and retried on the next run.
+func (p UserPurger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.Purge(ctx)
+		if err != nil {
+			log.Println("Error while purging deleted users:", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted users", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUserPurger_Purge(t *testing.T) {
+	ctx := testContext()
+	storage := NewMemoryUserStorage()
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	purger := NewUserPurger(storage, 24*time.Hour)
+	purger.now = func() time.Time {
+		return now
+	}
+
+	expired := addUser(t, storage, "John", "Doe", "john@example.com")
+	retained := addUser(t, storage, "Jane", "Doe", "jane@example.com")
+
+	err := storage.Delete(ctx, expired.ID(), now.Add(-25*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storage.Delete(ctx, retained.ID(), now.Add(-23*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := purger.Purge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if purged != 1 {
+		t.Fatalf("expected 1 user purged, got %d", purged)
+	}
+
+	err = storage.Restore(ctx, expired.ID(), now)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected the user deleted before the retention period to be purged, got %v", err)
+	}
+
+	err = storage.Restore(ctx, retained.ID(), now)
+	if err != nil {
+		t.Errorf("expected the user deleted within the retention period to be restorable, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrEmailAlreadyExists = errors.New("email already exists")
+	ErrUserModified       = errors.New("user has been modified in the meantime")
+)
+
+// UserFilter narrows down the users returned by UserRepository.All. Zero values match all users.
+type UserFilter struct {
+	CreatedAfter time.Time
+	// AfterID and Limit page through users ordered by ID, without keeping all of them in memory.
+	AfterID int
+	Limit   int
+}
+
+// PersonalData is everything stored about a user, returned by data exports.
+type PersonalData struct {
+	User User
+	// LastIP is empty if it wasn't recorded.
+	LastIP string
+	// HasPassword tells if a password hash is stored. The hash is a credential, so it's never exported.
+	HasPassword bool
+}
+
+// UserRepository stores users. All implementations must return the errors above,
+// so the handlers don't depend on the database behind it.
+//
+// Users belong to organizations. All methods except Purge see only users of the organization from the context
+// (see ContextWithOrganizationID), and return ErrOrganizationRequired without it. E-mails are unique per organization.
+//
+// Add, Update, Delete and Erase take the events of the change. The user lifecycle events are stored as webhook deliveries
+// in the same transaction as the change, so they're sent only if the change is saved. The caller publishes the events.
+type UserRepository interface {
+	All(ctx context.Context, filter UserFilter) ([]User, error)
+	// ByID returns ErrUserNotFound if there's no such user.
+	ByID(ctx context.Context, id int) (User, error)
+	// Add returns the new user's ID, or ErrEmailAlreadyExists if the user's e-mail belongs to someone else
+	// in the organization, ignoring the case.
+	Add(ctx context.Context, user User, events ...NewUserEvent) (int, error)
+	// AddBatch adds the users in a single transaction, and returns an error for each of them, in the same order.
+	// Users whose e-mail belongs to someone else get ErrEmailAlreadyExists and are skipped, and the rest is added.
+	// The second error means the whole batch failed.
+	AddBatch(ctx context.Context, users []User) ([]error, error)
+	// Update saves the user's name, primary e-mail and modification time. It returns ErrUserModified
+	// if the user's version changed since it was read.
+	Update(ctx context.Context, user User, events ...interface{}) error
+	// AddEmail returns ErrUserNotFound if there's no such user, and ErrEmailAlreadyExists if the address belongs
+	// to any user in the organization, ignoring the case.
+	AddEmail(ctx context.Context, userID int, email Email) error
+	// VerifyEmail returns ErrEmailNotFound if no user has the address.
+	VerifyEmail(ctx context.Context, address string) error
+	// Delete marks the user as deleted, so it can be restored until it's purged. Deleted users don't exist
+	// for the other methods, unless stated otherwise, but their e-mails stay taken.
+	// Deleting a missing user does nothing, and stores no deliveries of the events.
+	Delete(ctx context.Context, id int, now time.Time, events ...interface{}) error
+	// Restore undoes Delete. It returns ErrUserNotFound if there's no such deleted user.
+	Restore(ctx context.Context, id int, now time.Time) error
+	// Purge removes users deleted before the given time for good, freeing their e-mails, and returns how many were removed.
+	// It's a maintenance job, so it purges users of all organizations, and doesn't need one in the context.
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
+	// PersonalData returns ErrUserNotFound if there's no such user. It includes deleted users, as their data is still stored.
+	PersonalData(ctx context.Context, id int) (PersonalData, error)
+	// Erase anonymizes the user instead of deleting it, so references to its ID stay valid.
+	// It clears the names, password hash and last IP, deletes the e-mails and the webhook deliveries about the user,
+	// and stores an audit record of the erasure. The deliveries of the events are stored after the old ones are deleted.
+	// Deleted users can be erased too. Erased users don't exist for the other methods, and are never purged.
+	// Erase returns ErrUserNotFound if there's no such user.
+	Erase(ctx context.Context, id int, now time.Time, events ...interface{}) error
+}
+
+// NewUserEvent returns the event about the user added with the given ID, as the ID isn't known before it's added.
+type NewUserEvent func(userID int) interface{}
+
+func newUserEvents(userID int, newEvents []NewUserEvent) []interface{} {
+	events := make([]interface{}, 0, len(newEvents))
+	for _, newEvent := range newEvents {
+		events = append(events, newEvent(userID))
+	}
+
+	return events
+}
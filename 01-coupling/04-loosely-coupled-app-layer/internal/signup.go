@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// signUp saves the new user, sends the verification e-mail and publishes UserSignedUp.
+// If the e-mail can't be sent, the error is only logged, as the user is created anyway.
+// All APIs create users with it, so they behave the same way.
+func signUp(
+	ctx context.Context,
+	storage UserRepository,
+	verificationTokens VerificationTokens,
+	mailer Mailer,
+	eventPublisher EventPublisher,
+	user User,
+	productNewsConsent bool,
+) (int, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	signedUp := func(userID int) interface{} {
+		return UserSignedUp{
+			ID:                 strconv.Itoa(userID),
+			OrganizationID:     organizationID,
+			Email:              user.PrimaryEmail().Address(),
+			ProductNewsConsent: productNewsConsent,
+		}
+	}
+
+	userID, err := storage.Add(ctx, user, signedUp)
+	if err != nil {
+		return 0, err
+	}
+
+	// The user is already saved, so failing here would make retries fail with ErrEmailAlreadyExists.
+	err = sendVerificationEmail(ctx, verificationTokens, mailer, user.PrimaryEmail())
+	if err != nil {
+		log.Println("Error while sending the verification e-mail:", err)
+	}
+
+	// The user is already saved, so the event is lost if publishing fails. Its webhook deliveries aren't,
+	// as they're stored with the user. See 05-distributed-transactions/03-outbox for publishing events reliably.
+	err = eventPublisher.Publish(ctx, signedUp(userID))
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// sendVerificationEmail sends the token verifying the address in the context's organization.
+func sendVerificationEmail(ctx context.Context, verificationTokens VerificationTokens, mailer Mailer, email Email) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	token, err := verificationTokens.New(organizationID, email.Address())
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(ctx, Mail{
+		To:      email.Address(),
+		Subject: "Verify your e-mail address",
+		Body:    fmt.Sprintf("To verify your e-mail address, send this token to POST /emails/verify:\n\n%s", token),
+	})
+}
@@ -2,13 +2,15 @@ package internal
 
 import (
 	"errors"
-	"strings"
+	"time"
 )
 
 var (
-	ErrNameRequired  = errors.New("either first name or last name is required")
-	ErrEmailRequired = errors.New("email address is required")
-	ErrInvalidEmail  = errors.New("invalid email address")
+	ErrNameRequired     = errors.New("either first name or last name is required")
+	ErrEmailRequired    = errors.New("email address is required")
+	ErrInvalidEmail     = errors.New("invalid email address")
+	ErrEmailNotFound    = errors.New("email address not found")
+	ErrEmailNotVerified = errors.New("email address is not verified")
 )
 
 type User struct {
@@ -16,9 +18,13 @@ type User struct {
 	firstName string
 	lastName  string
 	emails    []Email
+	// version is incremented on each update, to detect concurrent changes.
+	version   int
+	createdAt time.Time
+	updatedAt time.Time
 }
 
-func NewUser(firstName string, lastName string, emailAddress string) (User, error) {
+func NewUser(firstName string, lastName string, emailAddress string, now time.Time) (User, error) {
 	if firstName == "" && lastName == "" {
 		return User{}, ErrNameRequired
 	}
@@ -32,16 +38,29 @@ func NewUser(firstName string, lastName string, emailAddress string) (User, erro
 		firstName: firstName,
 		lastName:  lastName,
 		emails:    []Email{email},
+		createdAt: now,
+		updatedAt: now,
 	}, nil
 }
 
 // UnmarshalUser loads the user from database data. It shouldn't be used for anything else.
-func UnmarshalUser(id int, firstName string, lastName string, emails []Email) User {
+func UnmarshalUser(
+	id int,
+	firstName string,
+	lastName string,
+	emails []Email,
+	version int,
+	createdAt time.Time,
+	updatedAt time.Time,
+) User {
 	return User{
 		id:        id,
 		firstName: firstName,
 		lastName:  lastName,
 		emails:    emails,
+		version:   version,
+		createdAt: createdAt,
+		updatedAt: updatedAt,
 	}
 }
 
@@ -61,6 +80,18 @@ func (u User) Emails() []Email {
 	return u.emails
 }
 
+func (u User) Version() int {
+	return u.version
+}
+
+func (u User) CreatedAt() time.Time {
+	return u.createdAt
+}
+
+func (u User) UpdatedAt() time.Time {
+	return u.updatedAt
+}
+
 func (u User) PrimaryEmail() Email {
 	for _, e := range u.emails {
 		if e.primary {
@@ -74,7 +105,58 @@ func (u User) PrimaryEmail() Email {
 	panic("no primary email found")
 }
 
-func (u *User) ChangeName(newFirstName *string, newLastName *string) error {
+// AddEmail adds a new, unverified e-mail address to the user.
+func (u *User) AddEmail(address string) (Email, error) {
+	email, err := NewEmail(address, false)
+	if err != nil {
+		return Email{}, err
+	}
+
+	for _, e := range u.emails {
+		if e.address.Equal(email.address) {
+			return Email{}, ErrEmailAlreadyExists
+		}
+	}
+
+	u.emails = append(u.emails, email)
+
+	return email, nil
+}
+
+// ChangePrimaryEmail makes the address primary. Only verified addresses can become primary.
+func (u *User) ChangePrimaryEmail(rawAddress string, now time.Time) error {
+	address, err := NewEmailAddress(rawAddress)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, e := range u.emails {
+		if !e.address.Equal(address) {
+			continue
+		}
+
+		if !e.verified {
+			return ErrEmailNotVerified
+		}
+
+		found = true
+	}
+
+	if !found {
+		return ErrEmailNotFound
+	}
+
+	for i := range u.emails {
+		u.emails[i].primary = u.emails[i].address.Equal(address)
+	}
+
+	u.updatedAt = now
+
+	return nil
+}
+
+func (u *User) ChangeName(newFirstName *string, newLastName *string, now time.Time) error {
 	if newFirstName == nil && newLastName == nil {
 		return nil
 	}
@@ -96,6 +178,7 @@ func (u *User) ChangeName(newFirstName *string, newLastName *string) error {
 
 	u.firstName = firstName
 	u.lastName = lastName
+	u.updatedAt = now
 
 	return nil
 }
@@ -113,18 +196,15 @@ func (u User) DisplayName() string {
 }
 
 type Email struct {
-	address string
-	primary bool
+	address  EmailAddress
+	primary  bool
+	verified bool
 }
 
-func NewEmail(address string, primary bool) (Email, error) {
-	if address == "" {
-		return Email{}, ErrEmailRequired
-	}
-
-	// A naive validation to make the example short, but you get the idea
-	if !strings.Contains(address, "@") {
-		return Email{}, ErrInvalidEmail
+func NewEmail(rawAddress string, primary bool) (Email, error) {
+	address, err := NewEmailAddress(rawAddress)
+	if err != nil {
+		return Email{}, err
 	}
 
 	return Email{
@@ -134,17 +214,23 @@ func NewEmail(address string, primary bool) (Email, error) {
 }
 
 // UnmarshalEmail loads the e-mail from database data. It shouldn't be used for anything else.
-func UnmarshalEmail(address string, primary bool) Email {
+func UnmarshalEmail(address string, primary bool, verified bool) Email {
 	return Email{
-		address: address,
-		primary: primary,
+		address:  EmailAddress{address: address},
+		primary:  primary,
+		verified: verified,
 	}
 }
 
 func (e Email) Address() string {
-	return e.address
+	return e.address.String()
 }
 
 func (e Email) Primary() bool {
 	return e.primary
 }
+
+// Verified tells if the owner confirmed the address with the token sent to it.
+func (e Email) Verified() bool {
+	return e.verified
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUser_ChangePrimaryEmail(t *testing.T) {
+	newUser := func() User {
+		return UnmarshalUser(1, "John", "Doe", []Email{
+			UnmarshalEmail("john@example.com", true, false),
+			UnmarshalEmail("verified@example.com", false, true),
+			UnmarshalEmail("unverified@example.com", false, false),
+		}, 1, time.Time{}, time.Time{})
+	}
+
+	testCases := []struct {
+		Name                 string
+		Address              string
+		ExpectedErr          error
+		ExpectedPrimaryEmail string
+	}{
+		{
+			Name:                 "verified",
+			Address:              "verified@example.com",
+			ExpectedPrimaryEmail: "verified@example.com",
+		},
+		{
+			Name:                 "unverified",
+			Address:              "unverified@example.com",
+			ExpectedErr:          ErrEmailNotVerified,
+			ExpectedPrimaryEmail: "john@example.com",
+		},
+		{
+			Name:                 "not_found",
+			Address:              "unknown@example.com",
+			ExpectedErr:          ErrEmailNotFound,
+			ExpectedPrimaryEmail: "john@example.com",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			user := newUser()
+			now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+			err := user.ChangePrimaryEmail(tc.Address, now)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if tc.ExpectedErr == nil && !user.UpdatedAt().Equal(now) {
+				t.Errorf("expected updated at %v, got %v", now, user.UpdatedAt())
+			}
+
+			if user.PrimaryEmail().Address() != tc.ExpectedPrimaryEmail {
+				t.Errorf("expected primary e-mail %q, got %q", tc.ExpectedPrimaryEmail, user.PrimaryEmail().Address())
+			}
+
+			primaryCount := 0
+			for _, e := range user.Emails() {
+				if e.Primary() {
+					primaryCount++
+				}
+			}
+
+			if primaryCount != 1 {
+				t.Errorf("expected exactly one primary e-mail, got %d", primaryCount)
+			}
+		})
+	}
+}
+
+func TestUser_AddEmail(t *testing.T) {
+	user, err := NewUser("John", "Doe", "john@example.com", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.PrimaryEmail().Verified() {
+		t.Error("expected the e-mail of a new user to be unverified")
+	}
+
+	email, err := user.AddEmail("john.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Primary() || email.Verified() {
+		t.Error("expected the added e-mail to be neither primary nor verified")
+	}
+
+	_, err = user.AddEmail("john.doe@example.com")
+	if !errors.Is(err, ErrEmailAlreadyExists) {
+		t.Errorf("expected %v, got %v", ErrEmailAlreadyExists, err)
+	}
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerificationTokens(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	address := "john@example.com"
+	organizationID := 2
+
+	tokens := NewVerificationTokens([]byte("secret"), time.Hour)
+	tokens.now = func() time.Time { return now }
+
+	token, err := tokens.New(organizationID, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSecretTokens := NewVerificationTokens([]byte("other-secret"), time.Hour)
+	otherSecretTokens.now = tokens.now
+
+	otherSecretToken, err := otherSecretTokens.New(organizationID, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Signed correctly, but issued before tokens had the organization.
+	withoutOrganizationPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"address":"john@example.com","expires_at":1622552400}`))
+	withoutOrganizationToken := withoutOrganizationPayload + "." + tokens.sign(withoutOrganizationPayload)
+
+	parts := strings.Split(token, ".")
+
+	testCases := []struct {
+		Name                   string
+		Token                  string
+		Now                    time.Time
+		ExpectedOrganizationID int
+		ExpectedAddress        string
+		ExpectedErr            error
+	}{
+		{
+			Name:                   "valid",
+			Token:                  token,
+			Now:                    now.Add(time.Hour - time.Second),
+			ExpectedOrganizationID: organizationID,
+			ExpectedAddress:        address,
+		},
+		{
+			Name:        "expired",
+			Token:       token,
+			Now:         now.Add(time.Hour),
+			ExpectedErr: ErrVerificationTokenExpired,
+		},
+		{
+			Name:        "signed_with_other_secret",
+			Token:       otherSecretToken,
+			Now:         now,
+			ExpectedErr: ErrInvalidVerificationToken,
+		},
+		{
+			Name:        "tampered_payload",
+			Token:       parts[0] + "x." + parts[1],
+			Now:         now,
+			ExpectedErr: ErrInvalidVerificationToken,
+		},
+		{
+			Name:        "without_organization",
+			Token:       withoutOrganizationToken,
+			Now:         now,
+			ExpectedErr: ErrInvalidVerificationToken,
+		},
+		{
+			Name:        "missing_signature",
+			Token:       parts[0],
+			Now:         now,
+			ExpectedErr: ErrInvalidVerificationToken,
+		},
+		{
+			Name:        "empty",
+			Token:       "",
+			Now:         now,
+			ExpectedErr: ErrInvalidVerificationToken,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			verifier := tokens
+			verifier.now = func() time.Time { return tc.Now }
+
+			organizationID, address, err := verifier.Verify(tc.Token)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if organizationID != tc.ExpectedOrganizationID {
+				t.Errorf("expected organization %d, got %d", tc.ExpectedOrganizationID, organizationID)
+			}
+
+			if address != tc.ExpectedAddress {
+				t.Errorf("expected address %q, got %q", tc.ExpectedAddress, address)
+			}
+		})
+	}
+}
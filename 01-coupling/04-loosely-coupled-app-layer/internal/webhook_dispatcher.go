@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	webhookBatchSize = 10
+	// webhookLease has to be longer than sending a whole batch takes, so other dispatchers don't send it again.
+	webhookLease   = 5 * time.Minute
+	webhookTimeout = 10 * time.Second
+	// With the initial backoff doubled after each failed attempt, deliveries are retried for about 4 hours.
+	webhookMaxAttempts    = 10
+	webhookInitialBackoff = 30 * time.Second
+)
+
+// Headers of webhook requests. Receivers verify the signature by computing the hex-encoded HMAC-SHA256
+// of the timestamp, a dot and the body, with the subscription's secret.
+const (
+	webhookDeliveryHeader  = "Webhook-Delivery"
+	webhookEventHeader     = "Webhook-Event"
+	webhookTimestampHeader = "Webhook-Timestamp"
+	webhookSignatureHeader = "Webhook-Signature"
+)
+
+// WebhookDispatcher sends the deliveries stored by the user storages. Failed deliveries are retried with exponential backoff,
+// until they run out of attempts. Receivers can get a delivery more than once, so they should deduplicate it by its ID.
+type WebhookDispatcher struct {
+	storage        WebhookRepository
+	client         *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+	now            func() time.Time
+}
+
+func NewWebhookDispatcher(storage WebhookRepository) WebhookDispatcher {
+	return WebhookDispatcher{
+		storage: storage,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+		},
+		maxAttempts:    webhookMaxAttempts,
+		initialBackoff: webhookInitialBackoff,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// Dispatch sends all due deliveries once, and returns how many were attempted.
+// Failed attempts aren't errors, as they're recorded in the deliveries.
+func (d WebhookDispatcher) Dispatch(ctx context.Context) (int, error) {
+	var attempted int
+
+	for {
+		deliveries, err := d.storage.ClaimDeliveries(ctx, d.now(), webhookLease, webhookBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+
+		if len(deliveries) == 0 {
+			return attempted, nil
+		}
+
+		// The deliveries can be of many organizations, and subscriptions are read per organization.
+		subscriptions := map[int]map[int]WebhookSubscription{}
+
+		for _, delivery := range deliveries {
+			organizationSubscriptions, ok := subscriptions[delivery.OrganizationID]
+			if !ok {
+				organizationSubscriptions, err = d.subscriptions(ContextWithOrganizationID(ctx, delivery.OrganizationID))
+				if err != nil {
+					return attempted, err
+				}
+
+				subscriptions[delivery.OrganizationID] = organizationSubscriptions
+			}
+
+			subscription, ok := organizationSubscriptions[delivery.SubscriptionID]
+			if !ok {
+				// The subscription was deleted after claiming, together with the delivery.
+				continue
+			}
+
+			err = d.attempt(ctx, subscription, delivery)
+			if err != nil {
+				return attempted, err
+			}
+
+			attempted++
+		}
+	}
+}
+
+// Run dispatches deliveries every interval, until the context is canceled. Failures are logged and retried on the next run.
+func (d WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := d.Dispatch(ctx)
+		if err != nil {
+			log.Println("Error while dispatching webhooks:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d WebhookDispatcher) subscriptions(ctx context.Context) (map[int]WebhookSubscription, error) {
+	subscriptions, err := d.storage.Subscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]WebhookSubscription, len(subscriptions))
+	for _, s := range subscriptions {
+		byID[s.ID] = s
+	}
+
+	return byID, nil
+}
+
+// attempt sends the delivery, and saves the result.
+func (d WebhookDispatcher) attempt(ctx context.Context, subscription WebhookSubscription, delivery WebhookDelivery) error {
+	statusCode, err := d.send(ctx, subscription, delivery)
+
+	now := d.now()
+
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case err == nil:
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.DeliveredAt = now
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.NextAttemptAt = now.Add(d.initialBackoff << (delivery.Attempts - 1))
+		delivery.LastError = err.Error()
+	}
+
+	return d.storage.UpdateDelivery(ctx, delivery)
+}
+
+// send returns the status code of the response, or 0 if there was none. Responses other than 2xx are errors.
+func (d WebhookDispatcher) send(ctx context.Context, subscription WebhookSubscription, delivery WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookDeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(webhookEventHeader, delivery.EventType)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+signWebhook(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	// Reading the body lets the connection be reused. Receivers shouldn't send much back.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// signWebhook signs the timestamp together with the body, so old requests can't be replayed with a new timestamp.
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
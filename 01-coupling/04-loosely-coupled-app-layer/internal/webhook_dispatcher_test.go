@@ -0,0 +1,335 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookDispatcher_Dispatch(t *testing.T) {
+	ctx := testContext()
+
+	receiver := newWebhookReceiver(t, http.StatusNoContent)
+
+	storage := NewMemoryWebhookStorage()
+	subscription := addWebhookSubscription(t, storage, receiver.URL, WebhookEventUserCreated)
+
+	dispatcher := NewWebhookDispatcher(storage)
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time {
+		return now
+	}
+
+	payload := `{"type":"user.created","occurred_at":"2021-03-01T12:00:00Z","data":{"id":"1"}}`
+
+	err := storage.AddDeliveries(ctx, []WebhookDelivery{newTestWebhookDelivery(subscription.ID, payload, now)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempted, err := dispatcher.Dispatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempted != 1 {
+		t.Errorf("expected 1 delivery attempted, got %d", attempted)
+	}
+
+	requests := receiver.requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+
+	req := requests[0]
+
+	if req.Body != payload {
+		t.Errorf("expected body %s, got %s", payload, req.Body)
+	}
+
+	expectedHeaders := map[string]string{
+		"Content-Type":      "application/json",
+		"Webhook-Delivery":  "1",
+		"Webhook-Event":     WebhookEventUserCreated,
+		"Webhook-Timestamp": strconv.FormatInt(now.Unix(), 10),
+	}
+
+	for name, expected := range expectedHeaders {
+		if value := req.Header.Get(name); value != expected {
+			t.Errorf("expected header %s: %s, got %q", name, expected, value)
+		}
+	}
+
+	// Verified the way receivers do it.
+	mac := hmac.New(sha256.New, []byte(subscription.Secret))
+	mac.Write([]byte(req.Header.Get("Webhook-Timestamp") + "." + req.Body))
+	expectedSignature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if signature := req.Header.Get("Webhook-Signature"); signature != expectedSignature {
+		t.Errorf("expected signature %s, got %s", expectedSignature, signature)
+	}
+
+	delivery := lastWebhookDelivery(t, storage, subscription.ID)
+
+	if delivery.Status != WebhookDeliveryDelivered || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusNoContent {
+		t.Errorf("expected the delivery to be delivered at the first attempt, got %+v", delivery)
+	}
+
+	if !delivery.DeliveredAt.Equal(now) {
+		t.Errorf("expected delivered at %v, got %v", now, delivery.DeliveredAt)
+	}
+
+	attempted, err = dispatcher.Dispatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempted != 0 {
+		t.Errorf("expected delivered deliveries not to be sent again, got %d attempted", attempted)
+	}
+}
+
+func TestWebhookDispatcher_Dispatch_retriesWithBackoff(t *testing.T) {
+	ctx := testContext()
+
+	receiver := newWebhookReceiver(t, http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK)
+
+	storage := NewMemoryWebhookStorage()
+	subscription := addWebhookSubscription(t, storage, receiver.URL, WebhookEventUserCreated)
+
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	dispatcher := NewWebhookDispatcher(storage)
+	dispatcher.initialBackoff = time.Minute
+
+	err := storage.AddDeliveries(ctx, []WebhookDelivery{newTestWebhookDelivery(subscription.ID, `{}`, start)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Name                   string
+		Elapsed                time.Duration
+		ExpectedAttempted      int
+		ExpectedStatus         WebhookDeliveryStatus
+		ExpectedLastStatusCode int
+		// ExpectedNextAttemptIn is counted from the start.
+		ExpectedNextAttemptIn time.Duration
+	}{
+		{
+			Name:                   "first_attempt",
+			Elapsed:                0,
+			ExpectedAttempted:      1,
+			ExpectedStatus:         WebhookDeliveryPending,
+			ExpectedLastStatusCode: http.StatusServiceUnavailable,
+			ExpectedNextAttemptIn:  time.Minute,
+		},
+		{
+			Name:                   "before_backoff",
+			Elapsed:                30 * time.Second,
+			ExpectedAttempted:      0,
+			ExpectedStatus:         WebhookDeliveryPending,
+			ExpectedLastStatusCode: http.StatusServiceUnavailable,
+			ExpectedNextAttemptIn:  time.Minute,
+		},
+		{
+			Name:                   "second_attempt",
+			Elapsed:                time.Minute,
+			ExpectedAttempted:      1,
+			ExpectedStatus:         WebhookDeliveryPending,
+			ExpectedLastStatusCode: http.StatusInternalServerError,
+			// The backoff doubles.
+			ExpectedNextAttemptIn: 3 * time.Minute,
+		},
+		{
+			Name:                   "third_attempt",
+			Elapsed:                3 * time.Minute,
+			ExpectedAttempted:      1,
+			ExpectedStatus:         WebhookDeliveryDelivered,
+			ExpectedLastStatusCode: http.StatusOK,
+		},
+	}
+
+	// The steps depend on each other, so they run in order.
+	for _, tc := range testCases {
+		now := start.Add(tc.Elapsed)
+		dispatcher.now = func() time.Time {
+			return now
+		}
+
+		attempted, err := dispatcher.Dispatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if attempted != tc.ExpectedAttempted {
+			t.Errorf("%s: expected %d attempted, got %d", tc.Name, tc.ExpectedAttempted, attempted)
+		}
+
+		delivery := lastWebhookDelivery(t, storage, subscription.ID)
+
+		if delivery.Status != tc.ExpectedStatus || delivery.LastStatusCode != tc.ExpectedLastStatusCode {
+			t.Errorf("%s: expected status %s after %d, got %s after %d",
+				tc.Name, tc.ExpectedStatus, tc.ExpectedLastStatusCode, delivery.Status, delivery.LastStatusCode)
+		}
+
+		expectedNextAttemptAt := start.Add(tc.ExpectedNextAttemptIn)
+		if tc.ExpectedStatus == WebhookDeliveryPending && !delivery.NextAttemptAt.Equal(expectedNextAttemptAt) {
+			t.Errorf("%s: expected next attempt at %v, got %v", tc.Name, expectedNextAttemptAt, delivery.NextAttemptAt)
+		}
+	}
+
+	requests := receiver.requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+
+	for _, req := range requests {
+		if req.Header.Get("Webhook-Delivery") != requests[0].Header.Get("Webhook-Delivery") {
+			t.Errorf("expected all attempts to have the same delivery ID, got %q and %q",
+				requests[0].Header.Get("Webhook-Delivery"), req.Header.Get("Webhook-Delivery"))
+		}
+	}
+}
+
+func TestWebhookDispatcher_Dispatch_givesUp(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	testCases := []struct {
+		Name                   string
+		URL                    string
+		ExpectedLastStatusCode int
+	}{
+		{
+			Name:                   "error_response",
+			URL:                    newWebhookReceiver(t, http.StatusBadRequest).URL,
+			ExpectedLastStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name: "unreachable",
+			URL:  unreachable.URL,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx := testContext()
+
+			storage := NewMemoryWebhookStorage()
+			subscription := addWebhookSubscription(t, storage, tc.URL, WebhookEventUserCreated)
+
+			dispatcher := NewWebhookDispatcher(storage)
+			dispatcher.maxAttempts = 3
+			// All attempts are due at once, so a single dispatch makes them all.
+			dispatcher.initialBackoff = 0
+
+			err := storage.AddDeliveries(ctx, []WebhookDelivery{newTestWebhookDelivery(subscription.ID, `{}`, dispatcher.now())})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			attempted, err := dispatcher.Dispatch(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if attempted != 3 {
+				t.Errorf("expected 3 attempts, got %d", attempted)
+			}
+
+			delivery := lastWebhookDelivery(t, storage, subscription.ID)
+
+			if delivery.Status != WebhookDeliveryFailed || delivery.Attempts != 3 {
+				t.Errorf("expected the delivery to fail after 3 attempts, got %+v", delivery)
+			}
+
+			if delivery.LastStatusCode != tc.ExpectedLastStatusCode {
+				t.Errorf("expected last status code %d, got %d", tc.ExpectedLastStatusCode, delivery.LastStatusCode)
+			}
+
+			if delivery.LastError == "" {
+				t.Error("expected the last error to be recorded")
+			}
+		})
+	}
+}
+
+// webhookReceiver records webhook requests, and responds with the given status codes in order.
+// The last status code is repeated for the following requests.
+type webhookReceiver struct {
+	*httptest.Server
+
+	lock        sync.Mutex
+	received    []receivedWebhook
+	statusCodes []int
+}
+
+type receivedWebhook struct {
+	Header http.Header
+	Body   string
+}
+
+func newWebhookReceiver(t *testing.T, statusCodes ...int) *webhookReceiver {
+	t.Helper()
+
+	r := &webhookReceiver{
+		statusCodes: statusCodes,
+	}
+
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		r.received = append(r.received, receivedWebhook{
+			Header: req.Header.Clone(),
+			Body:   string(body),
+		})
+
+		statusCode := r.statusCodes[0]
+		if len(r.statusCodes) > 1 {
+			r.statusCodes = r.statusCodes[1:]
+		}
+
+		w.WriteHeader(statusCode)
+	}))
+
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func (r *webhookReceiver) requests() []receivedWebhook {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]receivedWebhook(nil), r.received...)
+}
+
+func lastWebhookDelivery(t *testing.T, storage WebhookRepository, subscriptionID int) WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := storage.Deliveries(testContext(), subscriptionID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deliveries) == 0 {
+		t.Fatalf("expected a delivery of subscription %d", subscriptionID)
+	}
+
+	return deliveries[0]
+}
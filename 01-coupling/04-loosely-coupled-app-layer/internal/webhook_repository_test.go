@@ -0,0 +1,367 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate"
+)
+
+func TestMemoryWebhookStorage(t *testing.T) {
+	testWebhookRepository(t, NewMemoryWebhookStorage())
+}
+
+// TestMySQLWebhookStorage runs against the MySQL database from docker-compose, like TestUserStorage.
+func TestMySQLWebhookStorage(t *testing.T) {
+	dsn := os.Getenv("MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_DSN not set")
+	}
+
+	db := openTestDB(t, "mysql", dsn, NewMySQLMigrator)
+
+	testWebhookRepository(t, NewMySQLWebhookStorage(db))
+
+	t.Run("user_webhook_deliveries", func(t *testing.T) {
+		testUserWebhookDeliveries(t, NewUserStorage(db), NewMySQLWebhookStorage(db))
+	})
+}
+
+// TestPostgresWebhookStorage runs against the PostgreSQL database from docker-compose, like TestPostgresUserStorage.
+func TestPostgresWebhookStorage(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN not set")
+	}
+
+	db := openTestDB(t, "postgres", dsn, NewPostgresMigrator)
+
+	testWebhookRepository(t, NewPostgresWebhookStorage(db))
+
+	t.Run("user_webhook_deliveries", func(t *testing.T) {
+		testUserWebhookDeliveries(t, NewPostgresUserStorage(db), NewPostgresWebhookStorage(db))
+	})
+}
+
+// testWebhookRepository is the contract all WebhookRepository implementations have to fulfill.
+func testWebhookRepository(t *testing.T, repo WebhookRepository) {
+	testCases := []struct {
+		Name     string
+		TestFunc func(*testing.T, WebhookRepository)
+	}{
+		{
+			Name:     "subscriptions",
+			TestFunc: testWebhookRepositorySubscriptions,
+		},
+		{
+			Name:     "deliveries",
+			TestFunc: testWebhookRepositoryDeliveries,
+		},
+		{
+			Name:     "claim_deliveries",
+			TestFunc: testWebhookRepositoryClaimDeliveries,
+		},
+		{
+			Name:     "update_delivery",
+			TestFunc: testWebhookRepositoryUpdateDelivery,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			tc.TestFunc(t, repo)
+		})
+	}
+}
+
+func testWebhookRepositorySubscriptions(t *testing.T, repo WebhookRepository) {
+	ctx := testContext()
+
+	subscription := addWebhookSubscription(t, repo, testWebhookURL, WebhookEventUserCreated, WebhookEventUserDeleted)
+
+	subscriptions, err := repo.Subscriptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, ok := findWebhookSubscription(subscriptions, subscription.ID)
+	if !ok {
+		t.Fatalf("expected subscription %d to be listed", subscription.ID)
+	}
+
+	if found.URL != subscription.URL || found.Secret != subscription.Secret || !found.CreatedAt.Equal(subscription.CreatedAt) {
+		t.Errorf("expected subscription %+v, got %+v", subscription, found)
+	}
+
+	if len(found.EventTypes) != 2 || !found.Subscribes(WebhookEventUserCreated) || !found.Subscribes(WebhookEventUserDeleted) {
+		t.Errorf("expected event types %v, got %v", subscription.EventTypes, found.EventTypes)
+	}
+
+	err = repo.DeleteSubscription(ctx, subscription.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriptions, err = repo.Subscriptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := findWebhookSubscription(subscriptions, subscription.ID); ok {
+		t.Errorf("expected subscription %d to be deleted", subscription.ID)
+	}
+
+	err = repo.DeleteSubscription(ctx, subscription.ID)
+	if !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected %v, got %v", ErrWebhookNotFound, err)
+	}
+}
+
+func testWebhookRepositoryDeliveries(t *testing.T, repo WebhookRepository) {
+	ctx := testContext()
+
+	subscription := addWebhookSubscription(t, repo, testWebhookURL, WebhookEventUserCreated)
+	other := addWebhookSubscription(t, repo, testWebhookURL, WebhookEventUserCreated)
+
+	// Not due for years, so the claim_deliveries test doesn't lease them in the meantime.
+	now := testNow().AddDate(10, 0, 0)
+
+	err := repo.AddDeliveries(ctx, []WebhookDelivery{
+		newTestWebhookDelivery(subscription.ID, `{"n": 1}`, now),
+		newTestWebhookDelivery(other.ID, `{"n": 2}`, now),
+		newTestWebhookDelivery(subscription.ID, `{"n": 3}`, now),
+		newTestWebhookDelivery(subscription.ID, `{"n": 4}`, now),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := repo.Deliveries(ctx, subscription.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(deliveries))
+	}
+
+	// The latest deliveries come first.
+	for i, expectedPayload := range []string{`{"n": 4}`, `{"n": 3}`} {
+		d := deliveries[i]
+
+		if string(d.Payload) != expectedPayload {
+			t.Errorf("expected payload %s, got %s", expectedPayload, d.Payload)
+		}
+
+		if d.SubscriptionID != subscription.ID || d.UserID != 1 || d.EventType != WebhookEventUserCreated || d.Status != WebhookDeliveryPending {
+			t.Errorf("expected a pending %s delivery of subscription %d about user 1, got %+v", WebhookEventUserCreated, subscription.ID, d)
+		}
+
+		if !d.NextAttemptAt.Equal(now) || !d.CreatedAt.Equal(now) || !d.DeliveredAt.IsZero() {
+			t.Errorf("expected the delivery to be scheduled and created at %v, got %+v", now, d)
+		}
+	}
+
+	err = repo.DeleteSubscription(ctx, subscription.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.Deliveries(ctx, subscription.ID, 2)
+	if !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected %v, got %v", ErrWebhookNotFound, err)
+	}
+}
+
+func testWebhookRepositoryClaimDeliveries(t *testing.T, repo WebhookRepository) {
+	ctx := testContext()
+
+	subscription := addWebhookSubscription(t, repo, testWebhookURL, WebhookEventUserCreated)
+
+	now := testNow()
+	lease := time.Minute
+
+	due := newTestWebhookDelivery(subscription.ID, `{"due": true}`, now.Add(-time.Second))
+	notDue := newTestWebhookDelivery(subscription.ID, `{"due": false}`, now.Add(time.Second))
+	delivered := newTestWebhookDelivery(subscription.ID, `{"delivered": true}`, now.Add(-time.Second))
+	delivered.Status = WebhookDeliveryDelivered
+
+	err := repo.AddDeliveries(ctx, []WebhookDelivery{due, notDue, delivered})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed := claimWebhookDeliveries(t, repo, subscription.ID, now, lease)
+	if len(claimed) != 1 || string(claimed[0].Payload) != string(due.Payload) {
+		t.Fatalf("expected only the due delivery to be claimed, got %+v", claimed)
+	}
+
+	claimed = claimWebhookDeliveries(t, repo, subscription.ID, now, lease)
+	if len(claimed) != 0 {
+		t.Errorf("expected the claimed delivery to be leased, got %+v", claimed)
+	}
+
+	// Both the lease and the other delivery are over.
+	claimed = claimWebhookDeliveries(t, repo, subscription.ID, now.Add(lease), lease)
+	if len(claimed) != 2 {
+		t.Errorf("expected both pending deliveries to be claimed after the lease, got %+v", claimed)
+	}
+}
+
+func testWebhookRepositoryUpdateDelivery(t *testing.T, repo WebhookRepository) {
+	ctx := testContext()
+
+	subscription := addWebhookSubscription(t, repo, testWebhookURL, WebhookEventUserCreated)
+
+	// Not due for years, so the claim_deliveries test doesn't lease it in the meantime.
+	now := testNow().AddDate(10, 0, 0)
+
+	err := repo.AddDeliveries(ctx, []WebhookDelivery{newTestWebhookDelivery(subscription.ID, `{}`, now)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := repo.Deliveries(ctx, subscription.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := deliveries[0]
+	delivery.Attempts = 1
+	delivery.LastStatusCode = 503
+	delivery.LastError = "unexpected status code 503"
+	delivery.NextAttemptAt = now.Add(time.Minute)
+
+	err = repo.UpdateDelivery(ctx, delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err = repo.Deliveries(ctx, subscription.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deliveries[0].Attempts != 1 || deliveries[0].LastStatusCode != 503 || deliveries[0].LastError != delivery.LastError ||
+		!deliveries[0].NextAttemptAt.Equal(delivery.NextAttemptAt) {
+		t.Errorf("expected the failed attempt to be saved, got %+v", deliveries[0])
+	}
+
+	delivery.Attempts = 2
+	delivery.Status = WebhookDeliveryDelivered
+	delivery.LastStatusCode = 200
+	delivery.LastError = ""
+	delivery.DeliveredAt = now.Add(time.Minute)
+
+	err = repo.UpdateDelivery(ctx, delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err = repo.Deliveries(ctx, subscription.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deliveries[0].Status != WebhookDeliveryDelivered || deliveries[0].LastError != "" || !deliveries[0].DeliveredAt.Equal(delivery.DeliveredAt) {
+		t.Errorf("expected the delivery to be delivered, got %+v", deliveries[0])
+	}
+}
+
+// testWebhookURL is the URL of subscriptions that are never dispatched.
+const testWebhookURL = "https://example.com/webhooks"
+
+// addWebhookSubscription adds a new subscription and returns it with its ID.
+func addWebhookSubscription(t *testing.T, repo WebhookRepository, url string, eventTypes ...string) WebhookSubscription {
+	t.Helper()
+
+	subscription, err := NewWebhookSubscription(url, "secret", eventTypes, testNow())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscription.ID, err = repo.AddSubscription(testContext(), subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return subscription
+}
+
+func newTestWebhookDelivery(subscriptionID int, payload string, nextAttemptAt time.Time) WebhookDelivery {
+	return WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		UserID:         1,
+		EventType:      WebhookEventUserCreated,
+		Payload:        []byte(payload),
+		Status:         WebhookDeliveryPending,
+		NextAttemptAt:  nextAttemptAt,
+		CreatedAt:      nextAttemptAt,
+	}
+}
+
+// claimWebhookDeliveries claims all due deliveries, and returns the ones of the subscription.
+// Other tests sharing the database can have due deliveries too, so they're claimed in batches until none are left.
+func claimWebhookDeliveries(t *testing.T, repo WebhookRepository, subscriptionID int, now time.Time, lease time.Duration) []WebhookDelivery {
+	t.Helper()
+
+	var claimed []WebhookDelivery
+	for {
+		deliveries, err := repo.ClaimDeliveries(context.Background(), now, lease, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(deliveries) == 0 {
+			return claimed
+		}
+
+		for _, d := range deliveries {
+			if d.SubscriptionID == subscriptionID {
+				claimed = append(claimed, d)
+			}
+		}
+	}
+}
+
+func findWebhookSubscription(subscriptions []WebhookSubscription, id int) (WebhookSubscription, bool) {
+	for _, s := range subscriptions {
+		if s.ID == id {
+			return s, true
+		}
+	}
+
+	return WebhookSubscription{}, false
+}
+
+// openTestDB connects to the database and applies the migrations.
+func openTestDB(t *testing.T, driverName string, dsn string, newMigrator func(*sql.DB) (*migrate.Migrator, error)) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
@@ -0,0 +1,389 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// SQLWebhookStorage is the MySQL and PostgreSQL implementation of WebhookRepository.
+//
+// The tables are the same in both databases, so it uses plain SQL with the placeholders of the given database.
+type SQLWebhookStorage struct {
+	db       *sql.DB
+	postgres bool
+}
+
+func NewMySQLWebhookStorage(db *sql.DB) SQLWebhookStorage {
+	return SQLWebhookStorage{
+		db: db,
+	}
+}
+
+func NewPostgresWebhookStorage(db *sql.DB) SQLWebhookStorage {
+	return SQLWebhookStorage{
+		db:       db,
+		postgres: true,
+	}
+}
+
+// sqlExecutor is *sql.DB or *sql.Tx, as deliveries are also stored in the transactions of the user storages.
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+const webhookDeliveryColumns = `id, subscription_id, organization_id, user_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at`
+
+func (s SQLWebhookStorage) AddSubscription(ctx context.Context, subscription WebhookSubscription) (int, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO webhook_subscriptions (organization_id, url, secret, event_types, created_at) VALUES (?, ?, ?, ?, ?)`
+	args := []interface{}{
+		organizationID,
+		subscription.URL,
+		subscription.Secret,
+		strings.Join(subscription.EventTypes, ","),
+		subscription.CreatedAt,
+	}
+
+	if s.postgres {
+		var id int
+		err := s.db.QueryRowContext(ctx, s.rebind(query+` RETURNING id`), args...).Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+
+		return id, nil
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (s SQLWebhookStorage) Subscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.subscriptions(ctx, s.db, organizationID)
+}
+
+func (s SQLWebhookStorage) subscriptions(ctx context.Context, exec sqlExecutor, organizationID int) ([]WebhookSubscription, error) {
+	rows, err := exec.QueryContext(
+		ctx,
+		s.rebind(`SELECT id, url, secret, event_types, created_at FROM webhook_subscriptions WHERE organization_id = ? ORDER BY id`),
+		organizationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var subscriptions []WebhookSubscription
+	for rows.Next() {
+		var subscription WebhookSubscription
+		var eventTypes string
+
+		err = rows.Scan(&subscription.ID, &subscription.URL, &subscription.Secret, &eventTypes, &subscription.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		subscription.EventTypes = strings.Split(eventTypes, ",")
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func (s SQLWebhookStorage) DeleteSubscription(ctx context.Context, id int) error {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM webhook_subscriptions WHERE id = ? AND organization_id = ?`), id, organizationID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s SQLWebhookStorage) AddDeliveries(ctx context.Context, deliveries []WebhookDelivery) (err error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	return s.insertDeliveries(ctx, tx, organizationID, deliveries)
+}
+
+// addEventDeliveries stores the deliveries of the events in the transaction of the user change they're about.
+func (s SQLWebhookStorage) addEventDeliveries(ctx context.Context, tx *sql.Tx, events []interface{}, now time.Time) error {
+	for _, event := range events {
+		eventType, organizationID, userID, ok := webhookEvent(event)
+		if !ok {
+			continue
+		}
+
+		subscriptions, err := s.subscriptions(ctx, tx, organizationID)
+		if err != nil {
+			return err
+		}
+
+		deliveries, err := newWebhookDeliveries(event, eventType, userID, subscriptions, now)
+		if err != nil {
+			return err
+		}
+
+		err = s.insertDeliveries(ctx, tx, organizationID, deliveries)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s SQLWebhookStorage) insertDeliveries(ctx context.Context, exec sqlExecutor, organizationID int, deliveries []WebhookDelivery) error {
+	for _, d := range deliveries {
+		_, err := exec.ExecContext(
+			ctx,
+			s.rebind(`INSERT INTO webhook_deliveries (organization_id, subscription_id, user_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			organizationID, d.SubscriptionID, d.UserID, d.EventType, d.Payload, string(d.Status), d.Attempts, d.NextAttemptAt, d.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ClaimDeliveries locks the deliveries with SKIP LOCKED, so concurrent dispatchers claim different ones instead of waiting.
+func (s SQLWebhookStorage) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) (deliveries []WebhookDelivery, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		s.rebind(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED`),
+		string(WebhookDeliveryPending), now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err = scanWebhookDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{now.Add(lease)}
+	placeholders := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		args = append(args, d.ID)
+		placeholders = append(placeholders, "?")
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		s.rebind(`UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (`+strings.Join(placeholders, ", ")+`)`),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s SQLWebhookStorage) UpdateDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	var lastStatusCode sql.NullInt64
+	if delivery.LastStatusCode != 0 {
+		lastStatusCode = sql.NullInt64{Int64: int64(delivery.LastStatusCode), Valid: true}
+	}
+
+	var lastError sql.NullString
+	if delivery.LastError != "" {
+		lastError = sql.NullString{String: delivery.LastError, Valid: true}
+	}
+
+	var deliveredAt sql.NullTime
+	if !delivery.DeliveredAt.IsZero() {
+		deliveredAt = sql.NullTime{Time: delivery.DeliveredAt, Valid: true}
+	}
+
+	// No rows are updated if the subscription was deleted in the meantime, and that's fine.
+	_, err := s.db.ExecContext(
+		ctx,
+		s.rebind(`UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?`),
+		string(delivery.Status), delivery.Attempts, delivery.NextAttemptAt, lastStatusCode, lastError, deliveredAt, delivery.ID,
+	)
+
+	return err
+}
+
+func (s SQLWebhookStorage) Deliveries(ctx context.Context, subscriptionID int, limit int) ([]WebhookDelivery, error) {
+	organizationID, err := OrganizationIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	err = s.db.QueryRowContext(
+		ctx,
+		s.rebind(`SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = ? AND organization_id = ?)`),
+		subscriptionID, organizationID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		s.rebind(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE subscription_id = ? AND organization_id = ? ORDER BY id DESC LIMIT ?`),
+		subscriptionID, organizationID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanWebhookDeliveries(rows)
+}
+
+// rebind replaces the ? placeholders with $1, $2, ... for PostgreSQL. Queries can't have question marks otherwise.
+func (s SQLWebhookStorage) rebind(query string) string {
+	if !s.postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// scanWebhookDeliveries reads rows of webhookDeliveryColumns, and closes them.
+func scanWebhookDeliveries(rows *sql.Rows) ([]WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var status string
+		var lastStatusCode sql.NullInt64
+		var lastError sql.NullString
+		var deliveredAt sql.NullTime
+
+		err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.OrganizationID,
+			&d.UserID,
+			&d.EventType,
+			&d.Payload,
+			&status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&lastStatusCode,
+			&lastError,
+			&d.CreatedAt,
+			&deliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Status = WebhookDeliveryStatus(status)
+		d.LastStatusCode = int(lastStatusCode.Int64)
+		d.LastError = lastError.String
+		d.DeliveredAt = deliveredAt.Time
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
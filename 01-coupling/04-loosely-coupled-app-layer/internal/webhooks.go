@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook event types, which subscriptions can subscribe to.
+const (
+	WebhookEventUserCreated = "user.created"
+	WebhookEventUserRenamed = "user.renamed"
+	WebhookEventUserDeleted = "user.deleted"
+)
+
+var webhookEventTypes = []string{WebhookEventUserCreated, WebhookEventUserRenamed, WebhookEventUserDeleted}
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrInvalidWebhookURL       = errors.New("webhook URL must be an absolute http or https URL")
+	ErrWebhookSecretRequired   = errors.New("webhook secret is required")
+	ErrInvalidWebhookEventType = errors.New("webhook event types must be one or more of: " + strings.Join(webhookEventTypes, ", "))
+)
+
+// WebhookSubscription sends the events of its types to the URL, signed with the secret.
+type WebhookSubscription struct {
+	ID         int
+	URL        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+}
+
+func NewWebhookSubscription(rawURL string, secret string, eventTypes []string, now time.Time) (WebhookSubscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return WebhookSubscription{}, ErrInvalidWebhookURL
+	}
+
+	if secret == "" {
+		return WebhookSubscription{}, ErrWebhookSecretRequired
+	}
+
+	if len(eventTypes) == 0 {
+		return WebhookSubscription{}, ErrInvalidWebhookEventType
+	}
+
+	for _, t := range eventTypes {
+		if !containsString(webhookEventTypes, t) {
+			return WebhookSubscription{}, fmt.Errorf("%w, got %q", ErrInvalidWebhookEventType, t)
+		}
+	}
+
+	return WebhookSubscription{
+		URL:        rawURL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		CreatedAt:  now,
+	}, nil
+}
+
+func (s WebhookSubscription) Subscribes(eventType string) bool {
+	return containsString(s.EventTypes, eventType)
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending deliveries are sent when NextAttemptAt comes.
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryFailed deliveries ran out of attempts, and aren't sent anymore.
+	WebhookDeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is a single event sent to a subscription. Deliveries are kept after they're sent, as their log.
+type WebhookDelivery struct {
+	ID             int
+	SubscriptionID int
+	// OrganizationID is the organization of the subscription. AddDeliveries takes it from the context.
+	OrganizationID int
+	// UserID is the user the event is about. The payload has the user's personal data,
+	// so the deliveries are deleted when the user is erased.
+	UserID        int
+	EventType     string
+	Payload       []byte
+	Status        WebhookDeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	// LastStatusCode is 0 if the last attempt got no response.
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	// DeliveredAt is zero until the delivery succeeds.
+	DeliveredAt time.Time
+}
+
+// WebhookRepository stores webhook subscriptions and their deliveries.
+//
+// Like UserRepository, it works on the organization from the context, and returns ErrOrganizationRequired without one.
+// Only the dispatcher's methods, ClaimDeliveries and UpdateDelivery, work on deliveries of all organizations.
+type WebhookRepository interface {
+	// AddSubscription returns the new subscription's ID.
+	AddSubscription(ctx context.Context, subscription WebhookSubscription) (int, error)
+	Subscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	// DeleteSubscription deletes the subscription with its deliveries. It returns ErrWebhookNotFound if there's no such subscription.
+	DeleteSubscription(ctx context.Context, id int) error
+	// AddDeliveries stores the deliveries of the organization's subscriptions, ignoring their IDs.
+	AddDeliveries(ctx context.Context, deliveries []WebhookDelivery) error
+	// ClaimDeliveries returns up to limit pending deliveries due at the given time, oldest first.
+	// Their next attempt is postponed by the lease, so other dispatchers don't send them at the same time,
+	// and they're retried if the dispatcher dies before updating them.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error)
+	// UpdateDelivery saves the status, attempts and the results of the last attempt.
+	UpdateDelivery(ctx context.Context, delivery WebhookDelivery) error
+	// Deliveries returns up to limit latest deliveries of the subscription, newest first.
+	// It returns ErrWebhookNotFound if there's no such subscription.
+	Deliveries(ctx context.Context, subscriptionID int, limit int) ([]WebhookDelivery, error)
+}
+
+// webhookPayload is the body of webhook requests. Data is the event, as published to other services.
+type webhookPayload struct {
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// newWebhookDeliveries returns a delivery of the event for every subscription of its type.
+// The user storages store them in the transaction of the change the event is about, so they're an outbox.
+func newWebhookDeliveries(event interface{}, eventType string, userID int, subscriptions []WebhookSubscription, now time.Time) ([]WebhookDelivery, error) {
+	payload, err := json.Marshal(webhookPayload{
+		Type:       eventType,
+		OccurredAt: now,
+		Data:       event,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []WebhookDelivery
+	for _, s := range subscriptions {
+		if !s.Subscribes(eventType) {
+			continue
+		}
+
+		deliveries = append(deliveries, WebhookDelivery{
+			SubscriptionID: s.ID,
+			UserID:         userID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         WebhookDeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+
+	return deliveries, nil
+}
+
+// webhookEvent maps events to webhook event types, and returns the organization and the ID of the user they're about.
+// Only subscriptions of the organization get the event. Other events aren't sent to webhooks.
+func webhookEvent(event interface{}) (eventType string, organizationID int, userID int, ok bool) {
+	var id string
+	switch e := event.(type) {
+	case UserSignedUp:
+		eventType, organizationID, id = WebhookEventUserCreated, e.OrganizationID, e.ID
+	case UserRenamed:
+		eventType, organizationID, id = WebhookEventUserRenamed, e.OrganizationID, e.ID
+	case UserDeleted:
+		eventType, organizationID, id = WebhookEventUserDeleted, e.OrganizationID, e.ID
+	default:
+		return "", 0, 0, false
+	}
+
+	// The events are created with strconv.Itoa, so the ID is always a number.
+	userID, _ = strconv.Atoi(id)
+
+	return eventType, organizationID, userID, true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
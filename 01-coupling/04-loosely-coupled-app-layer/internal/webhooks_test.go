@@ -0,0 +1,312 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewWebhookSubscription(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		URL         string
+		Secret      string
+		EventTypes  []string
+		ExpectedErr error
+	}{
+		{
+			Name:       "valid",
+			URL:        "https://example.com/webhooks",
+			Secret:     "secret",
+			EventTypes: []string{WebhookEventUserCreated, WebhookEventUserDeleted},
+		},
+		{
+			Name:        "relative_url",
+			URL:         "/webhooks",
+			Secret:      "secret",
+			EventTypes:  []string{WebhookEventUserCreated},
+			ExpectedErr: ErrInvalidWebhookURL,
+		},
+		{
+			Name:        "unsupported_scheme",
+			URL:         "ftp://example.com/webhooks",
+			Secret:      "secret",
+			EventTypes:  []string{WebhookEventUserCreated},
+			ExpectedErr: ErrInvalidWebhookURL,
+		},
+		{
+			Name:        "missing_secret",
+			URL:         "https://example.com/webhooks",
+			EventTypes:  []string{WebhookEventUserCreated},
+			ExpectedErr: ErrWebhookSecretRequired,
+		},
+		{
+			Name:        "no_event_types",
+			URL:         "https://example.com/webhooks",
+			Secret:      "secret",
+			ExpectedErr: ErrInvalidWebhookEventType,
+		},
+		{
+			Name:        "unknown_event_type",
+			URL:         "https://example.com/webhooks",
+			Secret:      "secret",
+			EventTypes:  []string{WebhookEventUserCreated, "user.updated"},
+			ExpectedErr: ErrInvalidWebhookEventType,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := NewWebhookSubscription(tc.URL, tc.Secret, tc.EventTypes, time.Now())
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+// testUserWebhookDeliveries checks that the user storage stores the deliveries of the events with the changes,
+// in the webhook storage sharing its data.
+func testUserWebhookDeliveries(t *testing.T, users UserRepository, webhooks WebhookRepository) {
+	ctx := testContext()
+
+	created := addWebhookSubscription(t, webhooks, testWebhookURL, WebhookEventUserCreated)
+	all := addWebhookSubscription(t, webhooks, testWebhookURL, WebhookEventUserCreated, WebhookEventUserRenamed, WebhookEventUserDeleted)
+
+	now := testNow()
+
+	user, err := NewUser("John", "Doe", uniqueEmailAddress(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := users.Add(ctx, user, func(userID int) interface{} {
+		return UserSignedUp{ID: strconv.Itoa(userID), OrganizationID: testOrganizationID, Email: user.PrimaryEmail().Address()}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err = users.ByID(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := user
+
+	firstName := "Jack"
+	err = user.ChangeName(&firstName, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renamed := UserRenamed{ID: strconv.Itoa(userID), OrganizationID: testOrganizationID, FirstName: "Jack", LastName: "Doe", DisplayName: "Jack Doe"}
+
+	// Other events aren't sent to webhooks.
+	err = users.Update(ctx, user, renamed, struct{ ID string }{ID: strconv.Itoa(userID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes that aren't saved store no deliveries.
+	err = users.Update(ctx, stale, renamed)
+	if !errors.Is(err, ErrUserModified) {
+		t.Fatalf("expected %v, got %v", ErrUserModified, err)
+	}
+
+	deleted := UserDeleted{ID: strconv.Itoa(userID), OrganizationID: testOrganizationID}
+
+	err = users.Delete(ctx, userID, now, deleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting a deleted user does nothing, so it stores no deliveries either.
+	err = users.Delete(ctx, userID, now, deleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Name               string
+		SubscriptionID     int
+		ExpectedEventTypes []string
+	}{
+		{
+			Name:               "subscribed_to_created",
+			SubscriptionID:     created.ID,
+			ExpectedEventTypes: []string{WebhookEventUserCreated},
+		},
+		{
+			Name:               "subscribed_to_all",
+			SubscriptionID:     all.ID,
+			ExpectedEventTypes: []string{WebhookEventUserDeleted, WebhookEventUserRenamed, WebhookEventUserCreated},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			deliveries, err := webhooks.Deliveries(ctx, tc.SubscriptionID, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(deliveries) != len(tc.ExpectedEventTypes) {
+				t.Fatalf("expected %d deliveries, got %d", len(tc.ExpectedEventTypes), len(deliveries))
+			}
+
+			for i, d := range deliveries {
+				if d.EventType != tc.ExpectedEventTypes[i] {
+					t.Errorf("expected event type %s, got %s", tc.ExpectedEventTypes[i], d.EventType)
+				}
+
+				if d.UserID != userID || d.OrganizationID != testOrganizationID {
+					t.Errorf("expected the delivery of user %d in organization %d, got %+v", userID, testOrganizationID, d)
+				}
+
+				if d.Status != WebhookDeliveryPending || !d.NextAttemptAt.Equal(now) {
+					t.Errorf("expected the delivery to be pending and due at %v, got %+v", now, d)
+				}
+
+				var payload struct {
+					Type       string    `json:"type"`
+					OccurredAt time.Time `json:"occurred_at"`
+					Data       struct {
+						ID string `json:"id"`
+					} `json:"data"`
+				}
+
+				err = json.Unmarshal(d.Payload, &payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if payload.Type != d.EventType || !payload.OccurredAt.Equal(now) || payload.Data.ID != strconv.Itoa(userID) {
+					t.Errorf("expected the payload of the %s event of user %d, got %s", d.EventType, userID, d.Payload)
+				}
+			}
+		})
+	}
+
+	// Erasing deletes the old deliveries with the user's data, and stores the ones of its own events.
+	err = users.Erase(ctx, userID, now, UserDeleted{ID: strconv.Itoa(userID), OrganizationID: testOrganizationID, Erased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := webhooks.Deliveries(ctx, all.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deliveries) != 1 || deliveries[0].EventType != WebhookEventUserDeleted || !strings.Contains(string(deliveries[0].Payload), `"erased":true`) {
+		t.Errorf("expected only the delivery of the erasure, got %+v", deliveries)
+	}
+}
+
+func TestMemoryStorages_webhookDeliveries(t *testing.T) {
+	users, webhooks := NewMemoryStorages()
+	testUserWebhookDeliveries(t, users, webhooks)
+}
+
+func TestMemoryStorages_webhookDeliveriesScopedToOrganization(t *testing.T) {
+	otherCtx := ContextWithOrganizationID(context.Background(), otherTestOrganizationID)
+
+	users, webhooks := NewMemoryStorages()
+
+	subscription := addWebhookSubscription(t, webhooks, testWebhookURL, WebhookEventUserCreated)
+
+	otherSubscription, err := NewWebhookSubscription(testWebhookURL, "secret", []string{WebhookEventUserCreated}, testNow())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSubscription.ID, err = webhooks.AddSubscription(otherCtx, otherSubscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := NewUser("Jane", "Doe", uniqueEmailAddress(), testNow())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = users.Add(otherCtx, user, func(userID int) interface{} {
+		return UserSignedUp{ID: strconv.Itoa(userID), OrganizationID: otherTestOrganizationID, Email: user.PrimaryEmail().Address()}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := webhooks.Deliveries(testContext(), subscription.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deliveries) != 0 {
+		t.Errorf("expected no deliveries of another organization's event, got %+v", deliveries)
+	}
+
+	otherDeliveries, err := webhooks.Deliveries(otherCtx, otherSubscription.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(otherDeliveries) != 1 || otherDeliveries[0].OrganizationID != otherTestOrganizationID {
+		t.Errorf("expected a single delivery of organization %d, got %+v", otherTestOrganizationID, otherDeliveries)
+	}
+
+	// Subscriptions of another organization can't be read or deleted either.
+	_, err = webhooks.Deliveries(testContext(), otherSubscription.ID, 10)
+	if !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected %v, got %v", ErrWebhookNotFound, err)
+	}
+
+	err = webhooks.DeleteSubscription(testContext(), otherSubscription.ID)
+	if !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected %v, got %v", ErrWebhookNotFound, err)
+	}
+}
+
+func TestSignUp_webhookDeliveriesKeptWhenPublishingFails(t *testing.T) {
+	ctx := testContext()
+
+	users, webhooks := NewMemoryStorages()
+	subscription := addWebhookSubscription(t, webhooks, testWebhookURL, WebhookEventUserCreated)
+
+	user, err := NewUser("John", "Doe", uniqueEmailAddress(), testNow())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := NewVerificationTokens([]byte("secret"), time.Hour)
+
+	_, err = signUp(ctx, users, tokens, NewWriterMailer(io.Discard), failingEventPublisher{}, user, false)
+	if !errors.Is(err, errPublishingFailed) {
+		t.Fatalf("expected %v, got %v", errPublishingFailed, err)
+	}
+
+	deliveries, err := webhooks.Deliveries(ctx, subscription.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deliveries) != 1 {
+		t.Errorf("expected the delivery stored with the user, got %+v", deliveries)
+	}
+}
+
+var errPublishingFailed = errors.New("publishing failed")
+
+type failingEventPublisher struct{}
+
+func (failingEventPublisher) Publish(ctx context.Context, event interface{}) error {
+	return errPublishingFailed
+}
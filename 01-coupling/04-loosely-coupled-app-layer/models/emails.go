@@ -23,37 +23,47 @@ import (
 
 // Email is an object representing the database table.
 type Email struct {
-	ID      int64  `boil:"id" json:"id" toml:"id" yaml:"id"`
-	Address string `boil:"address" json:"address" toml:"address" yaml:"address"`
-	Primary bool   `boil:"primary" json:"primary" toml:"primary" yaml:"primary"`
-	UserID  int64  `boil:"user_id" json:"user_id" toml:"user_id" yaml:"user_id"`
+	ID             int64  `boil:"id" json:"id" toml:"id" yaml:"id"`
+	Address        string `boil:"address" json:"address" toml:"address" yaml:"address"`
+	Primary        bool   `boil:"primary" json:"primary" toml:"primary" yaml:"primary"`
+	Verified       bool   `boil:"verified" json:"verified" toml:"verified" yaml:"verified"`
+	UserID         int64  `boil:"user_id" json:"user_id" toml:"user_id" yaml:"user_id"`
+	OrganizationID int64  `boil:"organization_id" json:"organization_id" toml:"organization_id" yaml:"organization_id"`
 
 	R *emailR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L emailL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var EmailColumns = struct {
-	ID      string
-	Address string
-	Primary string
-	UserID  string
+	ID             string
+	Address        string
+	Primary        string
+	Verified       string
+	UserID         string
+	OrganizationID string
 }{
-	ID:      "id",
-	Address: "address",
-	Primary: "primary",
-	UserID:  "user_id",
+	ID:             "id",
+	Address:        "address",
+	Primary:        "primary",
+	Verified:       "verified",
+	UserID:         "user_id",
+	OrganizationID: "organization_id",
 }
 
 var EmailTableColumns = struct {
-	ID      string
-	Address string
-	Primary string
-	UserID  string
+	ID             string
+	Address        string
+	Primary        string
+	Verified       string
+	UserID         string
+	OrganizationID string
 }{
-	ID:      "emails.id",
-	Address: "emails.address",
-	Primary: "emails.primary",
-	UserID:  "emails.user_id",
+	ID:             "emails.id",
+	Address:        "emails.address",
+	Primary:        "emails.primary",
+	Verified:       "emails.verified",
+	UserID:         "emails.user_id",
+	OrganizationID: "emails.organization_id",
 }
 
 // Generated where
@@ -114,15 +124,19 @@ func (w whereHelperbool) GT(x bool) qm.QueryMod  { return qmhelper.Where(w.field
 func (w whereHelperbool) GTE(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
 
 var EmailWhere = struct {
-	ID      whereHelperint64
-	Address whereHelperstring
-	Primary whereHelperbool
-	UserID  whereHelperint64
+	ID             whereHelperint64
+	Address        whereHelperstring
+	Primary        whereHelperbool
+	Verified       whereHelperbool
+	UserID         whereHelperint64
+	OrganizationID whereHelperint64
 }{
-	ID:      whereHelperint64{field: "`emails`.`id`"},
-	Address: whereHelperstring{field: "`emails`.`address`"},
-	Primary: whereHelperbool{field: "`emails`.`primary`"},
-	UserID:  whereHelperint64{field: "`emails`.`user_id`"},
+	ID:             whereHelperint64{field: "`emails`.`id`"},
+	Address:        whereHelperstring{field: "`emails`.`address`"},
+	Primary:        whereHelperbool{field: "`emails`.`primary`"},
+	Verified:       whereHelperbool{field: "`emails`.`verified`"},
+	UserID:         whereHelperint64{field: "`emails`.`user_id`"},
+	OrganizationID: whereHelperint64{field: "`emails`.`organization_id`"},
 }
 
 // EmailRels is where relationship names are stored.
@@ -146,8 +160,8 @@ func (*emailR) NewStruct() *emailR {
 type emailL struct{}
 
 var (
-	emailAllColumns            = []string{"id", "address", "primary", "user_id"}
-	emailColumnsWithoutDefault = []string{"address", "primary", "user_id"}
+	emailAllColumns            = []string{"id", "address", "primary", "verified", "user_id", "organization_id"}
+	emailColumnsWithoutDefault = []string{"address", "primary", "verified", "user_id", "organization_id"}
 	emailColumnsWithDefault    = []string{"id"}
 	emailPrimaryKeyColumns     = []string{"id"}
 )
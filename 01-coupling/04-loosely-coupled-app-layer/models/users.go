@@ -24,52 +24,72 @@ import (
 
 // User is an object representing the database table.
 type User struct {
-	ID           int64       `boil:"id" json:"id" toml:"id" yaml:"id"`
-	FirstName    string      `boil:"first_name" json:"first_name" toml:"first_name" yaml:"first_name"`
-	LastName     string      `boil:"last_name" json:"last_name" toml:"last_name" yaml:"last_name"`
-	PasswordHash null.String `boil:"password_hash" json:"password_hash,omitempty" toml:"password_hash" yaml:"password_hash,omitempty"`
-	LastIP       null.String `boil:"last_ip" json:"last_ip,omitempty" toml:"last_ip" yaml:"last_ip,omitempty"`
-	CreatedAt    null.Time   `boil:"created_at" json:"created_at,omitempty" toml:"created_at" yaml:"created_at,omitempty"`
-	UpdatedAt    null.Time   `boil:"updated_at" json:"updated_at,omitempty" toml:"updated_at" yaml:"updated_at,omitempty"`
+	ID             int64       `boil:"id" json:"id" toml:"id" yaml:"id"`
+	FirstName      string      `boil:"first_name" json:"first_name" toml:"first_name" yaml:"first_name"`
+	LastName       string      `boil:"last_name" json:"last_name" toml:"last_name" yaml:"last_name"`
+	PasswordHash   null.String `boil:"password_hash" json:"password_hash,omitempty" toml:"password_hash" yaml:"password_hash,omitempty"`
+	LastIP         null.String `boil:"last_ip" json:"last_ip,omitempty" toml:"last_ip" yaml:"last_ip,omitempty"`
+	CreatedAt      null.Time   `boil:"created_at" json:"created_at,omitempty" toml:"created_at" yaml:"created_at,omitempty"`
+	UpdatedAt      null.Time   `boil:"updated_at" json:"updated_at,omitempty" toml:"updated_at" yaml:"updated_at,omitempty"`
+	Version        int64       `boil:"version" json:"version" toml:"version" yaml:"version"`
+	ErasedAt       null.Time   `boil:"erased_at" json:"erased_at,omitempty" toml:"erased_at" yaml:"erased_at,omitempty"`
+	DeletedAt      null.Time   `boil:"deleted_at" json:"deleted_at,omitempty" toml:"deleted_at" yaml:"deleted_at,omitempty"`
+	OrganizationID int64       `boil:"organization_id" json:"organization_id" toml:"organization_id" yaml:"organization_id"`
 
 	R *userR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L userL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var UserColumns = struct {
-	ID           string
-	FirstName    string
-	LastName     string
-	PasswordHash string
-	LastIP       string
-	CreatedAt    string
-	UpdatedAt    string
+	ID             string
+	FirstName      string
+	LastName       string
+	PasswordHash   string
+	LastIP         string
+	CreatedAt      string
+	UpdatedAt      string
+	Version        string
+	ErasedAt       string
+	DeletedAt      string
+	OrganizationID string
 }{
-	ID:           "id",
-	FirstName:    "first_name",
-	LastName:     "last_name",
-	PasswordHash: "password_hash",
-	LastIP:       "last_ip",
-	CreatedAt:    "created_at",
-	UpdatedAt:    "updated_at",
+	ID:             "id",
+	FirstName:      "first_name",
+	LastName:       "last_name",
+	PasswordHash:   "password_hash",
+	LastIP:         "last_ip",
+	CreatedAt:      "created_at",
+	UpdatedAt:      "updated_at",
+	Version:        "version",
+	ErasedAt:       "erased_at",
+	DeletedAt:      "deleted_at",
+	OrganizationID: "organization_id",
 }
 
 var UserTableColumns = struct {
-	ID           string
-	FirstName    string
-	LastName     string
-	PasswordHash string
-	LastIP       string
-	CreatedAt    string
-	UpdatedAt    string
+	ID             string
+	FirstName      string
+	LastName       string
+	PasswordHash   string
+	LastIP         string
+	CreatedAt      string
+	UpdatedAt      string
+	Version        string
+	ErasedAt       string
+	DeletedAt      string
+	OrganizationID string
 }{
-	ID:           "users.id",
-	FirstName:    "users.first_name",
-	LastName:     "users.last_name",
-	PasswordHash: "users.password_hash",
-	LastIP:       "users.last_ip",
-	CreatedAt:    "users.created_at",
-	UpdatedAt:    "users.updated_at",
+	ID:             "users.id",
+	FirstName:      "users.first_name",
+	LastName:       "users.last_name",
+	PasswordHash:   "users.password_hash",
+	LastIP:         "users.last_ip",
+	CreatedAt:      "users.created_at",
+	UpdatedAt:      "users.updated_at",
+	Version:        "users.version",
+	ErasedAt:       "users.erased_at",
+	DeletedAt:      "users.deleted_at",
+	OrganizationID: "users.organization_id",
 }
 
 // Generated where
@@ -121,21 +141,29 @@ func (w whereHelpernull_Time) GTE(x null.Time) qm.QueryMod {
 }
 
 var UserWhere = struct {
-	ID           whereHelperint64
-	FirstName    whereHelperstring
-	LastName     whereHelperstring
-	PasswordHash whereHelpernull_String
-	LastIP       whereHelpernull_String
-	CreatedAt    whereHelpernull_Time
-	UpdatedAt    whereHelpernull_Time
+	ID             whereHelperint64
+	FirstName      whereHelperstring
+	LastName       whereHelperstring
+	PasswordHash   whereHelpernull_String
+	LastIP         whereHelpernull_String
+	CreatedAt      whereHelpernull_Time
+	UpdatedAt      whereHelpernull_Time
+	Version        whereHelperint64
+	ErasedAt       whereHelpernull_Time
+	DeletedAt      whereHelpernull_Time
+	OrganizationID whereHelperint64
 }{
-	ID:           whereHelperint64{field: "`users`.`id`"},
-	FirstName:    whereHelperstring{field: "`users`.`first_name`"},
-	LastName:     whereHelperstring{field: "`users`.`last_name`"},
-	PasswordHash: whereHelpernull_String{field: "`users`.`password_hash`"},
-	LastIP:       whereHelpernull_String{field: "`users`.`last_ip`"},
-	CreatedAt:    whereHelpernull_Time{field: "`users`.`created_at`"},
-	UpdatedAt:    whereHelpernull_Time{field: "`users`.`updated_at`"},
+	ID:             whereHelperint64{field: "`users`.`id`"},
+	FirstName:      whereHelperstring{field: "`users`.`first_name`"},
+	LastName:       whereHelperstring{field: "`users`.`last_name`"},
+	PasswordHash:   whereHelpernull_String{field: "`users`.`password_hash`"},
+	LastIP:         whereHelpernull_String{field: "`users`.`last_ip`"},
+	CreatedAt:      whereHelpernull_Time{field: "`users`.`created_at`"},
+	UpdatedAt:      whereHelpernull_Time{field: "`users`.`updated_at`"},
+	Version:        whereHelperint64{field: "`users`.`version`"},
+	ErasedAt:       whereHelpernull_Time{field: "`users`.`erased_at`"},
+	DeletedAt:      whereHelpernull_Time{field: "`users`.`deleted_at`"},
+	OrganizationID: whereHelperint64{field: "`users`.`organization_id`"},
 }
 
 // UserRels is where relationship names are stored.
@@ -159,9 +187,9 @@ func (*userR) NewStruct() *userR {
 type userL struct{}
 
 var (
-	userAllColumns            = []string{"id", "first_name", "last_name", "password_hash", "last_ip", "created_at", "updated_at"}
-	userColumnsWithoutDefault = []string{"first_name", "last_name", "password_hash", "last_ip", "created_at", "updated_at"}
-	userColumnsWithDefault    = []string{"id"}
+	userAllColumns            = []string{"id", "first_name", "last_name", "password_hash", "last_ip", "created_at", "updated_at", "version", "erased_at", "deleted_at", "organization_id"}
+	userColumnsWithoutDefault = []string{"first_name", "last_name", "password_hash", "last_ip", "created_at", "updated_at", "erased_at", "deleted_at", "organization_id"}
+	userColumnsWithDefault    = []string{"id", "version"}
 	userPrimaryKeyColumns     = []string{"id"}
 )
 
@@ -0,0 +1,5 @@
+-- Only the databases are created here. Each service creates its tables with migrations on start.
+CREATE DATABASE IF NOT EXISTS tightly_coupled;
+CREATE DATABASE IF NOT EXISTS loosely_coupled;
+CREATE DATABASE IF NOT EXISTS loosely_coupled_generated;
+CREATE DATABASE IF NOT EXISTS loosely_coupled_app_layer;
@@ -5,4 +5,15 @@ for dir in /src/*; do
 
     oapi-codegen -generate types -o "$dir/internal/http_types.go" -package internal "$dir/openapi.yml"
     oapi-codegen -generate chi-server -o "$dir/internal/http_server.go" -package internal "$dir/openapi.yml"
+
+    # Only examples validating requests against the spec embed it.
+    if [ -f "$dir/internal/http_spec.go" ]; then
+        oapi-codegen -generate spec -o "$dir/internal/http_spec.go" -package internal "$dir/openapi.yml"
+    fi
 done
+
+# The end-to-end tests client uses the most complete spec, as other examples expose a subset of the same API.
+echo "Running in /tests"
+
+oapi-codegen -generate types -o /tests/client/http_types.go -package client /src/04-loosely-coupled-app-layer/openapi.yml
+oapi-codegen -generate client -o /tests/client/http_client.go -package client /src/04-loosely-coupled-app-layer/openapi.yml
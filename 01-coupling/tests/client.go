@@ -1,144 +1,238 @@
 package tests
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/tests/client"
 )
 
+// HTTPClient wraps the client generated from openapi.yml with assertions, so tests stay short.
 type HTTPClient struct {
 	t       *testing.T
-	client  http.Client
+	client  *client.ClientWithResponses
+	http    http.Client
 	baseURL *url.URL
+	// organizationID is sent in the X-Organization-ID header. Only the application layer example reads it.
+	organizationID int
+	// softDelete tells if the service keeps deleted users until they're purged.
+	softDelete bool
 }
 
+const (
+	organizationIDHeader  = "X-Organization-ID"
+	defaultOrganizationID = 1
+)
+
 func NewHTTPClient(t *testing.T, port int) HTTPClient {
-	client := http.Client{}
+	return NewOrganizationHTTPClient(t, port, defaultOrganizationID)
+}
 
+// NewOrganizationHTTPClient returns a client working on the organization's users.
+func NewOrganizationHTTPClient(t *testing.T, port int, organizationID int) HTTPClient {
 	baseUrl, err := url.Parse(fmt.Sprintf("http://localhost:%v", port))
 	require.NoError(t, err)
 
+	apiClient, err := client.NewClientWithResponses(
+		baseUrl.String(),
+		client.WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set(organizationIDHeader, strconv.Itoa(organizationID))
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
 	return HTTPClient{
-		t:       t,
-		client:  client,
-		baseURL: baseUrl,
+		t:              t,
+		client:         apiClient,
+		http:           http.Client{},
+		baseURL:        baseUrl,
+		organizationID: organizationID,
 	}
 }
 
-type User struct {
-	ID          int     `json:"id"`
-	DisplayName string  `json:"display_name"`
-	Emails      []Email `json:"emails"`
-}
-
-type PostUserRequest struct {
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
-}
-
-type PatchUserRequest struct {
-	FirstName *string `json:"first_name,omitempty"`
-	LastName  *string `json:"last_name,omitempty"`
-}
+// User and Problem are aliases, so tests don't depend on the generated package's names.
+type User = client.UserResponse
 
-type Email struct {
-	Address string `json:"address"`
-	Primary bool   `json:"primary"`
-}
+type Problem = client.Problem
 
 func (c HTTPClient) GetAllUsers() []User {
-	resp, err := c.client.Get(c.relativeURL("/users"))
+	resp, err := c.client.GetUsersWithResponse(context.Background(), nil)
 	require.NoError(c.t, err)
 
-	require.Equal(c.t, http.StatusOK, resp.StatusCode)
-
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	var users []User
-	err = json.NewDecoder(resp.Body).Decode(&users)
-	require.NoError(c.t, err)
+	require.Equal(c.t, http.StatusOK, resp.StatusCode())
+	require.NotNil(c.t, resp.JSON200)
 
-	return users
+	return *resp.JSON200
 }
 
 func (c HTTPClient) GetUser(id int) (User, bool) {
-	resp, err := c.client.Get(c.relativeURL(fmt.Sprintf("/users/%v", id)))
+	resp, err := c.client.GetUserWithResponse(context.Background(), userID(id))
 	require.NoError(c.t, err)
 
-	if resp.StatusCode == http.StatusNotFound {
+	if resp.StatusCode() == http.StatusNotFound {
 		return User{}, false
 	}
 
-	require.Equal(c.t, http.StatusOK, resp.StatusCode)
+	require.Equal(c.t, http.StatusOK, resp.StatusCode())
+	require.NotNil(c.t, resp.JSON200)
 
-	defer func() {
-		_ = resp.Body.Close()
-	}()
+	return *resp.JSON200, true
+}
 
-	var user User
-	err = json.NewDecoder(resp.Body).Decode(&user)
+// GetUserETag returns the ETag of the user, to be sent in If-Match when updating it.
+func (c HTTPClient) GetUserETag(id int) string {
+	resp, err := c.client.GetUserWithResponse(context.Background(), userID(id))
 	require.NoError(c.t, err)
 
-	return user, true
+	require.Equal(c.t, http.StatusOK, resp.StatusCode())
+
+	etag := resp.HTTPResponse.Header.Get("ETag")
+	require.NotEmpty(c.t, etag)
+
+	return etag
 }
 
 func (c HTTPClient) PostUser(firstName string, lastName string, email string, expectedStatusCode int) {
-	postUserRequest := PostUserRequest{
+	resp, err := c.client.PostUserWithResponse(context.Background(), client.PostUserJSONRequestBody{
 		FirstName: firstName,
 		LastName:  lastName,
 		Email:     email,
-	}
+	})
+	require.NoError(c.t, err)
 
-	body := &bytes.Buffer{}
+	require.Equal(c.t, expectedStatusCode, resp.StatusCode())
+}
 
-	err := json.NewEncoder(body).Encode(postUserRequest)
+func (c HTTPClient) PatchUser(id int, etag string, firstName *string, lastName *string, expectedStatusCode int) {
+	ifMatch := client.IfMatch(etag)
+
+	resp, err := c.client.PatchUserWithResponse(
+		context.Background(),
+		userID(id),
+		&client.PatchUserParams{IfMatch: &ifMatch},
+		client.PatchUserJSONRequestBody{
+			FirstName: firstName,
+			LastName:  lastName,
+		},
+	)
 	require.NoError(c.t, err)
 
-	resp, err := c.client.Post(c.relativeURL(fmt.Sprintf("/users")), "application/json", body)
+	require.Equal(c.t, expectedStatusCode, resp.StatusCode())
+}
+
+func (c HTTPClient) AddEmail(id int, address string, expectedStatusCode int) {
+	resp, err := c.client.AddUserEmailWithResponse(context.Background(), userID(id), client.AddUserEmailJSONRequestBody{
+		Address: address,
+	})
 	require.NoError(c.t, err)
 
-	require.Equal(c.t, expectedStatusCode, resp.StatusCode)
+	require.Equal(c.t, expectedStatusCode, resp.StatusCode())
 }
 
-func (c HTTPClient) PatchUser(id int, firstName *string, lastName *string, expectedStatusCode int) {
-	patchuserRequest := PatchUserRequest{
-		FirstName: firstName,
-		LastName:  lastName,
-	}
-	body := &bytes.Buffer{}
+func (c HTTPClient) ChangePrimaryEmail(id int, address string, expectedStatusCode int) {
+	resp, err := c.client.ChangePrimaryEmailWithResponse(context.Background(), userID(id), client.ChangePrimaryEmailJSONRequestBody{
+		Address: address,
+	})
+	require.NoError(c.t, err)
+
+	require.Equal(c.t, expectedStatusCode, resp.StatusCode())
+}
 
-	err := json.NewEncoder(body).Encode(patchuserRequest)
+func (c HTTPClient) VerifyEmail(token string, expectedStatusCode int) {
+	resp, err := c.client.VerifyEmailWithResponse(context.Background(), client.VerifyEmailJSONRequestBody{
+		Token: token,
+	})
 	require.NoError(c.t, err)
 
-	req, err := http.NewRequest("PATCH", c.relativeURL(fmt.Sprintf("/users/%v", id)), body)
+	require.Equal(c.t, expectedStatusCode, resp.StatusCode())
+}
+
+func (c HTTPClient) DeleteUser(id int) {
+	resp, err := c.client.DeleteUserWithResponse(context.Background(), userID(id), nil)
 	require.NoError(c.t, err)
 
-	req.Header.Set("Content-Type", "application/json")
+	require.Equal(c.t, http.StatusNoContent, resp.StatusCode())
+}
 
-	resp, err := c.client.Do(req)
+// EraseUser anonymizes the user instead of deleting it. It's available only in the application layer example.
+func (c HTTPClient) EraseUser(id int, expectedStatusCode int) {
+	mode := client.DeleteUserParamsMode("erase")
+
+	resp, err := c.client.DeleteUserWithResponse(context.Background(), userID(id), &client.DeleteUserParams{Mode: &mode})
 	require.NoError(c.t, err)
 
-	require.Equal(c.t, expectedStatusCode, resp.StatusCode)
+	require.Equal(c.t, expectedStatusCode, resp.StatusCode())
 }
 
-func (c HTTPClient) DeleteUser(id int) {
-	req, err := http.NewRequest("DELETE", c.relativeURL(fmt.Sprintf("/users/%v", id)), nil)
+// RestoreUser undoes DeleteUser. It's available only in the application layer example.
+func (c HTTPClient) RestoreUser(id int, expectedStatusCode int) {
+	resp, err := c.client.RestoreUserWithResponse(context.Background(), userID(id))
 	require.NoError(c.t, err)
 
-	resp, err := c.client.Do(req)
+	require.Equal(c.t, expectedStatusCode, resp.StatusCode())
+}
+
+// GetUserDataExport returns all personal data stored about the user. It's available only in the application layer example.
+func (c HTTPClient) GetUserDataExport(id int) (client.UserDataExport, bool) {
+	resp, err := c.client.GetUserDataExportWithResponse(context.Background(), userID(id))
 	require.NoError(c.t, err)
 
-	require.Equal(c.t, http.StatusNoContent, resp.StatusCode)
+	if resp.StatusCode() == http.StatusNotFound {
+		return client.UserDataExport{}, false
+	}
+
+	require.Equal(c.t, http.StatusOK, resp.StatusCode())
+	require.NotNil(c.t, resp.JSON200)
+
+	return *resp.JSON200, true
+}
+
+// RequestProblem sends the raw body with the headers and decodes the problem details from the response.
+// It's meant for requests the generated client can't send, like invalid JSON or a missing If-Match.
+func (c HTTPClient) RequestProblem(method string, path string, header http.Header, body string, expectedStatusCode int) Problem {
+	req, err := http.NewRequest(method, c.relativeURL(path), strings.NewReader(body))
+	require.NoError(c.t, err)
+
+	req.Header.Set(organizationIDHeader, strconv.Itoa(c.organizationID))
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	require.NoError(c.t, err)
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	require.Equal(c.t, expectedStatusCode, resp.StatusCode)
+	require.Equal(c.t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+	var problem Problem
+	err = json.NewDecoder(resp.Body).Decode(&problem)
+	require.NoError(c.t, err)
+
+	require.Equal(c.t, expectedStatusCode, problem.Status)
+
+	return problem
 }
 
 func (c HTTPClient) relativeURL(path string) string {
 	return c.baseURL.ResolveReference(&url.URL{Path: path}).String()
 }
+
+func userID(id int) client.UserID {
+	return client.UserID(fmt.Sprint(id))
+}
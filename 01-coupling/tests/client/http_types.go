@@ -0,0 +1,279 @@
+// Package client provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/deepmap/oapi-codegen version v1.8.2 DO NOT EDIT.
+package client
+
+import (
+	"time"
+)
+
+// Defines values for WebhookDeliveryResponseStatus.
+const (
+	WebhookDeliveryResponseStatusDelivered WebhookDeliveryResponseStatus = "delivered"
+
+	WebhookDeliveryResponseStatusFailed WebhookDeliveryResponseStatus = "failed"
+
+	WebhookDeliveryResponseStatusPending WebhookDeliveryResponseStatus = "pending"
+)
+
+// Defines values for WebhookEventType.
+const (
+	WebhookEventTypeUserCreated WebhookEventType = "user.created"
+
+	WebhookEventTypeUserDeleted WebhookEventType = "user.deleted"
+
+	WebhookEventTypeUserRenamed WebhookEventType = "user.renamed"
+)
+
+// AddEmailRequest defines model for AddEmailRequest.
+type AddEmailRequest struct {
+	// E-mail
+	Address string `json:"address"`
+}
+
+// ChangePrimaryEmailRequest defines model for ChangePrimaryEmailRequest.
+type ChangePrimaryEmailRequest struct {
+	// Verified e-mail of the user
+	Address string `json:"address"`
+}
+
+// EmailResponse defines model for EmailResponse.
+type EmailResponse struct {
+	Address  string `json:"address"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// FieldError defines model for FieldError.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportFailure defines model for ImportFailure.
+type ImportFailure struct {
+	// Problem details (RFC 7807)
+	Problem Problem `json:"problem"`
+
+	// Number of the row (CSV record or NDJSON line), starting from 1. The CSV header doesn't count.
+	Row int `json:"row"`
+}
+
+// ImportReport defines model for ImportReport.
+type ImportReport struct {
+	// Problem details (RFC 7807)
+	Error  *Problem        `json:"error,omitempty"`
+	Failed []ImportFailure `json:"failed"`
+
+	// Number of users created
+	Imported int `json:"imported"`
+}
+
+// PatchUserRequest defines model for PatchUserRequest.
+type PatchUserRequest struct {
+	// First name
+	FirstName *string `json:"first_name,omitempty"`
+
+	// Last name
+	LastName *string `json:"last_name,omitempty"`
+}
+
+// PostUserRequest defines model for PostUserRequest.
+type PostUserRequest struct {
+	// E-mail
+	Email string `json:"email"`
+
+	// First name
+	FirstName string `json:"first_name"`
+
+	// Last name
+	LastName string `json:"last_name"`
+
+	// Whether the user agreed to receive the product news newsletter
+	ProductNewsConsent *bool `json:"product_news_consent,omitempty"`
+}
+
+// PostWebhookRequest defines model for PostWebhookRequest.
+type PostWebhookRequest struct {
+	EventTypes []WebhookEventType `json:"event_types"`
+
+	// Secret signing the requests. It's never returned.
+	Secret string `json:"secret"`
+
+	// Absolute http or https URL receiving the events
+	Url string `json:"url"`
+}
+
+// Problem details (RFC 7807)
+type Problem struct {
+	// Explanation specific to this occurrence of the problem
+	Detail *string `json:"detail,omitempty"`
+
+	// Invalid fields of the request
+	Errors *[]FieldError `json:"errors,omitempty"`
+
+	// HTTP status code
+	Status int `json:"status"`
+
+	// Short summary of the problem type
+	Title string `json:"title"`
+
+	// URI reference identifying the problem type
+	Type string `json:"type"`
+}
+
+// UserDataExport defines model for UserDataExport.
+type UserDataExport struct {
+	ExportedAt time.Time `json:"exported_at"`
+
+	// IP address of the last login, if recorded
+	LastIp *string `json:"last_ip,omitempty"`
+
+	// Whether a password hash is stored
+	PasswordSet bool         `json:"password_set"`
+	User        UserResponse `json:"user"`
+}
+
+// UserResponse defines model for UserResponse.
+type UserResponse struct {
+	CreatedAt   time.Time       `json:"created_at"`
+	DisplayName string          `json:"display_name"`
+	Emails      []EmailResponse `json:"emails"`
+	FirstName   string          `json:"first_name"`
+	Id          int             `json:"id"`
+	LastName    string          `json:"last_name"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// UsersResponse defines model for UsersResponse.
+type UsersResponse []UserResponse
+
+// VerifyEmailRequest defines model for VerifyEmailRequest.
+type VerifyEmailRequest struct {
+	// Verification token sent to the e-mail address
+	Token string `json:"token"`
+}
+
+// WebhookDeliveriesResponse defines model for WebhookDeliveriesResponse.
+type WebhookDeliveriesResponse []WebhookDeliveryResponse
+
+// WebhookDeliveryResponse defines model for WebhookDeliveryResponse.
+type WebhookDeliveryResponse struct {
+	Attempts    int              `json:"attempts"`
+	CreatedAt   time.Time        `json:"created_at"`
+	DeliveredAt *time.Time       `json:"delivered_at,omitempty"`
+	EventType   WebhookEventType `json:"event_type"`
+
+	// Sent in the Webhook-Delivery header, so receivers can skip deliveries they got before
+	Id int `json:"id"`
+
+	// Why the last attempt failed
+	LastError *string `json:"last_error,omitempty"`
+
+	// Status code of the last response, if there was any
+	LastStatusCode *int `json:"last_status_code,omitempty"`
+
+	// When a pending delivery is sent next
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+
+	// Body of the request
+	Payload map[string]interface{} `json:"payload"`
+
+	// Pending deliveries are being retried, and failed ones ran out of attempts.
+	Status WebhookDeliveryResponseStatus `json:"status"`
+}
+
+// Pending deliveries are being retried, and failed ones ran out of attempts.
+type WebhookDeliveryResponseStatus string
+
+// WebhookEventType defines model for WebhookEventType.
+type WebhookEventType string
+
+// WebhookResponse defines model for WebhookResponse.
+type WebhookResponse struct {
+	CreatedAt  time.Time          `json:"created_at"`
+	EventTypes []WebhookEventType `json:"event_types"`
+	Id         int                `json:"id"`
+	Url        string             `json:"url"`
+}
+
+// WebhooksResponse defines model for WebhooksResponse.
+type WebhooksResponse []WebhookResponse
+
+// CreatedAfter defines model for createdAfter.
+type CreatedAfter string
+
+// IfMatch defines model for ifMatch.
+type IfMatch string
+
+// UserID defines model for userID.
+type UserID string
+
+// WebhookID defines model for webhookID.
+type WebhookID string
+
+// VerifyEmailJSONBody defines parameters for VerifyEmail.
+type VerifyEmailJSONBody VerifyEmailRequest
+
+// GetUsersParams defines parameters for GetUsers.
+type GetUsersParams struct {
+	// Return only users created after this time (RFC 3339)
+	CreatedAfter *CreatedAfter `json:"created_after,omitempty"`
+}
+
+// PostUserJSONBody defines parameters for PostUser.
+type PostUserJSONBody PostUserRequest
+
+// DeleteUserParams defines parameters for DeleteUser.
+type DeleteUserParams struct {
+	// Whether to delete or erase the user. Deleted users can be erased too. Erasing a missing user responds with 404.
+	Mode *DeleteUserParamsMode `json:"mode,omitempty"`
+}
+
+// DeleteUserParamsMode defines parameters for DeleteUser.
+type DeleteUserParamsMode string
+
+// PatchUserJSONBody defines parameters for PatchUser.
+type PatchUserJSONBody PatchUserRequest
+
+// PatchUserParams defines parameters for PatchUser.
+type PatchUserParams struct {
+	// ETag of the user returned by GET. Requests without it are rejected with 428 Precondition Required, and requests with an outdated one with 412 Precondition Failed.
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// AddUserEmailJSONBody defines parameters for AddUserEmail.
+type AddUserEmailJSONBody AddEmailRequest
+
+// ChangePrimaryEmailJSONBody defines parameters for ChangePrimaryEmail.
+type ChangePrimaryEmailJSONBody ChangePrimaryEmailRequest
+
+// ExportUsersParams defines parameters for ExportUsers.
+type ExportUsersParams struct {
+	// NDJSON has one UserResponse per line. CSV has a header row and the primary e-mail only, and can be imported back.
+	Format *ExportUsersParamsFormat `json:"format,omitempty"`
+}
+
+// ExportUsersParamsFormat defines parameters for ExportUsers.
+type ExportUsersParamsFormat string
+
+// PostWebhookJSONBody defines parameters for PostWebhook.
+type PostWebhookJSONBody PostWebhookRequest
+
+// VerifyEmailJSONRequestBody defines body for VerifyEmail for application/json ContentType.
+type VerifyEmailJSONRequestBody VerifyEmailJSONBody
+
+// PostUserJSONRequestBody defines body for PostUser for application/json ContentType.
+type PostUserJSONRequestBody PostUserJSONBody
+
+// PatchUserJSONRequestBody defines body for PatchUser for application/json ContentType.
+type PatchUserJSONRequestBody PatchUserJSONBody
+
+// AddUserEmailJSONRequestBody defines body for AddUserEmail for application/json ContentType.
+type AddUserEmailJSONRequestBody AddUserEmailJSONBody
+
+// ChangePrimaryEmailJSONRequestBody defines body for ChangePrimaryEmail for application/json ContentType.
+type ChangePrimaryEmailJSONRequestBody ChangePrimaryEmailJSONBody
+
+// PostWebhookJSONRequestBody defines body for PostWebhook for application/json ContentType.
+type PostWebhookJSONRequestBody PostWebhookJSONBody
@@ -3,6 +3,8 @@ package tests
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/brianvoe/gofakeit/v6"
@@ -14,6 +16,8 @@ func TestUser(t *testing.T) {
 	services := []struct {
 		Name string
 		Port int
+		// SoftDelete is set for services that keep deleted users, and their e-mails, until they're purged.
+		SoftDelete bool
 	}{
 		{
 			Name: "01_tightly_coupled",
@@ -28,8 +32,9 @@ func TestUser(t *testing.T) {
 			Port: 8082,
 		},
 		{
-			Name: "04_loosely_coupled_app_layer",
-			Port: 8083,
+			Name:       "04_loosely_coupled_app_layer",
+			Port:       8083,
+			SoftDelete: true,
 		},
 	}
 
@@ -49,6 +54,14 @@ func TestUser(t *testing.T) {
 			Name:     "update_user",
 			TestFunc: testUpdateUser,
 		},
+		{
+			Name:     "problem_details",
+			TestFunc: testProblemDetails,
+		},
+		{
+			Name:     "concurrent_update",
+			TestFunc: testConcurrentUpdate,
+		},
 	}
 
 	for i := range services {
@@ -60,6 +73,7 @@ func TestUser(t *testing.T) {
 			t.Run(name, func(t *testing.T) {
 				t.Parallel()
 				client := NewHTTPClient(t, s.Port)
+				client.softDelete = s.SoftDelete
 				tc.TestFunc(t, client)
 			})
 		}
@@ -82,20 +96,26 @@ func testUserLifecycle(t *testing.T, client HTTPClient) {
 	require.Equal(t, firstName+" "+lastName, user.DisplayName)
 	require.Len(t, user.Emails, 1)
 
-	userByID, ok := client.GetUser(user.ID)
+	userByID, ok := client.GetUser(user.Id)
 	require.True(t, ok, "Expected to find the user by ID")
 
 	require.Equal(t, firstName+" "+lastName, userByID.DisplayName)
 	require.Len(t, userByID.Emails, 1)
 
-	client.DeleteUser(user.ID)
+	client.DeleteUser(user.Id)
 
 	_, ok = findUserByEmail(client.GetAllUsers(), email)
 	require.False(t, ok, "Didn't expect to find the user by email")
 
-	_, ok = client.GetUser(user.ID)
+	_, ok = client.GetUser(user.Id)
 	require.False(t, ok, "Didn't expect to find the user by ID")
 
+	if client.softDelete {
+		// The e-mail stays taken until the deleted user is purged.
+		client.PostUser(firstName, lastName, email, http.StatusBadRequest)
+		return
+	}
+
 	client.PostUser(firstName, lastName, email, http.StatusCreated)
 }
 
@@ -267,8 +287,9 @@ func testUpdateUser(t *testing.T, client HTTPClient) {
 		tc := testCases[i]
 		t.Run(tc.Name, func(t *testing.T) {
 			// No parallelism allowed here, as the order matters
-			client.PatchUser(user.ID, tc.NewFirstName, tc.NewLastName, tc.ExpectedStatusCode)
-			updatedUser, ok := client.GetUser(user.ID)
+			etag := client.GetUserETag(user.Id)
+			client.PatchUser(user.Id, etag, tc.NewFirstName, tc.NewLastName, tc.ExpectedStatusCode)
+			updatedUser, ok := client.GetUser(user.Id)
 			require.True(t, ok, "Expected to find the user by ID")
 
 			assert.Equal(t, tc.ExpectedDisplayName, updatedUser.DisplayName)
@@ -276,6 +297,319 @@ func testUpdateUser(t *testing.T, client HTTPClient) {
 	}
 }
 
+func testProblemDetails(t *testing.T, client HTTPClient) {
+	email := gofakeit.Email()
+	client.PostUser(gofakeit.FirstName(), gofakeit.LastName(), email, http.StatusCreated)
+
+	user, ok := findUserByEmail(client.GetAllUsers(), email)
+	require.True(t, ok, "Expected to find the user by email")
+
+	testCases := []struct {
+		Name               string
+		Method             string
+		Path               string
+		Header             http.Header
+		Body               string
+		ExpectedStatusCode int
+		ExpectedType       string
+		ExpectedFields     []string
+	}{
+		{
+			Name:               "post_invalid_json",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			Body:               `{"first_name": `,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/invalid-request-body",
+		},
+		{
+			Name:               "post_missing_name",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			Body:               fmt.Sprintf(`{"email": %q}`, gofakeit.Email()),
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/validation-failed",
+			ExpectedFields:     []string{"first_name", "last_name"},
+		},
+		{
+			Name:               "post_invalid_email",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			Body:               `{"first_name": "John", "email": "invalid"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/validation-failed",
+		},
+		{
+			Name:               "post_existing_email",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			Body:               fmt.Sprintf(`{"first_name": "John", "email": %q}`, email),
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/email-already-exists",
+		},
+		{
+			Name:               "post_existing_email_other_case",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			Body:               fmt.Sprintf(`{"first_name": "John", "email": %q}`, strings.ToUpper(email)),
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/email-already-exists",
+		},
+		{
+			Name:               "post_email_with_display_name",
+			Method:             http.MethodPost,
+			Path:               "/users",
+			Body:               fmt.Sprintf(`{"first_name": "John", "email": "John <%s>"}`, gofakeit.Email()),
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/validation-failed",
+			ExpectedFields:     []string{"email"},
+		},
+		{
+			Name:               "get_invalid_user_id",
+			Method:             http.MethodGet,
+			Path:               "/users/invalid",
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/invalid-user-id",
+		},
+		{
+			Name:               "get_not_existing_user",
+			Method:             http.MethodGet,
+			Path:               "/users/2147483647",
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedType:       "/problems/user-not-found",
+		},
+		{
+			Name:               "patch_invalid_json",
+			Method:             http.MethodPatch,
+			Path:               fmt.Sprintf("/users/%v", user.Id),
+			Body:               `{"first_name": `,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/invalid-request-body",
+		},
+		{
+			Name:               "patch_empty_name",
+			Method:             http.MethodPatch,
+			Path:               fmt.Sprintf("/users/%v", user.Id),
+			Header:             http.Header{"If-Match": []string{"*"}},
+			Body:               `{"first_name": "", "last_name": ""}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedType:       "/problems/validation-failed",
+			ExpectedFields:     []string{"first_name", "last_name"},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			problem := client.RequestProblem(tc.Method, tc.Path, tc.Header, tc.Body, tc.ExpectedStatusCode)
+
+			assert.Equal(t, tc.ExpectedType, problem.Type)
+			assert.NotEmpty(t, problem.Title)
+			assert.NotEmpty(t, problem.Detail)
+
+			if tc.ExpectedType == "/problems/validation-failed" {
+				assert.NotEmpty(t, problem.Errors)
+			}
+
+			var fields []string
+			if problem.Errors != nil {
+				for _, e := range *problem.Errors {
+					fields = append(fields, e.Field)
+				}
+			}
+
+			for _, f := range tc.ExpectedFields {
+				assert.Contains(t, fields, f)
+			}
+		})
+	}
+}
+
+func testConcurrentUpdate(t *testing.T, client HTTPClient) {
+	firstName := gofakeit.FirstName()
+	lastName := gofakeit.LastName()
+	email := gofakeit.Email()
+
+	client.PostUser(firstName, lastName, email, http.StatusCreated)
+
+	user, ok := findUserByEmail(client.GetAllUsers(), email)
+	require.True(t, ok, "Expected to find the user by email")
+
+	path := fmt.Sprintf("/users/%v", user.Id)
+	body := fmt.Sprintf(`{"first_name": %q}`, gofakeit.FirstName())
+
+	problem := client.RequestProblem(http.MethodPatch, path, nil, body, http.StatusPreconditionRequired)
+	assert.Equal(t, "/problems/precondition-required", problem.Type)
+
+	etag := client.GetUserETag(user.Id)
+	assert.Equal(t, etag, client.GetUserETag(user.Id), "Expected the ETag to be stable between reads")
+
+	newFirstName := gofakeit.FirstName()
+	client.PatchUser(user.Id, etag, &newFirstName, nil, http.StatusNoContent)
+
+	newETag := client.GetUserETag(user.Id)
+	assert.NotEqual(t, etag, newETag)
+
+	// The second client still has the old ETag, so it can't overwrite the change.
+	header := http.Header{"If-Match": []string{etag}}
+	problem = client.RequestProblem(http.MethodPatch, path, header, body, http.StatusPreconditionFailed)
+	assert.Equal(t, "/problems/precondition-failed", problem.Type)
+
+	updatedUser, ok := client.GetUser(user.Id)
+	require.True(t, ok, "Expected to find the user by ID")
+	assert.Equal(t, newFirstName+" "+lastName, updatedUser.DisplayName)
+
+	// The current ETag is accepted.
+	client.PatchUser(user.Id, newETag, nil, &lastName, http.StatusNoContent)
+}
+
+// mailsFile is where 04_loosely_coupled_app_layer writes e-mails (see docker-compose.yml).
+const mailsFile = "../04-loosely-coupled-app-layer/mails.log"
+
+// TestEmailVerification covers the e-mail verification flow, available only in the application layer example.
+func TestEmailVerification(t *testing.T) {
+	client := NewHTTPClient(t, 8083)
+
+	email := gofakeit.Email()
+	client.PostUser(gofakeit.FirstName(), gofakeit.LastName(), email, http.StatusCreated)
+
+	user, ok := findUserByEmail(client.GetAllUsers(), email)
+	require.True(t, ok, "Expected to find the user by email")
+	require.Len(t, user.Emails, 1)
+	assert.False(t, user.Emails[0].Verified, "Expected a new e-mail to be unverified")
+
+	secondEmail := gofakeit.Email()
+	client.AddEmail(user.Id, secondEmail, http.StatusCreated)
+	client.AddEmail(user.Id, secondEmail, http.StatusBadRequest)
+
+	problem := client.RequestProblem(http.MethodPut, fmt.Sprintf("/users/%v/emails/primary", user.Id), nil, fmt.Sprintf(`{"address": %q}`, secondEmail), http.StatusConflict)
+	assert.Equal(t, "/problems/email-not-verified", problem.Type)
+
+	problem = client.RequestProblem(http.MethodPost, "/emails/verify", nil, `{"token": "invalid"}`, http.StatusBadRequest)
+	assert.Equal(t, "/problems/invalid-verification-token", problem.Type)
+
+	client.VerifyEmail(verificationToken(t, secondEmail), http.StatusNoContent)
+	client.ChangePrimaryEmail(user.Id, secondEmail, http.StatusNoContent)
+
+	user, ok = client.GetUser(user.Id)
+	require.True(t, ok, "Expected to find the user by ID")
+
+	for _, e := range user.Emails {
+		switch e.Address {
+		case email:
+			assert.False(t, e.Primary)
+			assert.False(t, e.Verified)
+		case secondEmail:
+			assert.True(t, e.Primary)
+			assert.True(t, e.Verified)
+		default:
+			t.Errorf("unexpected e-mail %v", e.Address)
+		}
+	}
+}
+
+// TestUserDataExportAndErasure covers the GDPR endpoints, available only in the application layer example.
+func TestUserDataExportAndErasure(t *testing.T) {
+	client := NewHTTPClient(t, 8083)
+
+	email := gofakeit.Email()
+	client.PostUser(gofakeit.FirstName(), gofakeit.LastName(), email, http.StatusCreated)
+
+	user, ok := findUserByEmail(client.GetAllUsers(), email)
+	require.True(t, ok, "Expected to find the user by email")
+
+	export, ok := client.GetUserDataExport(user.Id)
+	require.True(t, ok, "Expected to export the user's data")
+	assert.Equal(t, user.Id, export.User.Id)
+	assert.Equal(t, user.DisplayName, export.User.DisplayName)
+	require.Len(t, export.User.Emails, 1)
+	assert.Equal(t, email, export.User.Emails[0].Address)
+
+	// Deleted users can be erased too.
+	client.DeleteUser(user.Id)
+	client.EraseUser(user.Id, http.StatusNoContent)
+	client.EraseUser(user.Id, http.StatusNotFound)
+
+	_, ok = client.GetUser(user.Id)
+	assert.False(t, ok, "Expected the erased user not to be found")
+
+	_, ok = client.GetUserDataExport(user.Id)
+	assert.False(t, ok, "Expected no data to export after erasure")
+
+	// The e-mails are deleted, so the address can sign up again.
+	client.PostUser(gofakeit.FirstName(), gofakeit.LastName(), email, http.StatusCreated)
+}
+
+// TestUserRestore covers restoring deleted users, available only in the application layer example.
+func TestUserRestore(t *testing.T) {
+	client := NewHTTPClient(t, 8083)
+
+	email := gofakeit.Email()
+	client.PostUser(gofakeit.FirstName(), gofakeit.LastName(), email, http.StatusCreated)
+
+	user, ok := findUserByEmail(client.GetAllUsers(), email)
+	require.True(t, ok, "Expected to find the user by email")
+
+	client.RestoreUser(user.Id, http.StatusNotFound)
+
+	client.DeleteUser(user.Id)
+
+	_, ok = client.GetUser(user.Id)
+	require.False(t, ok, "Didn't expect to find the deleted user")
+
+	client.RestoreUser(user.Id, http.StatusNoContent)
+
+	restored, ok := client.GetUser(user.Id)
+	require.True(t, ok, "Expected to find the restored user")
+	assert.Equal(t, user.DisplayName, restored.DisplayName)
+	assert.Equal(t, user.Emails, restored.Emails)
+}
+
+// TestOrganizations covers scoping users to organizations, available only in the application layer example.
+func TestOrganizations(t *testing.T) {
+	client := NewHTTPClient(t, 8083)
+	otherClient := NewOrganizationHTTPClient(t, 8083, defaultOrganizationID+1)
+
+	email := gofakeit.Email()
+	client.PostUser(gofakeit.FirstName(), gofakeit.LastName(), email, http.StatusCreated)
+
+	user, ok := findUserByEmail(client.GetAllUsers(), email)
+	require.True(t, ok, "Expected to find the user by email")
+
+	_, ok = findUserByEmail(otherClient.GetAllUsers(), email)
+	assert.False(t, ok, "Didn't expect the user to be listed in another organization")
+
+	_, ok = otherClient.GetUser(user.Id)
+	assert.False(t, ok, "Didn't expect to find the user in another organization")
+
+	// E-mails are unique only within an organization.
+	otherClient.PostUser(gofakeit.FirstName(), gofakeit.LastName(), email, http.StatusCreated)
+	otherClient.PostUser(gofakeit.FirstName(), gofakeit.LastName(), email, http.StatusBadRequest)
+
+	_, ok = client.GetUser(user.Id)
+	assert.True(t, ok, "Expected the user to stay in its organization")
+}
+
+// verificationToken returns the token from the last verification e-mail sent to the address.
+func verificationToken(t *testing.T, address string) string {
+	mails, err := os.ReadFile(mailsFile)
+	require.NoError(t, err)
+
+	var token string
+	for _, mail := range strings.Split(string(mails), "To: ") {
+		if !strings.HasPrefix(mail, address+"\n") {
+			continue
+		}
+
+		lines := strings.Split(strings.TrimSpace(mail), "\n")
+		token = lines[len(lines)-1]
+	}
+
+	require.NotEmpty(t, token, "Expected to find the verification e-mail")
+
+	return token
+}
+
 func findUserByEmail(users []User, emailToFind string) (User, bool) {
 	for _, u := range users {
 		for _, e := range u.Emails {
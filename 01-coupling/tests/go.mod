@@ -4,5 +4,6 @@ go 1.16
 
 require (
 	github.com/brianvoe/gofakeit/v6 v6.5.0
+	github.com/deepmap/oapi-codegen v1.8.1
 	github.com/stretchr/testify v1.7.0
 )
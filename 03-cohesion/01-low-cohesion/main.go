@@ -33,7 +33,7 @@ func main() {
 
 		ctx := ContextWithUser(r.Context(), user)
 
-		err = handler.Execute(ctx, cmd)
+		err = handler.Handle(ctx, cmd)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
@@ -57,7 +57,7 @@ func main() {
 			NewsletterID: "product-news",
 		}
 
-		return handler.Execute(ctx, cmd)
+		return handler.Handle(ctx, cmd)
 	}
 
 	rpcHandler := func(ctx context.Context, req SubscribeRPCRequest) error {
@@ -73,12 +73,31 @@ func main() {
 			NewsletterID: "product-news",
 		}
 
-		return handler.Execute(ctx, cmd)
+		return handler.Handle(ctx, cmd)
 	}
 
 	_ = httpHandler
-	_ = eventHandler
 	_ = rpcHandler
+
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		redisAddr = "localhost:6379"
+	}
+
+	subscriber, err := NewRedisSubscriber(redisAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	router, err := NewEventsRouter(subscriber, eventHandler)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = router.Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func userFromRequest(r *http.Request) (User, error) {
@@ -105,10 +124,11 @@ type SubscribeRPCRequest struct {
 	NewsletterID string
 }
 
+// UserSignedUp is published by the users service in 01-coupling/04-loosely-coupled-app-layer.
 type UserSignedUp struct {
-	ID                 string
-	Email              string
-	ProductNewsConsent bool
+	ID                 string `json:"id"`
+	Email              string `json:"email"`
+	ProductNewsConsent bool   `json:"product_news_consent"`
 }
 
 type nopMetricsClient struct{}
@@ -10,64 +10,57 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-type PointsUsedForDiscount struct {
-	UserID int `json:"user_id"`
-	Points int `json:"points"`
-}
-
-type OnPointsUsedForDiscountHandler struct {
-	addDiscountHandler AddDiscountHandler
-}
+func NewRedisSubscriber(redisAddr string) (message.Subscriber, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
 
-func (h OnPointsUsedForDiscountHandler) Handle(ctx context.Context, event *PointsUsedForDiscount) error {
-	cmd := AddDiscount{
-		UserID:   event.UserID,
-		Discount: event.Points,
-	}
+	logger := watermill.NewStdLogger(false, false)
 
-	return h.addDiscountHandler.Handle(ctx, cmd)
+	return redisstream.NewSubscriber(
+		redisstream.SubscriberConfig{
+			Client:        client,
+			ConsumerGroup: "newsletter",
+		},
+		logger,
+	)
 }
 
+// NewEventsRouter consumes JSON-encoded events from topics named after them,
+// as published by 01-coupling/04-loosely-coupled-app-layer.
 func NewEventsRouter(
-	redisAddr string,
-	addDiscountHandler AddDiscountHandler,
+	subscriber message.Subscriber,
+	onUserSignedUp func(ctx context.Context, event UserSignedUp) error,
 ) (*message.Router, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
-	})
-
 	logger := watermill.NewStdLogger(false, false)
 
-	router := message.NewDefaultRouter(logger)
+	router, err := message.NewRouter(message.RouterConfig{}, logger)
+	if err != nil {
+		return nil, err
+	}
 
 	eventProcessor, err := cqrs.NewEventProcessorWithConfig(router, cqrs.EventProcessorConfig{
 		GenerateSubscribeTopic: func(params cqrs.EventProcessorGenerateSubscribeTopicParams) (string, error) {
 			return params.EventName, nil
 		},
 		SubscriberConstructor: func(params cqrs.EventProcessorSubscriberConstructorParams) (message.Subscriber, error) {
-			return redisstream.NewSubscriber(
-				redisstream.SubscriberConfig{
-					Client:        client,
-					ConsumerGroup: "orders-svc",
-				},
-				logger,
-			)
+			return subscriber, nil
+		},
+		Marshaler: cqrs.JSONMarshaler{
+			GenerateName: cqrs.StructName,
 		},
-		Marshaler: cqrs.JSONMarshaler{},
-		Logger:    logger,
+		Logger: logger,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	onPointsUsedForDiscountHandler := OnPointsUsedForDiscountHandler{
-		addDiscountHandler: addDiscountHandler,
-	}
-
 	err = eventProcessor.AddHandlers(
 		cqrs.NewEventHandler(
-			"OnPointsUsedForDiscountHandler",
-			onPointsUsedForDiscountHandler.Handle,
+			"OnUserSignedUpHandler",
+			func(ctx context.Context, event *UserSignedUp) error {
+				return onUserSignedUp(ctx, *event)
+			},
 		),
 	)
 	if err != nil {
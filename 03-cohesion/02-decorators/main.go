@@ -64,8 +64,27 @@ func main() {
 	}
 
 	_ = httpHandler
-	_ = eventHandler
 	_ = rpcHandler
+
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		redisAddr = "localhost:6379"
+	}
+
+	subscriber, err := NewRedisSubscriber(redisAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	router, err := NewEventsRouter(subscriber, eventHandler)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = router.Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func userFromRequest(r *http.Request) (User, error) {
@@ -92,10 +111,11 @@ type SubscribeRPCRequest struct {
 	NewsletterID string
 }
 
+// UserSignedUp is published by the users service in 01-coupling/04-loosely-coupled-app-layer.
 type UserSignedUp struct {
-	ID                 string
-	Email              string
-	ProductNewsConsent bool
+	ID                 string `json:"id"`
+	Email              string `json:"email"`
+	ProductNewsConsent bool   `json:"product_news_consent"`
 }
 
 type nopMetricsClient struct{}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+func NewRedisSubscriber(redisAddr string) (message.Subscriber, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	logger := watermill.NewStdLogger(false, false)
+
+	return redisstream.NewSubscriber(
+		redisstream.SubscriberConfig{
+			Client:        client,
+			ConsumerGroup: "newsletter",
+		},
+		logger,
+	)
+}
+
+// NewEventsRouter consumes JSON-encoded events from topics named after them,
+// as published by 01-coupling/04-loosely-coupled-app-layer.
+func NewEventsRouter(
+	subscriber message.Subscriber,
+	onUserSignedUp func(ctx context.Context, event UserSignedUp) error,
+) (*message.Router, error) {
+	logger := watermill.NewStdLogger(false, false)
+
+	router, err := message.NewRouter(message.RouterConfig{}, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	eventProcessor, err := cqrs.NewEventProcessorWithConfig(router, cqrs.EventProcessorConfig{
+		GenerateSubscribeTopic: func(params cqrs.EventProcessorGenerateSubscribeTopicParams) (string, error) {
+			return params.EventName, nil
+		},
+		SubscriberConstructor: func(params cqrs.EventProcessorSubscriberConstructorParams) (message.Subscriber, error) {
+			return subscriber, nil
+		},
+		Marshaler: cqrs.JSONMarshaler{
+			GenerateName: cqrs.StructName,
+		},
+		Logger: logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventProcessor.AddHandlers(
+		cqrs.NewEventHandler(
+			"OnUserSignedUpHandler",
+			func(ctx context.Context, event *UserSignedUp) error {
+				return onUserSignedUp(ctx, *event)
+			},
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return router, nil
+}
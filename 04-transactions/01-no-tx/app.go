@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 )
 
+var (
+	ErrInvalidPoints   = NewDomainError(ErrorKindValidation, "points must be greater than 0")
+	ErrNotEnoughPoints = NewDomainError(ErrorKindConflict, "not enough points")
+	ErrUserNotFound    = NewDomainError(ErrorKindNotFound, "user not found")
+)
+
 type UsePointsAsDiscount struct {
 	UserID int
 	Points int
@@ -37,7 +42,7 @@ func NewUsePointsAsDiscountHandler(
 
 func (h UsePointsAsDiscountHandler) Handle(ctx context.Context, cmd UsePointsAsDiscount) error {
 	if cmd.Points <= 0 {
-		return errors.New("points must be greater than 0")
+		return ErrInvalidPoints
 	}
 
 	currentPoints, err := h.userRepository.GetPoints(ctx, cmd.UserID)
@@ -46,7 +51,7 @@ func (h UsePointsAsDiscountHandler) Handle(ctx context.Context, cmd UsePointsAsD
 	}
 
 	if currentPoints < cmd.Points {
-		return errors.New("not enough points")
+		return ErrNotEnoughPoints
 	}
 
 	err = h.userRepository.TakePoints(ctx, cmd.UserID, cmd.Points)
@@ -3,25 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 )
 
-func MigrateDB(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			email TEXT NOT NULL UNIQUE,
-			points INT NOT NULL DEFAULT 0,
-			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS user_discounts (
-			user_id INT PRIMARY KEY REFERENCES users(id),
-			next_order_discount INT NOT NULL DEFAULT 0
-		);
-	`)
-	return err
-}
-
 type PostgresUserRepository struct {
 	db *sql.DB
 }
@@ -37,6 +21,9 @@ func (r *PostgresUserRepository) GetPoints(ctx context.Context, userID int) (int
 
 	var points int
 	err := row.Scan(&points)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -7,6 +7,12 @@ import (
 	"fmt"
 )
 
+var (
+	ErrInvalidPoints   = NewDomainError(ErrorKindValidation, "points must be greater than 0")
+	ErrNotEnoughPoints = NewDomainError(ErrorKindConflict, "not enough points")
+	ErrUserNotFound    = NewDomainError(ErrorKindNotFound, "user not found")
+)
+
 type UsePointsAsDiscount struct {
 	UserID int
 	Points int
@@ -42,7 +48,7 @@ func NewUsePointsAsDiscountHandler(
 func (h UsePointsAsDiscountHandler) Handle(ctx context.Context, cmd UsePointsAsDiscount) error {
 	return runInTx(h.db, func(tx *sql.Tx) error {
 		if cmd.Points <= 0 {
-			return errors.New("points must be greater than 0")
+			return ErrInvalidPoints
 		}
 
 		currentPoints, err := h.userRepository.GetPoints(ctx, tx, cmd.UserID)
@@ -51,7 +57,7 @@ func (h UsePointsAsDiscountHandler) Handle(ctx context.Context, cmd UsePointsAsD
 		}
 
 		if currentPoints < cmd.Points {
-			return errors.New("not enough points")
+			return ErrNotEnoughPoints
 		}
 
 		err = h.userRepository.TakePoints(ctx, tx, cmd.UserID, cmd.Points)
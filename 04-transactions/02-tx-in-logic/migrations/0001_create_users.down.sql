@@ -0,0 +1 @@
+-- users and user_discounts are shared with the other examples, so they're kept.
@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 )
 
+var (
+	ErrInvalidPoints   = NewDomainError(ErrorKindValidation, "points must be greater than 0")
+	ErrNotEnoughPoints = NewDomainError(ErrorKindConflict, "not enough points")
+	ErrUserNotFound    = NewDomainError(ErrorKindNotFound, "user not found")
+)
+
 type UsePointsAsDiscount struct {
 	UserID int
 	Points int
@@ -29,7 +34,7 @@ func NewUsePointsAsDiscountHandler(
 
 func (h UsePointsAsDiscountHandler) Handle(ctx context.Context, cmd UsePointsAsDiscount) error {
 	if cmd.Points <= 0 {
-		return errors.New("points must be greater than 0")
+		return ErrInvalidPoints
 	}
 
 	err := h.userRepository.UsePointsForDiscount(ctx, cmd.UserID, cmd.Points)
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
+	"os"
 
 	_ "github.com/lib/pq"
 )
@@ -13,7 +15,20 @@ func main() {
 		panic(err)
 	}
 
-	err = MigrateDB(db)
+	migrator, err := NewMigrator(db, "03-tx-in-repo")
+	if err != nil {
+		panic(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = migrator.RunCommand(context.Background(), os.Args[2:])
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	err = migrator.Up(context.Background())
 	if err != nil {
 		panic(err)
 	}
@@ -6,23 +6,6 @@ import (
 	"errors"
 )
 
-func MigrateDB(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			email TEXT NOT NULL UNIQUE,
-			points INT NOT NULL DEFAULT 0,
-			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS user_discounts (
-			user_id INT PRIMARY KEY REFERENCES users(id),
-			next_order_discount INT NOT NULL DEFAULT 0
-		);
-	`)
-	return err
-}
-
 type PostgresUserRepository struct {
 	db *sql.DB
 }
@@ -39,12 +22,15 @@ func (r *PostgresUserRepository) UsePointsForDiscount(ctx context.Context, userI
 
 		var currentPoints int
 		err := row.Scan(&currentPoints)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
 		if err != nil {
 			return err
 		}
 
 		if currentPoints < points {
-			return errors.New("not enough points")
+			return ErrNotEnoughPoints
 		}
 
 		_, err = tx.ExecContext(ctx, "UPDATE users SET points = points - $1 WHERE id = $2", points, userID)
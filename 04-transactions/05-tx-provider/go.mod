@@ -2,4 +2,9 @@ module github.com/ThreeDotsLabs/go-web-app-antipatterns/04-transactions/05-tx-pr
 
 go 1.22.0
 
-require github.com/lib/pq v1.10.9
+require (
+	github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate v0.0.0-00010101000000-000000000000
+	github.com/lib/pq v1.10.9
+)
+
+replace github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate => ../../migrate
@@ -0,0 +1,2 @@
+-- users and user_discounts are shared with the other examples, so they're kept.
+DROP TABLE IF EXISTS audit_log;
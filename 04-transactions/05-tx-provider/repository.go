@@ -3,31 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 )
 
-func MigrateDB(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			email TEXT NOT NULL UNIQUE,
-			points INT NOT NULL DEFAULT 0,
-			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS user_discounts (
-			user_id INT PRIMARY KEY REFERENCES users(id),
-			next_order_discount INT NOT NULL DEFAULT 0
-		);
-
-		CREATE TABLE IF NOT EXISTS audit_log (
-			id SERIAL PRIMARY KEY,
-			log TEXT NOT NULL,
-			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
-	return err
-}
-
 type db interface {
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
@@ -48,6 +26,9 @@ func (r *PostgresUserRepository) UpdateByID(ctx context.Context, userID int, upd
 	var email string
 	var currentPoints int
 	err := row.Scan(&email, &currentPoints)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
 	if err != nil {
 		return err
 	}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -45,6 +46,66 @@ func TestAll(t *testing.T) {
 
 			assertPoints(t, userID, 25)
 			assertDiscount(t, userID, 75)
+
+			testUsePointsErrors(t, tc, userID)
+
+			assertPoints(t, userID, 25)
+			assertDiscount(t, userID, 75)
+		})
+	}
+}
+
+// testUsePointsErrors checks if domain errors are mapped to status codes.
+// The user must have less than 1000 points.
+func testUsePointsErrors(t *testing.T, tc testCase, userID int) {
+	t.Helper()
+
+	testCases := []struct {
+		Name               string
+		Body               string
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "invalid_json",
+			Body:               `{"user_id": `,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "zero_points",
+			Body:               fmt.Sprintf(`{"user_id": %d, "points": 0}`, userID),
+			ExpectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:               "unknown_user",
+			Body:               `{"user_id": 2147483647, "points": 10}`,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "not_enough_points",
+			Body:               fmt.Sprintf(`{"user_id": %d, "points": 1000}`, userID),
+			ExpectedStatusCode: http.StatusConflict,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.Name, func(t *testing.T) {
+			res, err := http.Post(tc.URL+"/use-points", "application/json", strings.NewReader(c.Body))
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, c.ExpectedStatusCode, res.StatusCode, string(body))
+			assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+			var response struct {
+				Error string `json:"error"`
+			}
+			err = json.Unmarshal(body, &response)
+			require.NoError(t, err)
+
+			assert.NotEmpty(t, response.Error)
 		})
 	}
 }
@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
-	"errors"
+)
+
+var (
+	ErrInvalidDiscount = NewDomainError(ErrorKindValidation, "discount must be greater than 0")
+	ErrUserNotFound    = NewDomainError(ErrorKindNotFound, "user not found")
 )
 
 type AddDiscount struct {
@@ -28,7 +32,7 @@ func NewAddDiscountHandler(
 
 func (h AddDiscountHandler) Handle(ctx context.Context, cmd AddDiscount) error {
 	if cmd.Discount <= 0 {
-		return errors.New("discount must be greater than 0")
+		return ErrInvalidDiscount
 	}
 
 	return h.discountRepository.AddDiscount(ctx, cmd.UserID, cmd.Discount)
@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 )
 
@@ -19,7 +21,7 @@ func NewHTTPHandler(
 		var p payload
 		err := json.NewDecoder(r.Body).Decode(&p)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeErrorResponse(w, http.StatusBadRequest, err)
 			return
 		}
 
@@ -30,10 +32,50 @@ func NewHTTPHandler(
 
 		err = addDiscountHandler.Handle(r.Context(), cmd)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 	})
 
 	return mux
 }
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+var errInternal = errors.New("internal server error")
+
+// writeError responds with the status code matching the kind of the domain error.
+// Other errors are logged and reported as Internal Server Error, without their details,
+// as they may contain SQL queries or driver messages.
+func writeError(w http.ResponseWriter, err error) {
+	var domainErr DomainError
+	if !errors.As(err, &domainErr) {
+		slog.Error("Internal server error", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, errInternal)
+		return
+	}
+
+	status := http.StatusInternalServerError
+
+	switch domainErr.Kind() {
+	case ErrorKindValidation:
+		status = http.StatusUnprocessableEntity
+	case ErrorKindNotFound:
+		status = http.StatusNotFound
+	case ErrorKindConflict:
+		status = http.StatusConflict
+	}
+
+	writeErrorResponse(w, status, domainErr)
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(errorResponse{
+		Error: err.Error(),
+	})
+}
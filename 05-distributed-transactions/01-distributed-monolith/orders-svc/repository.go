@@ -5,16 +5,6 @@ import (
 	"database/sql"
 )
 
-func MigrateDB(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS user_discounts (
-			user_id INT PRIMARY KEY,
-			next_order_discount INT NOT NULL DEFAULT 0
-	    );
-	`)
-	return err
-}
-
 type PostgresDiscountRepository struct {
 	db *sql.DB
 }
@@ -26,10 +16,19 @@ func NewPostgresDiscountRepository(db *sql.DB) *PostgresDiscountRepository {
 }
 
 func (r *PostgresDiscountRepository) AddDiscount(ctx context.Context, userID int, discount int) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE user_discounts SET next_order_discount = next_order_discount + $1 WHERE user_id = $2", discount, userID)
+	res, err := r.db.ExecContext(ctx, "UPDATE user_discounts SET next_order_discount = next_order_discount + $1 WHERE user_id = $2", discount, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+
 	return nil
 }
@@ -0,0 +1 @@
+-- users is shared with users-svc of the other examples, so it's kept.
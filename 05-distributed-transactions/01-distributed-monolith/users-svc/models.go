@@ -1,6 +1,10 @@
 package main
 
-import "errors"
+var (
+	ErrInvalidPoints   = NewDomainError(ErrorKindValidation, "points must be greater than 0")
+	ErrNotEnoughPoints = NewDomainError(ErrorKindConflict, "not enough points")
+	ErrUserNotFound    = NewDomainError(ErrorKindNotFound, "user not found")
+)
 
 type User struct {
 	id     int
@@ -10,11 +14,11 @@ type User struct {
 
 func (u *User) UsePoints(points int) error {
 	if points <= 0 {
-		return errors.New("points must be greater than 0")
+		return ErrInvalidPoints
 	}
 
 	if u.points < points {
-		return errors.New("not enough points")
+		return ErrNotEnoughPoints
 	}
 
 	u.points -= points
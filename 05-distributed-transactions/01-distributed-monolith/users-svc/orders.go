@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 )
 
@@ -49,8 +50,12 @@ func (c OrdersClient) AddDiscount(ctx context.Context, userID int, discount int)
 		return err
 	}
 
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
 	if resp.StatusCode != http.StatusOK {
-		return err
+		return fmt.Errorf("orders-svc responded with status %d", resp.StatusCode)
 	}
 
 	return nil
@@ -6,18 +6,6 @@ import (
 	"errors"
 )
 
-func MigrateDB(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			email TEXT NOT NULL UNIQUE,
-			points INT NOT NULL DEFAULT 0,
-			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
-	return err
-}
-
 type PostgresUserRepository struct {
 	db *sql.DB
 }
@@ -35,6 +23,9 @@ func (r *PostgresUserRepository) UpdateByID(ctx context.Context, userID int, upd
 		var email string
 		var currentPoints int
 		err := row.Scan(&email, &currentPoints)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,71 @@
+package orders
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidDiscount = NewDomainError(ErrorKindValidation, "discount must be greater than 0")
+	ErrUserNotFound    = NewDomainError(ErrorKindNotFound, "user not found")
+)
+
+type AddDiscount struct {
+	OperationID string
+	UserID      int
+	Discount    int
+}
+
+type AddDiscountHandler struct {
+	discountRepository DiscountRepository
+	eventPublisher     EventPublisher
+}
+
+type DiscountRepository interface {
+	// AddDiscount adds the discount once per operation. It does nothing for an operation
+	// that was already added, so redelivered events don't add the discount twice.
+	AddDiscount(ctx context.Context, operationID string, userID int, discount int) error
+	NextOrderDiscount(ctx context.Context, userID int) (int, error)
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event any) error
+}
+
+func NewAddDiscountHandler(
+	discountRepository DiscountRepository,
+	eventPublisher EventPublisher,
+) AddDiscountHandler {
+	return AddDiscountHandler{
+		discountRepository: discountRepository,
+		eventPublisher:     eventPublisher,
+	}
+}
+
+func (h AddDiscountHandler) Handle(ctx context.Context, cmd AddDiscount) error {
+	err := h.addDiscount(ctx, cmd)
+	if errors.Is(err, ErrInvalidDiscount) || errors.Is(err, ErrUserNotFound) {
+		// Retrying won't help, so we let the users service know the discount won't be applied.
+		return h.eventPublisher.Publish(ctx, &DiscountApplicationFailed{
+			OperationID: cmd.OperationID,
+			Reason:      err.Error(),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	// The event is published again for an operation that was already added, as the previous
+	// publish may be the reason the event was redelivered.
+	return h.eventPublisher.Publish(ctx, &DiscountApplied{
+		OperationID: cmd.OperationID,
+	})
+}
+
+func (h AddDiscountHandler) addDiscount(ctx context.Context, cmd AddDiscount) error {
+	if cmd.Discount <= 0 {
+		return ErrInvalidDiscount
+	}
+
+	return h.discountRepository.AddDiscount(ctx, cmd.OperationID, cmd.UserID, cmd.Discount)
+}
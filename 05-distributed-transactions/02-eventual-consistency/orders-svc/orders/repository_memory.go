@@ -0,0 +1,60 @@
+package orders
+
+import (
+	"context"
+	"sync"
+)
+
+type MemoryDiscountRepository struct {
+	lock      sync.Mutex
+	discounts map[int]int
+	applied   map[string]struct{}
+}
+
+func NewMemoryDiscountRepository() *MemoryDiscountRepository {
+	return &MemoryDiscountRepository{
+		discounts: map[int]int{},
+		applied:   map[string]struct{}{},
+	}
+}
+
+func (r *MemoryDiscountRepository) AddUser(ctx context.Context, userID int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.discounts[userID]; !ok {
+		r.discounts[userID] = 0
+	}
+
+	return nil
+}
+
+func (r *MemoryDiscountRepository) AddDiscount(ctx context.Context, operationID string, userID int, discount int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.applied[operationID]; ok {
+		return nil
+	}
+
+	if _, ok := r.discounts[userID]; !ok {
+		return ErrUserNotFound
+	}
+
+	r.discounts[userID] += discount
+	r.applied[operationID] = struct{}{}
+
+	return nil
+}
+
+func (r *MemoryDiscountRepository) NextOrderDiscount(ctx context.Context, userID int) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	discount, ok := r.discounts[userID]
+	if !ok {
+		return 0, ErrUserNotFound
+	}
+
+	return discount, nil
+}
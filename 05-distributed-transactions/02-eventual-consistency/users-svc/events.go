@@ -1,39 +0,0 @@
-package main
-
-import (
-	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
-	"github.com/ThreeDotsLabs/watermill/components/cqrs"
-	"github.com/redis/go-redis/v9"
-)
-
-func NewWatermillEventBus(redisAddr string) (*cqrs.EventBus, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
-	})
-
-	logger := watermill.NewStdLogger(false, false)
-
-	publisher, err := redisstream.NewPublisher(
-		redisstream.PublisherConfig{
-			Client: client,
-		},
-		logger,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	eventBus, err := cqrs.NewEventBusWithConfig(publisher, cqrs.EventBusConfig{
-		GeneratePublishTopic: func(params cqrs.GenerateEventPublishTopicParams) (string, error) {
-			return params.EventName, nil
-		},
-		Marshaler: cqrs.JSONMarshaler{},
-		Logger:    logger,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return eventBus, nil
-}
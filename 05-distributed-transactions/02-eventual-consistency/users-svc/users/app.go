@@ -0,0 +1,141 @@
+package users
+
+import (
+	"context"
+	"fmt"
+)
+
+var ErrOperationNotFound = NewDomainError(ErrorKindNotFound, "operation not found")
+
+type UsePointsAsDiscount struct {
+	OperationID string
+	UserID      int
+	Points      int
+}
+
+type UsePointsAsDiscountHandler struct {
+	userRepository UserRepository
+	eventPublisher EventPublisher
+}
+
+type UserRepository interface {
+	// UpdateByIDWithOperation adds the operation in the same transaction as the user's update,
+	// so the operation exists only if the points were used.
+	UpdateByIDWithOperation(ctx context.Context, userID int, operation *Operation, updateFn func(user *User) (bool, error)) error
+}
+
+type OperationRepository interface {
+	ByID(ctx context.Context, operationID string) (*Operation, error)
+	UpdateByID(ctx context.Context, operationID string, updateFn func(operation *Operation) (bool, error)) error
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event any) error
+}
+
+func NewUsePointsAsDiscountHandler(
+	userRepository UserRepository,
+	eventPublisher EventPublisher,
+) UsePointsAsDiscountHandler {
+	return UsePointsAsDiscountHandler{
+		userRepository: userRepository,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (h UsePointsAsDiscountHandler) Handle(ctx context.Context, cmd UsePointsAsDiscount) error {
+	operation, err := NewOperation(cmd.OperationID, cmd.UserID, cmd.Points)
+	if err != nil {
+		return err
+	}
+
+	err = h.userRepository.UpdateByIDWithOperation(ctx, cmd.UserID, operation, func(user *User) (bool, error) {
+		err := user.UsePoints(cmd.Points)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not update user: %w", err)
+	}
+
+	event := &PointsUsedForDiscount{
+		OperationID: cmd.OperationID,
+		UserID:      cmd.UserID,
+		Points:      cmd.Points,
+	}
+
+	err = h.eventPublisher.Publish(ctx, event)
+	if err != nil {
+		return fmt.Errorf("could not publish event: %w", err)
+	}
+
+	return nil
+}
+
+type MarkOperationApplied struct {
+	OperationID string
+}
+
+type MarkOperationAppliedHandler struct {
+	operationRepository OperationRepository
+}
+
+func NewMarkOperationAppliedHandler(
+	operationRepository OperationRepository,
+) MarkOperationAppliedHandler {
+	return MarkOperationAppliedHandler{
+		operationRepository: operationRepository,
+	}
+}
+
+func (h MarkOperationAppliedHandler) Handle(ctx context.Context, cmd MarkOperationApplied) error {
+	return h.operationRepository.UpdateByID(ctx, cmd.OperationID, func(operation *Operation) (bool, error) {
+		if operation.Status() == OperationStatusApplied {
+			// The confirmation was delivered more than once.
+			return false, nil
+		}
+
+		err := operation.MarkApplied()
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	})
+}
+
+type MarkOperationFailed struct {
+	OperationID string
+	Reason      string
+}
+
+type MarkOperationFailedHandler struct {
+	operationRepository OperationRepository
+}
+
+func NewMarkOperationFailedHandler(
+	operationRepository OperationRepository,
+) MarkOperationFailedHandler {
+	return MarkOperationFailedHandler{
+		operationRepository: operationRepository,
+	}
+}
+
+func (h MarkOperationFailedHandler) Handle(ctx context.Context, cmd MarkOperationFailed) error {
+	return h.operationRepository.UpdateByID(ctx, cmd.OperationID, func(operation *Operation) (bool, error) {
+		if operation.Status() == OperationStatusFailed {
+			// The confirmation was delivered more than once.
+			return false, nil
+		}
+
+		err := operation.MarkFailed(cmd.Reason)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	})
+}
@@ -0,0 +1,127 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+func NewHTTPHandler(
+	usePointsAsDiscountHandler UsePointsAsDiscountHandler,
+	operationRepository OperationRepository,
+) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /use-points", func(w http.ResponseWriter, r *http.Request) {
+		type payload struct {
+			UserID int `json:"user_id"`
+			Points int `json:"points"`
+		}
+
+		var p payload
+		err := json.NewDecoder(r.Body).Decode(&p)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err)
+			return
+		}
+
+		cmd := UsePointsAsDiscount{
+			OperationID: watermill.NewUUID(),
+			UserID:      p.UserID,
+			Points:      p.Points,
+		}
+
+		err = usePointsAsDiscountHandler.Handle(r.Context(), cmd)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		type response struct {
+			OperationID string `json:"operation_id"`
+		}
+
+		writeJSON(w, response{
+			OperationID: cmd.OperationID,
+		})
+	})
+
+	mux.HandleFunc("GET /operations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		operation, err := operationRepository.ByID(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		type response struct {
+			ID     string `json:"id"`
+			UserID int    `json:"user_id"`
+			Points int    `json:"points"`
+			Status string `json:"status"`
+			Reason string `json:"reason,omitempty"`
+		}
+
+		writeJSON(w, response{
+			ID:     operation.ID(),
+			UserID: operation.UserID(),
+			Points: operation.Points(),
+			Status: string(operation.Status()),
+			Reason: operation.Reason(),
+		})
+	})
+
+	return observabilityMiddleware(mux)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+var errInternal = errors.New("internal server error")
+
+// writeError responds with the status code matching the kind of the domain error.
+// Other errors are logged and reported as Internal Server Error, without their details,
+// as they may contain SQL queries or driver messages.
+func writeError(w http.ResponseWriter, err error) {
+	var domainErr DomainError
+	if !errors.As(err, &domainErr) {
+		slog.Error("Internal server error", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, errInternal)
+		return
+	}
+
+	status := http.StatusInternalServerError
+
+	switch domainErr.Kind() {
+	case ErrorKindValidation:
+		status = http.StatusUnprocessableEntity
+	case ErrorKindNotFound:
+		status = http.StatusNotFound
+	case ErrorKindConflict:
+		status = http.StatusConflict
+	}
+
+	writeErrorResponse(w, status, domainErr)
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(errorResponse{
+		Error: err.Error(),
+	})
+}
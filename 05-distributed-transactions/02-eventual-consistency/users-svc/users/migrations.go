@@ -0,0 +1,15 @@
+package users
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+func NewMigrator(db *sql.DB, service string) (*migrate.Migrator, error) {
+	return migrate.New(db, migrate.PostgresService(service), migrationFiles, "migrations")
+}
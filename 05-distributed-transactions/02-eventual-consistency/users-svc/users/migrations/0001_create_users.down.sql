@@ -0,0 +1 @@
+-- users and operations are shared with users-svc of the other examples, so they're kept.
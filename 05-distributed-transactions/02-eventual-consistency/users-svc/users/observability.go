@@ -0,0 +1,162 @@
+package users
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	correlationIDHeader       = "X-Correlation-ID"
+	maxCorrelationIDLength    = 128
+	correlationIDLogAttribute = "correlation_id"
+)
+
+var tracer = otel.Tracer("users-svc")
+
+type correlationIDKey struct{}
+
+func ContextWithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID
+}
+
+// NewLogHandler wraps the handler, so each log line contains the correlation ID from the context.
+func NewLogHandler(handler slog.Handler) slog.Handler {
+	return correlationIDLogHandler{handler}
+}
+
+type correlationIDLogHandler struct {
+	slog.Handler
+}
+
+func (h correlationIDLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if correlationID := CorrelationIDFromContext(ctx); correlationID != "" {
+		record.AddAttrs(slog.String(correlationIDLogAttribute, correlationID))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h correlationIDLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return correlationIDLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h correlationIDLogHandler) WithGroup(name string) slog.Handler {
+	return correlationIDLogHandler{h.Handler.WithGroup(name)}
+}
+
+// newWatermillLogger logs Watermill's messages with the default slog logger,
+// so they go through the handler from NewLogHandler like the rest of the logs.
+func newWatermillLogger() watermill.LoggerAdapter {
+	return watermill.NewSlogLogger(slog.Default())
+}
+
+// observabilityMiddleware accepts the X-Correlation-ID and traceparent headers (or starts new ones),
+// and logs each request.
+func observabilityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		correlationID := r.Header.Get(correlationIDHeader)
+		if correlationID == "" || len(correlationID) > maxCorrelationIDLength {
+			correlationID = watermill.NewUUID()
+		}
+
+		w.Header().Set(correlationIDHeader, correlationID)
+
+		ctx := ContextWithCorrelationID(r.Context(), correlationID)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracer.Start(
+			ctx,
+			r.Method+" "+r.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String(correlationIDLogAttribute, correlationID)),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+
+		slog.InfoContext(
+			ctx,
+			"Request handled",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.status,
+			"duration", time.Since(start),
+		)
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// propagateToMessage stores the correlation ID and the trace context from the message's context in its metadata.
+func propagateToMessage(msg *message.Message) {
+	ctx := msg.Context()
+
+	middleware.SetCorrelationID(CorrelationIDFromContext(ctx), msg)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(msg.Metadata))
+}
+
+// restoreFromMessage is a router middleware restoring the context stored by propagateToMessage.
+func restoreFromMessage(h message.HandlerFunc) message.HandlerFunc {
+	return func(msg *message.Message) ([]*message.Message, error) {
+		correlationID := middleware.MessageCorrelationID(msg)
+
+		ctx := ContextWithCorrelationID(msg.Context(), correlationID)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Metadata))
+
+		handlerName := message.HandlerNameFromCtx(msg.Context())
+
+		ctx, span := tracer.Start(
+			ctx,
+			handlerName,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.String(correlationIDLogAttribute, correlationID)),
+		)
+		defer span.End()
+
+		msg.SetContext(ctx)
+
+		msgs, err := h(msg)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			slog.ErrorContext(ctx, "Message handling failed", "handler", handlerName, "message_uuid", msg.UUID, "error", err)
+			return nil, err
+		}
+
+		slog.InfoContext(ctx, "Message handled", "handler", handlerName, "message_uuid", msg.UUID)
+
+		return msgs, nil
+	}
+}
@@ -0,0 +1,124 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryUserRepository keeps users in memory. Operations are added to operationRepository
+// while both locks are held, which plays the role of the transaction.
+type MemoryUserRepository struct {
+	lock                sync.Mutex
+	users               map[int]User
+	operationRepository *MemoryOperationRepository
+}
+
+func NewMemoryUserRepository(operationRepository *MemoryOperationRepository) *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:               map[int]User{},
+		operationRepository: operationRepository,
+	}
+}
+
+func (r *MemoryUserRepository) Add(ctx context.Context, user *User) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.users[user.ID()]; ok {
+		return fmt.Errorf("user %d already exists", user.ID())
+	}
+
+	r.users[user.ID()] = *user
+
+	return nil
+}
+
+func (r *MemoryUserRepository) ByID(ctx context.Context, userID int) (*User, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) UpdateByIDWithOperation(ctx context.Context, userID int, operation *Operation, updateFn func(user *User) (bool, error)) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.operationRepository.lock.Lock()
+	defer r.operationRepository.lock.Unlock()
+
+	if _, ok := r.operationRepository.operations[operation.ID()]; ok {
+		return fmt.Errorf("operation %s already exists", operation.ID())
+	}
+
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	updated, err := updateFn(&user)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return nil
+	}
+
+	r.users[userID] = user
+	r.operationRepository.operations[operation.ID()] = *operation
+
+	return nil
+}
+
+type MemoryOperationRepository struct {
+	lock       sync.Mutex
+	operations map[string]Operation
+}
+
+func NewMemoryOperationRepository() *MemoryOperationRepository {
+	return &MemoryOperationRepository{
+		operations: map[string]Operation{},
+	}
+}
+
+func (r *MemoryOperationRepository) ByID(ctx context.Context, operationID string) (*Operation, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	operation, ok := r.operations[operationID]
+	if !ok {
+		return nil, ErrOperationNotFound
+	}
+
+	return &operation, nil
+}
+
+func (r *MemoryOperationRepository) UpdateByID(ctx context.Context, operationID string, updateFn func(operation *Operation) (bool, error)) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	operation, ok := r.operations[operationID]
+	if !ok {
+		return ErrOperationNotFound
+	}
+
+	updated, err := updateFn(&operation)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return nil
+	}
+
+	r.operations[operationID] = operation
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package orders
+
+//go:generate sh -c "protoc -I=. -I=$(go list -m -f '{{.Dir}}' github.com/gogo/protobuf) --gogofaster_out=. events.proto"
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+type OnPointsUsedForDiscountHandler struct {
+	addDiscountHandler AddDiscountHandler
+}
+
+func (h OnPointsUsedForDiscountHandler) Handle(ctx context.Context, event *PointsUsedForDiscount) error {
+	cmd := AddDiscount{
+		OperationID: event.OperationID,
+		UserID:      event.UserID,
+		Discount:    event.Points,
+	}
+
+	return h.addDiscountHandler.Handle(ctx, cmd)
+}
+
+// NewEventsMarshaler returns the marshaler for the given name ("json" or "protobuf").
+func NewEventsMarshaler(name string) (cqrs.CommandEventMarshaler, error) {
+	switch name {
+	case "", "json":
+		return cqrs.JSONMarshaler{
+			GenerateName: eventName,
+		}, nil
+	case "protobuf":
+		return cqrs.ProtobufMarshaler{
+			GenerateName: eventName,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown events marshaler: %s", name)
+	}
+}
+
+// eventName names events, and so their topics, after the struct alone, e.g. PointsUsedForDiscount.
+func eventName(v any) string {
+	return cqrs.StructName(v)
+}
+
+func NewRedisSubscriber(redisAddr string) (message.Subscriber, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	logger := newWatermillLogger()
+
+	return redisstream.NewSubscriber(
+		redisstream.SubscriberConfig{
+			Client:        client,
+			ConsumerGroup: "orders-svc",
+		},
+		logger,
+	)
+}
+
+func NewRedisPublisher(redisAddr string) (message.Publisher, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	logger := newWatermillLogger()
+
+	return redisstream.NewPublisher(
+		redisstream.PublisherConfig{
+			Client: client,
+		},
+		logger,
+	)
+}
+
+func NewWatermillEventBus(publisher message.Publisher, marshaler cqrs.CommandEventMarshaler) (*cqrs.EventBus, error) {
+	logger := newWatermillLogger()
+
+	eventBus, err := cqrs.NewEventBusWithConfig(publisher, cqrs.EventBusConfig{
+		GeneratePublishTopic: func(params cqrs.GenerateEventPublishTopicParams) (string, error) {
+			return params.EventName, nil
+		},
+		OnPublish: func(params cqrs.OnEventSendParams) error {
+			propagateToMessage(params.Message)
+			return nil
+		},
+		Marshaler: marshaler,
+		Logger:    logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return eventBus, nil
+}
+
+func NewEventsRouter(
+	subscriber message.Subscriber,
+	marshaler cqrs.CommandEventMarshaler,
+	addDiscountHandler AddDiscountHandler,
+) (*message.Router, error) {
+	logger := newWatermillLogger()
+
+	router := message.NewDefaultRouter(logger)
+	router.AddMiddleware(restoreFromMessage)
+
+	eventProcessor, err := cqrs.NewEventProcessorWithConfig(router, cqrs.EventProcessorConfig{
+		GenerateSubscribeTopic: func(params cqrs.EventProcessorGenerateSubscribeTopicParams) (string, error) {
+			return params.EventName, nil
+		},
+		SubscriberConstructor: func(params cqrs.EventProcessorSubscriberConstructorParams) (message.Subscriber, error) {
+			return subscriber, nil
+		},
+		Marshaler: marshaler,
+		Logger:    logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	onPointsUsedForDiscountHandler := OnPointsUsedForDiscountHandler{
+		addDiscountHandler: addDiscountHandler,
+	}
+
+	err = eventProcessor.AddHandlers(
+		cqrs.NewEventHandler(
+			"OnPointsUsedForDiscountHandler",
+			onPointsUsedForDiscountHandler.Handle,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return router, nil
+}
@@ -0,0 +1,86 @@
+package orders
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+func NewHTTPHandler(
+	discountRepository DiscountRepository,
+) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /users/{id}/discount", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, err)
+			return
+		}
+
+		discount, err := discountRepository.NextOrderDiscount(r.Context(), userID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		type response struct {
+			UserID            int `json:"user_id"`
+			NextOrderDiscount int `json:"next_order_discount"`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(response{
+			UserID:            userID,
+			NextOrderDiscount: discount,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	})
+
+	return observabilityMiddleware(mux)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+var errInternal = errors.New("internal server error")
+
+// writeError responds with the status code matching the kind of the domain error.
+// Other errors are logged and reported as Internal Server Error, without their details,
+// as they may contain SQL queries or driver messages.
+func writeError(w http.ResponseWriter, err error) {
+	var domainErr DomainError
+	if !errors.As(err, &domainErr) {
+		slog.Error("Internal server error", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, errInternal)
+		return
+	}
+
+	status := http.StatusInternalServerError
+
+	switch domainErr.Kind() {
+	case ErrorKindValidation:
+		status = http.StatusUnprocessableEntity
+	case ErrorKindNotFound:
+		status = http.StatusNotFound
+	case ErrorKindConflict:
+		status = http.StatusConflict
+	}
+
+	writeErrorResponse(w, status, domainErr)
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(errorResponse{
+		Error: err.Error(),
+	})
+}
@@ -0,0 +1 @@
+-- user_discounts is shared with orders-svc of the other examples, so it's kept.
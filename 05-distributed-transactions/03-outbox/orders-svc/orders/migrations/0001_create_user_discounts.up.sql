@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS user_discounts (
+  user_id INT PRIMARY KEY,
+  next_order_discount INT NOT NULL DEFAULT 0
+);
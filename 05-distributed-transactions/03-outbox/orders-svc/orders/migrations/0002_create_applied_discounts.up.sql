@@ -0,0 +1,7 @@
+-- The operations whose discounts were added, so redelivered events don't add them twice.
+CREATE TABLE IF NOT EXISTS applied_discounts (
+  operation_id TEXT PRIMARY KEY,
+  user_id INT NOT NULL,
+  discount INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
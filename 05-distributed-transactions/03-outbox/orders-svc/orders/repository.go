@@ -0,0 +1,90 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type PostgresDiscountRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDiscountRepository(db *sql.DB) *PostgresDiscountRepository {
+	return &PostgresDiscountRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresDiscountRepository) AddDiscount(ctx context.Context, operationID string, userID int, discount int) error {
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO applied_discounts (operation_id, user_id, discount) VALUES ($1, $2, $3) ON CONFLICT (operation_id) DO NOTHING",
+			operationID, userID, discount,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			// The operation was already added.
+			return nil
+		}
+
+		res, err = tx.ExecContext(ctx, "UPDATE user_discounts SET next_order_discount = next_order_discount + $1 WHERE user_id = $2", discount, userID)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return ErrUserNotFound
+		}
+
+		return nil
+	})
+}
+
+func (r *PostgresDiscountRepository) NextOrderDiscount(ctx context.Context, userID int) (int, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT next_order_discount FROM user_discounts WHERE user_id = $1", userID)
+
+	var discount int
+	err := row.Scan(&discount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return discount, nil
+}
+
+func runInTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err == nil {
+		return tx.Commit()
+	}
+
+	rollbackErr := tx.Rollback()
+	if rollbackErr != nil {
+		return errors.Join(err, rollbackErr)
+	}
+
+	return err
+}
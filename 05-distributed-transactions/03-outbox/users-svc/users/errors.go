@@ -0,0 +1,31 @@
+package users
+
+// ErrorKind groups domain errors, so the HTTP layer can map them to status codes.
+type ErrorKind string
+
+const (
+	ErrorKindValidation ErrorKind = "validation"
+	ErrorKindNotFound   ErrorKind = "not_found"
+	ErrorKindConflict   ErrorKind = "conflict"
+)
+
+// DomainError is caused by the request (e.g., invalid input), not by a failure of the system.
+type DomainError struct {
+	kind    ErrorKind
+	message string
+}
+
+func NewDomainError(kind ErrorKind, message string) DomainError {
+	return DomainError{
+		kind:    kind,
+		message: message,
+	}
+}
+
+func (e DomainError) Error() string {
+	return e.message
+}
+
+func (e DomainError) Kind() ErrorKind {
+	return e.kind
+}
@@ -0,0 +1,226 @@
+package users
+
+//go:generate sh -c "protoc -I=. -I=$(go list -m -f '{{.Dir}}' github.com/gogo/protobuf) --gogofaster_out=. events.proto"
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	watermillSQL "github.com/ThreeDotsLabs/watermill-sql/v3/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/components/forwarder"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+const forwarderTopic = "forwarder"
+
+type OnDiscountAppliedHandler struct {
+	markOperationAppliedHandler MarkOperationAppliedHandler
+}
+
+func (h OnDiscountAppliedHandler) Handle(ctx context.Context, event *DiscountApplied) error {
+	cmd := MarkOperationApplied{
+		OperationID: event.OperationID,
+	}
+
+	return h.markOperationAppliedHandler.Handle(ctx, cmd)
+}
+
+type OnDiscountApplicationFailedHandler struct {
+	markOperationFailedHandler MarkOperationFailedHandler
+}
+
+func (h OnDiscountApplicationFailedHandler) Handle(ctx context.Context, event *DiscountApplicationFailed) error {
+	cmd := MarkOperationFailed{
+		OperationID: event.OperationID,
+		Reason:      event.Reason,
+	}
+
+	return h.markOperationFailedHandler.Handle(ctx, cmd)
+}
+
+// NewEventsMarshaler returns the marshaler for the given name ("json" or "protobuf").
+func NewEventsMarshaler(name string) (cqrs.CommandEventMarshaler, error) {
+	switch name {
+	case "", "json":
+		return cqrs.JSONMarshaler{
+			GenerateName: eventName,
+		}, nil
+	case "protobuf":
+		return cqrs.ProtobufMarshaler{
+			GenerateName: eventName,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown events marshaler: %s", name)
+	}
+}
+
+// eventName names events, and so their topics, after the struct alone, e.g. PointsUsedForDiscount.
+func eventName(v any) string {
+	return cqrs.StructName(v)
+}
+
+func NewPostgresOutboxPublisher(tx *sql.Tx) (message.Publisher, error) {
+	logger := newWatermillLogger()
+
+	return watermillSQL.NewPublisher(
+		tx,
+		watermillSQL.PublisherConfig{
+			SchemaAdapter: watermillSQL.DefaultPostgreSQLSchema{},
+		},
+		logger,
+	)
+}
+
+func NewPostgresOutboxSubscriber(db *sql.DB) (message.Subscriber, error) {
+	logger := newWatermillLogger()
+
+	return watermillSQL.NewSubscriber(
+		db,
+		watermillSQL.SubscriberConfig{
+			SchemaAdapter:    watermillSQL.DefaultPostgreSQLSchema{},
+			OffsetsAdapter:   watermillSQL.DefaultPostgreSQLOffsetsAdapter{},
+			InitializeSchema: true,
+		},
+		logger,
+	)
+}
+
+func NewRedisPublisher(redisAddr string) (message.Publisher, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	logger := newWatermillLogger()
+
+	return redisstream.NewPublisher(
+		redisstream.PublisherConfig{
+			Client: client,
+		},
+		logger,
+	)
+}
+
+// NewWatermillEventBus returns an event bus storing events in the outbox behind outboxPublisher.
+func NewWatermillEventBus(outboxPublisher message.Publisher, marshaler cqrs.CommandEventMarshaler) (*cqrs.EventBus, error) {
+	publisher := forwarder.NewPublisher(
+		outboxPublisher,
+		forwarder.PublisherConfig{
+			ForwarderTopic: forwarderTopic,
+		},
+	)
+
+	return newEventBus(publisher, marshaler)
+}
+
+func newEventBus(publisher message.Publisher, marshaler cqrs.CommandEventMarshaler) (*cqrs.EventBus, error) {
+	logger := newWatermillLogger()
+
+	eventBus, err := cqrs.NewEventBusWithConfig(publisher, cqrs.EventBusConfig{
+		GeneratePublishTopic: func(params cqrs.GenerateEventPublishTopicParams) (string, error) {
+			return params.EventName, nil
+		},
+		OnPublish: func(params cqrs.OnEventSendParams) error {
+			propagateToMessage(params.Message)
+			return nil
+		},
+		Marshaler: marshaler,
+		Logger:    logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return eventBus, nil
+}
+
+// NewEventsForwarder moves events from the outbox to the publisher.
+func NewEventsForwarder(
+	outboxSubscriber message.Subscriber,
+	publisher message.Publisher,
+) (*forwarder.Forwarder, error) {
+	logger := newWatermillLogger()
+
+	fwd, err := forwarder.NewForwarder(
+		outboxSubscriber,
+		publisher,
+		logger,
+		forwarder.Config{
+			ForwarderTopic: forwarderTopic,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return fwd, nil
+}
+
+func NewRedisSubscriber(redisAddr string) (message.Subscriber, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+
+	logger := newWatermillLogger()
+
+	return redisstream.NewSubscriber(
+		redisstream.SubscriberConfig{
+			Client:        client,
+			ConsumerGroup: "users-svc",
+		},
+		logger,
+	)
+}
+
+func NewEventsRouter(
+	subscriber message.Subscriber,
+	marshaler cqrs.CommandEventMarshaler,
+	markOperationAppliedHandler MarkOperationAppliedHandler,
+	markOperationFailedHandler MarkOperationFailedHandler,
+) (*message.Router, error) {
+	logger := newWatermillLogger()
+
+	router := message.NewDefaultRouter(logger)
+	router.AddMiddleware(restoreFromMessage)
+
+	eventProcessor, err := cqrs.NewEventProcessorWithConfig(router, cqrs.EventProcessorConfig{
+		GenerateSubscribeTopic: func(params cqrs.EventProcessorGenerateSubscribeTopicParams) (string, error) {
+			return params.EventName, nil
+		},
+		SubscriberConstructor: func(params cqrs.EventProcessorSubscriberConstructorParams) (message.Subscriber, error) {
+			return subscriber, nil
+		},
+		Marshaler: marshaler,
+		Logger:    logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	onDiscountAppliedHandler := OnDiscountAppliedHandler{
+		markOperationAppliedHandler: markOperationAppliedHandler,
+	}
+
+	onDiscountApplicationFailedHandler := OnDiscountApplicationFailedHandler{
+		markOperationFailedHandler: markOperationFailedHandler,
+	}
+
+	err = eventProcessor.AddHandlers(
+		cqrs.NewEventHandler(
+			"OnDiscountAppliedHandler",
+			onDiscountAppliedHandler.Handle,
+		),
+		cqrs.NewEventHandler(
+			"OnDiscountApplicationFailedHandler",
+			onDiscountApplicationFailedHandler.Handle,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return router, nil
+}
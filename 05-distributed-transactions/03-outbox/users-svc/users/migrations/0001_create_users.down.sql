@@ -0,0 +1,2 @@
+-- users and operations are shared with users-svc of the other examples, so they're kept.
+DROP TABLE IF EXISTS outbox_messages;
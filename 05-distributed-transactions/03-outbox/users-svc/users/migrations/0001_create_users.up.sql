@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS users (
+  id SERIAL PRIMARY KEY,
+  email TEXT NOT NULL UNIQUE,
+  points INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS operations (
+  id TEXT PRIMARY KEY,
+  user_id INT NOT NULL,
+  points INT NOT NULL,
+  status TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+  id BIGSERIAL PRIMARY KEY,
+  uuid TEXT NOT NULL,
+  topic TEXT NOT NULL,
+  payload BYTEA NOT NULL,
+  metadata JSONB NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  published_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_messages_unpublished_idx ON outbox_messages (id) WHERE published_at IS NULL;
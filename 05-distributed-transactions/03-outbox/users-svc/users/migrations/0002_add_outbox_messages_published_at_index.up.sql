@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS outbox_messages_published_at_idx ON outbox_messages (published_at) WHERE published_at IS NOT NULL;
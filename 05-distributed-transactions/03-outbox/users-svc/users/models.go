@@ -0,0 +1,133 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidPoints   = NewDomainError(ErrorKindValidation, "points must be greater than 0")
+	ErrNotEnoughPoints = NewDomainError(ErrorKindConflict, "not enough points")
+	ErrUserNotFound    = NewDomainError(ErrorKindNotFound, "user not found")
+)
+
+type User struct {
+	id     int
+	email  string
+	points int
+}
+
+func (u *User) UsePoints(points int) error {
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+
+	if u.points < points {
+		return ErrNotEnoughPoints
+	}
+
+	u.points -= points
+
+	return nil
+}
+
+func (u *User) ID() int {
+	return u.id
+}
+
+func (u *User) Email() string {
+	return u.email
+}
+
+func (u *User) Points() int {
+	return u.points
+}
+
+func UnmarshalUser(id int, email string, points int) *User {
+	return &User{
+		id:     id,
+		email:  email,
+		points: points,
+	}
+}
+
+type OperationStatus string
+
+const (
+	OperationStatusPending OperationStatus = "pending"
+	OperationStatusApplied OperationStatus = "applied"
+	OperationStatusFailed  OperationStatus = "failed"
+)
+
+// Operation tracks propagating the discount to the orders service.
+type Operation struct {
+	id     string
+	userID int
+	points int
+	status OperationStatus
+	reason string
+}
+
+func NewOperation(id string, userID int, points int) (*Operation, error) {
+	if id == "" {
+		return nil, errors.New("operation id must not be empty")
+	}
+
+	return &Operation{
+		id:     id,
+		userID: userID,
+		points: points,
+		status: OperationStatusPending,
+	}, nil
+}
+
+func (o *Operation) MarkApplied() error {
+	if o.status != OperationStatusPending {
+		return fmt.Errorf("operation is already %s", o.status)
+	}
+
+	o.status = OperationStatusApplied
+
+	return nil
+}
+
+func (o *Operation) MarkFailed(reason string) error {
+	if o.status != OperationStatusPending {
+		return fmt.Errorf("operation is already %s", o.status)
+	}
+
+	o.status = OperationStatusFailed
+	o.reason = reason
+
+	return nil
+}
+
+func (o *Operation) ID() string {
+	return o.id
+}
+
+func (o *Operation) UserID() int {
+	return o.userID
+}
+
+func (o *Operation) Points() int {
+	return o.points
+}
+
+func (o *Operation) Status() OperationStatus {
+	return o.status
+}
+
+func (o *Operation) Reason() string {
+	return o.reason
+}
+
+func UnmarshalOperation(id string, userID int, points int, status OperationStatus, reason string) *Operation {
+	return &Operation{
+		id:     id,
+		userID: userID,
+		points: points,
+		status: status,
+		reason: reason,
+	}
+}
@@ -0,0 +1,78 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/components/forwarder"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// Outbox stores events in the same transaction as the changes that caused them,
+// and publishes them once the transaction is committed.
+type Outbox interface {
+	// EventBus returns an event bus storing events within the transaction.
+	EventBus(tx *sql.Tx) (*cqrs.EventBus, error)
+	// Run publishes the stored events until the context is canceled.
+	Run(ctx context.Context) error
+}
+
+// NewPostgresOutbox returns the outbox for the given name ("watermill-sql" or "hand-rolled").
+func NewPostgresOutbox(
+	name string,
+	db *sql.DB,
+	dsn string,
+	marshaler cqrs.CommandEventMarshaler,
+	publisher message.Publisher,
+) (Outbox, error) {
+	switch name {
+	case "", "watermill-sql":
+		return NewWatermillSQLOutbox(db, marshaler, publisher)
+	case "hand-rolled":
+		return NewHandRolledOutbox(db, dsn, marshaler, publisher), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox: %s", name)
+	}
+}
+
+// WatermillSQLOutbox stores events using watermill-sql and moves them to the publisher with watermill's forwarder.
+type WatermillSQLOutbox struct {
+	marshaler cqrs.CommandEventMarshaler
+	forwarder *forwarder.Forwarder
+}
+
+func NewWatermillSQLOutbox(
+	db *sql.DB,
+	marshaler cqrs.CommandEventMarshaler,
+	publisher message.Publisher,
+) (*WatermillSQLOutbox, error) {
+	outboxSubscriber, err := NewPostgresOutboxSubscriber(db)
+	if err != nil {
+		return nil, err
+	}
+
+	fwd, err := NewEventsForwarder(outboxSubscriber, publisher)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WatermillSQLOutbox{
+		marshaler: marshaler,
+		forwarder: fwd,
+	}, nil
+}
+
+func (o *WatermillSQLOutbox) EventBus(tx *sql.Tx) (*cqrs.EventBus, error) {
+	publisher, err := NewPostgresOutboxPublisher(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWatermillEventBus(publisher, o.marshaler)
+}
+
+func (o *WatermillSQLOutbox) Run(ctx context.Context) error {
+	return o.forwarder.Run(ctx)
+}
@@ -0,0 +1,241 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/lib/pq"
+)
+
+const (
+	outboxNotifyChannel = "outbox_messages"
+
+	outboxBatchSize = 100
+	// outboxPollInterval is a fallback in case a notification is lost (e.g., while the listener reconnects).
+	outboxPollInterval = 5 * time.Second
+
+	// Published messages are kept for a while, so they can be inspected (or published again by hand).
+	outboxRetention       = 7 * 24 * time.Hour
+	outboxCleanupInterval = time.Hour
+)
+
+// HandRolledOutbox stores events in the outbox_messages table and relays them to the publisher.
+//
+// Many relays can run at the same time: each locks its batch with FOR UPDATE SKIP LOCKED,
+// so the other relays pick the next messages instead of waiting. Because of this, events
+// can be published out of order. They can also be published more than once, if the relay
+// fails after publishing, but before marking the batch as published.
+type HandRolledOutbox struct {
+	db        *sql.DB
+	dsn       string
+	marshaler cqrs.CommandEventMarshaler
+	publisher message.Publisher
+}
+
+func NewHandRolledOutbox(
+	db *sql.DB,
+	dsn string,
+	marshaler cqrs.CommandEventMarshaler,
+	publisher message.Publisher,
+) *HandRolledOutbox {
+	return &HandRolledOutbox{
+		db:        db,
+		dsn:       dsn,
+		marshaler: marshaler,
+		publisher: publisher,
+	}
+}
+
+func (o *HandRolledOutbox) EventBus(tx *sql.Tx) (*cqrs.EventBus, error) {
+	return newEventBus(outboxMessagesPublisher{tx: tx}, o.marshaler)
+}
+
+// Run relays messages whenever a transaction storing them is committed,
+// and deletes the messages published longer than outboxRetention ago.
+func (o *HandRolledOutbox) Run(ctx context.Context) error {
+	listener := pq.NewListener(o.dsn, time.Second, time.Minute, nil)
+	defer listener.Close()
+
+	err := listener.Listen(outboxNotifyChannel)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", outboxNotifyChannel, err)
+	}
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	cleanupTicker := time.NewTicker(outboxCleanupInterval)
+	defer cleanupTicker.Stop()
+
+	o.cleanup(ctx)
+
+	for {
+		o.relayAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-listener.Notify:
+		case <-ticker.C:
+		case <-cleanupTicker.C:
+			o.cleanup(ctx)
+		}
+	}
+}
+
+// cleanup deletes the published messages older than outboxRetention.
+// Errors are only logged, as the messages are deleted on the next run.
+func (o *HandRolledOutbox) cleanup(ctx context.Context) {
+	result, err := o.db.ExecContext(
+		ctx,
+		"DELETE FROM outbox_messages WHERE published_at < $1",
+		time.Now().Add(-outboxRetention),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Could not delete published outbox messages", "error", err)
+		return
+	}
+
+	deleted, err := result.RowsAffected()
+	if err == nil && deleted > 0 {
+		slog.InfoContext(ctx, "Deleted published outbox messages", "count", deleted)
+	}
+}
+
+// relayAll relays batches until there are no messages left.
+// Errors are only logged, as the messages are relayed again on the next run.
+func (o *HandRolledOutbox) relayAll(ctx context.Context) {
+	for {
+		relayed, err := o.relayBatch(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Could not relay outbox messages", "error", err)
+			return
+		}
+
+		if relayed < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (o *HandRolledOutbox) relayBatch(ctx context.Context) (int, error) {
+	var relayed int
+
+	err := runInTx(o.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(
+			ctx,
+			`SELECT id, uuid, topic, payload, metadata FROM outbox_messages
+			WHERE published_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED`,
+			outboxBatchSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		type outboxMessage struct {
+			id    int64
+			topic string
+			msg   *message.Message
+		}
+
+		var messages []outboxMessage
+		for rows.Next() {
+			var m outboxMessage
+			var uuid string
+			var payload, metadata []byte
+
+			err = rows.Scan(&m.id, &uuid, &m.topic, &payload, &metadata)
+			if err != nil {
+				_ = rows.Close()
+				return err
+			}
+
+			m.msg = message.NewMessage(uuid, payload)
+
+			err = json.Unmarshal(metadata, &m.msg.Metadata)
+			if err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("could not unmarshal metadata of message %s: %w", uuid, err)
+			}
+
+			messages = append(messages, m)
+		}
+
+		err = rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, 0, len(messages))
+		for _, m := range messages {
+			err = o.publisher.Publish(m.topic, m.msg)
+			if err != nil {
+				return fmt.Errorf("could not publish message %s: %w", m.msg.UUID, err)
+			}
+
+			ids = append(ids, m.id)
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE outbox_messages SET published_at = NOW() WHERE id = ANY($1)", pq.Array(ids))
+		if err != nil {
+			return err
+		}
+
+		relayed = len(messages)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return relayed, nil
+}
+
+// outboxMessagesPublisher stores messages in the outbox_messages table within the transaction.
+// The notification is delivered to the relay only after the transaction is committed.
+type outboxMessagesPublisher struct {
+	tx *sql.Tx
+}
+
+func (p outboxMessagesPublisher) Publish(topic string, messages ...*message.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	for _, msg := range messages {
+		metadata, err := json.Marshal(msg.Metadata)
+		if err != nil {
+			return err
+		}
+
+		_, err = p.tx.ExecContext(
+			msg.Context(),
+			"INSERT INTO outbox_messages (uuid, topic, payload, metadata) VALUES ($1, $2, $3, $4)",
+			msg.UUID, topic, []byte(msg.Payload), metadata,
+		)
+		if err != nil {
+			return fmt.Errorf("could not store message %s in the outbox: %w", msg.UUID, err)
+		}
+	}
+
+	_, err := p.tx.ExecContext(messages[0].Context(), "SELECT pg_notify($1, '')", outboxNotifyChannel)
+	return err
+}
+
+func (p outboxMessagesPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,150 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type PostgresUserRepository struct {
+	db     *sql.DB
+	outbox Outbox
+}
+
+func NewPostgresUserRepository(db *sql.DB, outbox Outbox) *PostgresUserRepository {
+	return &PostgresUserRepository{
+		db:     db,
+		outbox: outbox,
+	}
+}
+
+func (r *PostgresUserRepository) UpdateByIDWithOperation(ctx context.Context, userID int, operation *Operation, updateFn func(user *User) (bool, []any, error)) error {
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, "SELECT email, points FROM users WHERE id = $1 FOR UPDATE", userID)
+
+		var email string
+		var currentPoints int
+		err := row.Scan(&email, &currentPoints)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		user := UnmarshalUser(userID, email, currentPoints)
+
+		updated, events, err := updateFn(user)
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE users SET email = $1, points = $2 WHERE id = $3", user.Email(), user.Points(), user.ID())
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO operations (id, user_id, points, status, reason) VALUES ($1, $2, $3, $4, $5)",
+			operation.ID(), operation.UserID(), operation.Points(), operation.Status(), operation.Reason(),
+		)
+		if err != nil {
+			return err
+		}
+
+		eventBus, err := r.outbox.EventBus(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			err = eventBus.Publish(ctx, event)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+type PostgresOperationRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresOperationRepository(db *sql.DB) *PostgresOperationRepository {
+	return &PostgresOperationRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresOperationRepository) ByID(ctx context.Context, operationID string) (*Operation, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT user_id, points, status, reason FROM operations WHERE id = $1", operationID)
+
+	return scanOperation(row, operationID)
+}
+
+func (r *PostgresOperationRepository) UpdateByID(ctx context.Context, operationID string, updateFn func(operation *Operation) (bool, error)) error {
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, "SELECT user_id, points, status, reason FROM operations WHERE id = $1 FOR UPDATE", operationID)
+
+		operation, err := scanOperation(row, operationID)
+		if err != nil {
+			return err
+		}
+
+		updated, err := updateFn(operation)
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE operations SET status = $1, reason = $2 WHERE id = $3", operation.Status(), operation.Reason(), operation.ID())
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func scanOperation(row *sql.Row, operationID string) (*Operation, error) {
+	var userID, points int
+	var status, reason string
+	err := row.Scan(&userID, &points, &status, &reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOperationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return UnmarshalOperation(operationID, userID, points, OperationStatus(status), reason), nil
+}
+
+func runInTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err == nil {
+		return tx.Commit()
+	}
+
+	rollbackErr := tx.Rollback()
+	if rollbackErr != nil {
+		return errors.Join(err, rollbackErr)
+	}
+
+	return err
+}
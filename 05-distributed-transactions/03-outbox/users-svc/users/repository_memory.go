@@ -0,0 +1,147 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ThreeDotsLabs/watermill/components/cqrs"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// MemoryUserRepository keeps users in memory. The locks play the role of the transaction:
+// the user and the operation are saved only if all events were stored in the outbox.
+type MemoryUserRepository struct {
+	lock                sync.Mutex
+	users               map[int]User
+	operationRepository *MemoryOperationRepository
+	outboxPublisher     message.Publisher
+	marshaler           cqrs.CommandEventMarshaler
+}
+
+func NewMemoryUserRepository(
+	operationRepository *MemoryOperationRepository,
+	outboxPublisher message.Publisher,
+	marshaler cqrs.CommandEventMarshaler,
+) *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:               map[int]User{},
+		operationRepository: operationRepository,
+		outboxPublisher:     outboxPublisher,
+		marshaler:           marshaler,
+	}
+}
+
+func (r *MemoryUserRepository) Add(ctx context.Context, user *User) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.users[user.ID()]; ok {
+		return fmt.Errorf("user %d already exists", user.ID())
+	}
+
+	r.users[user.ID()] = *user
+
+	return nil
+}
+
+func (r *MemoryUserRepository) ByID(ctx context.Context, userID int) (*User, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) UpdateByIDWithOperation(ctx context.Context, userID int, operation *Operation, updateFn func(user *User) (bool, []any, error)) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.operationRepository.lock.Lock()
+	defer r.operationRepository.lock.Unlock()
+
+	if _, ok := r.operationRepository.operations[operation.ID()]; ok {
+		return fmt.Errorf("operation %s already exists", operation.ID())
+	}
+
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	updated, events, err := updateFn(&user)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return nil
+	}
+
+	eventBus, err := NewWatermillEventBus(r.outboxPublisher, r.marshaler)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		err = eventBus.Publish(ctx, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.users[userID] = user
+	r.operationRepository.operations[operation.ID()] = *operation
+
+	return nil
+}
+
+type MemoryOperationRepository struct {
+	lock       sync.Mutex
+	operations map[string]Operation
+}
+
+func NewMemoryOperationRepository() *MemoryOperationRepository {
+	return &MemoryOperationRepository{
+		operations: map[string]Operation{},
+	}
+}
+
+func (r *MemoryOperationRepository) ByID(ctx context.Context, operationID string) (*Operation, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	operation, ok := r.operations[operationID]
+	if !ok {
+		return nil, ErrOperationNotFound
+	}
+
+	return &operation, nil
+}
+
+func (r *MemoryOperationRepository) UpdateByID(ctx context.Context, operationID string, updateFn func(operation *Operation) (bool, error)) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	operation, ok := r.operations[operationID]
+	if !ok {
+		return ErrOperationNotFound
+	}
+
+	updated, err := updateFn(&operation)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return nil
+	}
+
+	r.operations[operationID] = operation
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,13 +21,18 @@ import (
 type testCase struct {
 	Name string
 	URL  string
+	// OrdersURL is set for examples where the discount is applied asynchronously.
+	OrdersURL string
 }
 
 func TestAll(t *testing.T) {
 	testCases := []testCase{
 		{Name: "01-distributed-monolith", URL: "http://localhost:8101"},
-		{Name: "02-eventual-consistency", URL: "http://localhost:8103"},
-		{Name: "03-outbox", URL: "http://localhost:8105"},
+		{Name: "02-eventual-consistency", URL: "http://localhost:8103", OrdersURL: "http://localhost:8104"},
+		{Name: "03-outbox", URL: "http://localhost:8105", OrdersURL: "http://localhost:8106"},
+		{Name: "02-eventual-consistency-protobuf", URL: "http://localhost:8107", OrdersURL: "http://localhost:8108"},
+		{Name: "03-outbox-protobuf", URL: "http://localhost:8109", OrdersURL: "http://localhost:8110"},
+		{Name: "03-outbox-hand-rolled", URL: "http://localhost:8111", OrdersURL: "http://localhost:8112"},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
@@ -34,12 +40,27 @@ func TestAll(t *testing.T) {
 
 			userID := createUser(t, tc, 100)
 
-			usePoints(t, tc, userID, 25)
+			operationID := usePoints(t, tc, userID, 25)
 
 			assertPoints(t, userID, 75)
 			assertDiscount(t, userID, 25)
 
-			usePoints(t, tc, userID, 50)
+			if tc.OrdersURL != "" {
+				assertOperationStatus(t, tc, operationID, "applied")
+				assertDiscountResponse(t, tc, userID, 25)
+			}
+
+			operationID = usePoints(t, tc, userID, 50)
+
+			assertPoints(t, userID, 25)
+			assertDiscount(t, userID, 75)
+
+			if tc.OrdersURL != "" {
+				assertOperationStatus(t, tc, operationID, "applied")
+				assertDiscountResponse(t, tc, userID, 75)
+			}
+
+			testUsePointsErrors(t, tc, userID)
 
 			assertPoints(t, userID, 25)
 			assertDiscount(t, userID, 75)
@@ -47,6 +68,61 @@ func TestAll(t *testing.T) {
 	}
 }
 
+// testUsePointsErrors checks if domain errors are mapped to status codes.
+// The user must have less than 1000 points.
+func testUsePointsErrors(t *testing.T, tc testCase, userID int) {
+	t.Helper()
+
+	testCases := []struct {
+		Name               string
+		Body               string
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "invalid_json",
+			Body:               `{"user_id": `,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "zero_points",
+			Body:               fmt.Sprintf(`{"user_id": %d, "points": 0}`, userID),
+			ExpectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:               "unknown_user",
+			Body:               `{"user_id": 2147483647, "points": 10}`,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "not_enough_points",
+			Body:               fmt.Sprintf(`{"user_id": %d, "points": 1000}`, userID),
+			ExpectedStatusCode: http.StatusConflict,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.Name, func(t *testing.T) {
+			res, err := http.Post(tc.URL+"/use-points", "application/json", strings.NewReader(c.Body))
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, c.ExpectedStatusCode, res.StatusCode, string(body))
+			assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+			var response struct {
+				Error string `json:"error"`
+			}
+			err = json.Unmarshal(body, &response)
+			require.NoError(t, err)
+
+			assert.NotEmpty(t, response.Error)
+		})
+	}
+}
+
 func getDB(t *testing.T) *sql.DB {
 	t.Helper()
 
@@ -77,7 +153,8 @@ func createUser(t *testing.T, tc testCase, points int) int {
 	return int(id)
 }
 
-func usePoints(t *testing.T, tc testCase, userID int, points int) {
+// usePoints returns the operation ID if the discount is applied asynchronously.
+func usePoints(t *testing.T, tc testCase, userID int, points int) string {
 	t.Helper()
 
 	type request struct {
@@ -103,6 +180,18 @@ func usePoints(t *testing.T, tc testCase, userID int, points int) {
 	require.NoError(t, err)
 
 	require.Equal(t, http.StatusOK, res.StatusCode, string(body))
+
+	if tc.OrdersURL == "" {
+		return ""
+	}
+
+	var response struct {
+		OperationID string `json:"operation_id"`
+	}
+	err = json.Unmarshal(body, &response)
+	require.NoError(t, err)
+
+	return response.OperationID
 }
 
 func assertPoints(t *testing.T, userID int, expectedPoints int) {
@@ -136,3 +225,52 @@ func assertDiscount(t *testing.T, userID int, expectedDiscount int) {
 		assert.Equal(t, expectedDiscount, discount)
 	}, 2*time.Second, 100*time.Millisecond)
 }
+
+func assertOperationStatus(t *testing.T, tc testCase, operationID string, expectedStatus string) {
+	t.Helper()
+
+	assert.EventuallyWithT(t, func(t *assert.CollectT) {
+		var operation struct {
+			Status string `json:"status"`
+		}
+		if !getJSON(t, tc.URL+"/operations/"+operationID, &operation) {
+			return
+		}
+
+		assert.Equal(t, expectedStatus, operation.Status)
+	}, 2*time.Second, 100*time.Millisecond)
+}
+
+func assertDiscountResponse(t *testing.T, tc testCase, userID int, expectedDiscount int) {
+	t.Helper()
+
+	assert.EventuallyWithT(t, func(t *assert.CollectT) {
+		var discount struct {
+			NextOrderDiscount int `json:"next_order_discount"`
+		}
+		if !getJSON(t, fmt.Sprintf("%s/users/%d/discount", tc.OrdersURL, userID), &discount) {
+			return
+		}
+
+		assert.Equal(t, expectedDiscount, discount.NextOrderDiscount)
+	}, 2*time.Second, 100*time.Millisecond)
+}
+
+func getJSON(t assert.TestingT, url string, v any) bool {
+	res, err := http.Get(url)
+	if !assert.NoError(t, err) {
+		return false
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if !assert.NoError(t, err) {
+		return false
+	}
+
+	if !assert.Equal(t, http.StatusOK, res.StatusCode, string(body)) {
+		return false
+	}
+
+	return assert.NoError(t, json.Unmarshal(body, v))
+}
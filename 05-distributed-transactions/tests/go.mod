@@ -3,15 +3,59 @@ module github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transacti
 go 1.22.0
 
 require (
-	github.com/google/uuid v1.3.1
+	github.com/ThreeDotsLabs/watermill v1.4.0-rc.1
+	github.com/google/uuid v1.6.0
 	github.com/lib/pq v1.10.9
-	github.com/stretchr/testify v1.8.4
+	github.com/stretchr/testify v1.9.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
 )
 
 require (
+	github.com/Rican7/retry v0.3.1 // indirect
+	github.com/ThreeDotsLabs/watermill-redisstream v1.3.0 // indirect
+	github.com/ThreeDotsLabs/watermill-sql/v3 v3.0.1 // indirect
+	github.com/cenkalti/backoff/v3 v3.2.2 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/hashicorp/errwrap v1.1.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
+	github.com/lithammer/shortuuid/v3 v3.0.7 // indirect
+	github.com/oklog/ulid v1.3.1 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/redis/go-redis/v9 v9.5.3 // indirect
+	github.com/sony/gobreaker v1.0.0 // indirect
+	github.com/vmihailenco/msgpack v4.0.4+incompatible // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	go.opentelemetry.io/otel/trace v1.28.0 // indirect
+	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+require (
+	github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/02-eventual-consistency/orders-svc v0.0.0-00010101000000-000000000000
+	github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/02-eventual-consistency/users-svc v0.0.0-00010101000000-000000000000
+	github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/03-outbox/orders-svc v0.0.0-00010101000000-000000000000
+	github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/03-outbox/users-svc v0.0.0-00010101000000-000000000000
+	github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate v0.0.0-00010101000000-000000000000
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/02-eventual-consistency/users-svc => ../02-eventual-consistency/users-svc
+
+replace github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/02-eventual-consistency/orders-svc => ../02-eventual-consistency/orders-svc
+
+replace github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/03-outbox/users-svc => ../03-outbox/users-svc
+
+replace github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/03-outbox/orders-svc => ../03-outbox/orders-svc
+
+replace github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate => ../../migrate
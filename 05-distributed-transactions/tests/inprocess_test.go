@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ecorders "github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/02-eventual-consistency/orders-svc/orders"
+	ecusers "github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/02-eventual-consistency/users-svc/users"
+	outboxorders "github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/03-outbox/orders-svc/orders"
+	outboxusers "github.com/ThreeDotsLabs/go-web-app-antipatterns/05-distributed-transactions/03-outbox/users-svc/users"
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var marshalers = []string{"json", "protobuf"}
+
+// spanExporter collects spans of all in-process services. Tests filter them by their trace ID.
+var spanExporter = tracetest.NewInMemoryExporter()
+
+func init() {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// inProcessServices are users-svc and orders-svc of one example, running in the test binary.
+type inProcessServices struct {
+	testCase
+
+	// AddUser adds the user to users-svc only.
+	AddUser func(userID int, points int) error
+	// AddOrdersUser adds the user to orders-svc only.
+	AddOrdersUser func(userID int) error
+	Points        func(userID int) (int, error)
+}
+
+func TestInProcessEventualConsistency(t *testing.T) {
+	for _, marshalerName := range marshalers {
+		t.Run(marshalerName, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			pubSub := newGoChannel(t)
+
+			ordersMarshaler, err := ecorders.NewEventsMarshaler(marshalerName)
+			require.NoError(t, err)
+
+			ordersEventBus, err := ecorders.NewWatermillEventBus(pubSub, ordersMarshaler)
+			require.NoError(t, err)
+
+			discountRepo := ecorders.NewMemoryDiscountRepository()
+
+			ordersRouter, err := ecorders.NewEventsRouter(pubSub, ordersMarshaler, ecorders.NewAddDiscountHandler(discountRepo, ordersEventBus))
+			require.NoError(t, err)
+			runRouter(t, ordersRouter)
+
+			ordersServer := httptest.NewServer(ecorders.NewHTTPHandler(discountRepo))
+			t.Cleanup(ordersServer.Close)
+
+			usersMarshaler, err := ecusers.NewEventsMarshaler(marshalerName)
+			require.NoError(t, err)
+
+			usersEventBus, err := ecusers.NewWatermillEventBus(pubSub, usersMarshaler)
+			require.NoError(t, err)
+
+			operationRepo := ecusers.NewMemoryOperationRepository()
+			userRepo := ecusers.NewMemoryUserRepository(operationRepo)
+
+			usersRouter, err := ecusers.NewEventsRouter(
+				pubSub,
+				usersMarshaler,
+				ecusers.NewMarkOperationAppliedHandler(operationRepo),
+				ecusers.NewMarkOperationFailedHandler(operationRepo),
+			)
+			require.NoError(t, err)
+			runRouter(t, usersRouter)
+
+			usersServer := httptest.NewServer(ecusers.NewHTTPHandler(
+				ecusers.NewUsePointsAsDiscountHandler(userRepo, usersEventBus),
+				operationRepo,
+			))
+			t.Cleanup(usersServer.Close)
+
+			testInProcessServices(t, inProcessServices{
+				testCase: testCase{
+					Name:      "02-eventual-consistency-in-process",
+					URL:       usersServer.URL,
+					OrdersURL: ordersServer.URL,
+				},
+				AddUser: func(userID int, points int) error {
+					return userRepo.Add(ctx, ecusers.UnmarshalUser(userID, "user@example.com", points))
+				},
+				AddOrdersUser: func(userID int) error {
+					return discountRepo.AddUser(ctx, userID)
+				},
+				Points: func(userID int) (int, error) {
+					user, err := userRepo.ByID(ctx, userID)
+					if err != nil {
+						return 0, err
+					}
+
+					return user.Points(), nil
+				},
+			})
+		})
+	}
+}
+
+func TestInProcessOutbox(t *testing.T) {
+	for _, marshalerName := range marshalers {
+		t.Run(marshalerName, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			outbox := newGoChannel(t)
+			pubSub := newGoChannel(t)
+
+			ordersMarshaler, err := outboxorders.NewEventsMarshaler(marshalerName)
+			require.NoError(t, err)
+
+			ordersEventBus, err := outboxorders.NewWatermillEventBus(pubSub, ordersMarshaler)
+			require.NoError(t, err)
+
+			discountRepo := outboxorders.NewMemoryDiscountRepository()
+
+			ordersRouter, err := outboxorders.NewEventsRouter(pubSub, ordersMarshaler, outboxorders.NewAddDiscountHandler(discountRepo, ordersEventBus))
+			require.NoError(t, err)
+			runRouter(t, ordersRouter)
+
+			ordersServer := httptest.NewServer(outboxorders.NewHTTPHandler(discountRepo))
+			t.Cleanup(ordersServer.Close)
+
+			forwarder, err := outboxusers.NewEventsForwarder(outbox, pubSub)
+			require.NoError(t, err)
+
+			go func() {
+				_ = forwarder.Run(ctx)
+			}()
+			t.Cleanup(func() {
+				_ = forwarder.Close()
+			})
+			<-forwarder.Running()
+
+			usersMarshaler, err := outboxusers.NewEventsMarshaler(marshalerName)
+			require.NoError(t, err)
+
+			operationRepo := outboxusers.NewMemoryOperationRepository()
+			userRepo := outboxusers.NewMemoryUserRepository(operationRepo, outbox, usersMarshaler)
+
+			usersRouter, err := outboxusers.NewEventsRouter(
+				pubSub,
+				usersMarshaler,
+				outboxusers.NewMarkOperationAppliedHandler(operationRepo),
+				outboxusers.NewMarkOperationFailedHandler(operationRepo),
+			)
+			require.NoError(t, err)
+			runRouter(t, usersRouter)
+
+			usersServer := httptest.NewServer(outboxusers.NewHTTPHandler(
+				outboxusers.NewUsePointsAsDiscountHandler(userRepo),
+				operationRepo,
+			))
+			t.Cleanup(usersServer.Close)
+
+			testInProcessServices(t, inProcessServices{
+				testCase: testCase{
+					Name:      "03-outbox-in-process",
+					URL:       usersServer.URL,
+					OrdersURL: ordersServer.URL,
+				},
+				AddUser: func(userID int, points int) error {
+					return userRepo.Add(ctx, outboxusers.UnmarshalUser(userID, "user@example.com", points))
+				},
+				AddOrdersUser: func(userID int) error {
+					return discountRepo.AddUser(ctx, userID)
+				},
+				Points: func(userID int) (int, error) {
+					user, err := userRepo.ByID(ctx, userID)
+					if err != nil {
+						return 0, err
+					}
+
+					return user.Points(), nil
+				},
+			})
+		})
+	}
+}
+
+// TestAddDiscountHandler_redelivered checks if a redelivered PointsUsedForDiscount, e.g., after
+// publishing DiscountApplied failed, doesn't add the discount twice, but publishes the event again.
+func TestAddDiscountHandler_redelivered(t *testing.T) {
+	ctx := context.Background()
+	userID := 1
+
+	t.Run("02-eventual-consistency", func(t *testing.T) {
+		discountRepo := ecorders.NewMemoryDiscountRepository()
+		require.NoError(t, discountRepo.AddUser(ctx, userID))
+
+		publisher := &recordingEventPublisher{}
+		handler := ecorders.NewAddDiscountHandler(discountRepo, publisher)
+
+		cmd := ecorders.AddDiscount{OperationID: "operation-1", UserID: userID, Discount: 25}
+		require.NoError(t, handler.Handle(ctx, cmd))
+		require.NoError(t, handler.Handle(ctx, cmd))
+
+		discount, err := discountRepo.NextOrderDiscount(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 25, discount)
+
+		applied := &ecorders.DiscountApplied{OperationID: "operation-1"}
+		assert.Equal(t, []any{applied, applied}, publisher.events)
+	})
+
+	t.Run("03-outbox", func(t *testing.T) {
+		discountRepo := outboxorders.NewMemoryDiscountRepository()
+		require.NoError(t, discountRepo.AddUser(ctx, userID))
+
+		publisher := &recordingEventPublisher{}
+		handler := outboxorders.NewAddDiscountHandler(discountRepo, publisher)
+
+		cmd := outboxorders.AddDiscount{OperationID: "operation-1", UserID: userID, Discount: 25}
+		require.NoError(t, handler.Handle(ctx, cmd))
+		require.NoError(t, handler.Handle(ctx, cmd))
+
+		discount, err := discountRepo.NextOrderDiscount(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 25, discount)
+
+		applied := &outboxorders.DiscountApplied{OperationID: "operation-1"}
+		assert.Equal(t, []any{applied, applied}, publisher.events)
+	})
+}
+
+type recordingEventPublisher struct {
+	events []any
+}
+
+func (p *recordingEventPublisher) Publish(ctx context.Context, event any) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func testInProcessServices(t *testing.T, s inProcessServices) {
+	t.Helper()
+
+	userID := 1
+
+	err := s.AddUser(userID, 100)
+	require.NoError(t, err)
+
+	err = s.AddOrdersUser(userID)
+	require.NoError(t, err)
+
+	points := func() (int, error) {
+		return s.Points(userID)
+	}
+
+	operationID := usePoints(t, s.testCase, userID, 25)
+
+	assertEventually(t, 75, points)
+	assertOperationStatus(t, s.testCase, operationID, "applied")
+	assertDiscountResponse(t, s.testCase, userID, 25)
+
+	operationID = usePoints(t, s.testCase, userID, 50)
+
+	assertEventually(t, 25, points)
+	assertOperationStatus(t, s.testCase, operationID, "applied")
+	assertDiscountResponse(t, s.testCase, userID, 75)
+
+	testUsePointsErrors(t, s.testCase, userID)
+
+	assertEventually(t, 25, points)
+
+	// orders-svc doesn't know this user, so it can't apply the discount.
+	unknownUserID := 2
+
+	err = s.AddUser(unknownUserID, 100)
+	require.NoError(t, err)
+
+	operationID = usePoints(t, s.testCase, unknownUserID, 10)
+
+	assertOperationStatus(t, s.testCase, operationID, "failed")
+
+	testCorrelationAndTracePropagation(t, s.testCase, userID)
+}
+
+// testCorrelationAndTracePropagation checks if the correlation ID and the trace
+// travel from the HTTP request, through orders-svc, back to users-svc.
+func testCorrelationAndTracePropagation(t *testing.T, tc testCase, userID int) {
+	t.Helper()
+
+	ctx, span := otel.Tracer("tests").Start(context.Background(), "test")
+	span.End()
+
+	traceID := span.SpanContext().TraceID()
+	correlationID := "test-" + watermill.NewUUID()
+
+	payload, err := json.Marshal(map[string]int{
+		"user_id": userID,
+		"points":  5,
+	})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", tc.URL+"/use-points", bytes.NewReader(payload))
+	require.NoError(t, err)
+
+	req.Header.Set("X-Correlation-ID", correlationID)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, correlationID, res.Header.Get("X-Correlation-ID"))
+
+	assert.EventuallyWithT(t, func(t *assert.CollectT) {
+		spans := map[string]tracetest.SpanStub{}
+		for _, s := range spanExporter.GetSpans() {
+			if s.SpanContext.TraceID() == traceID {
+				spans[s.Name] = s
+			}
+		}
+
+		for _, name := range []string{"POST /use-points", "OnPointsUsedForDiscountHandler", "OnDiscountAppliedHandler"} {
+			s, ok := spans[name]
+			if !assert.True(t, ok, "span %s not found", name) {
+				continue
+			}
+
+			assert.Contains(t, s.Attributes, attribute.String("correlation_id", correlationID), "span %s", name)
+		}
+	}, 2*time.Second, 100*time.Millisecond)
+}
+
+func newGoChannel(t *testing.T) *gochannel.GoChannel {
+	t.Helper()
+
+	pubSub := gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+	t.Cleanup(func() {
+		_ = pubSub.Close()
+	})
+
+	return pubSub
+}
+
+func runRouter(t *testing.T, router *message.Router) {
+	t.Helper()
+
+	go func() {
+		_ = router.Run(context.Background())
+	}()
+	t.Cleanup(func() {
+		_ = router.Close()
+	})
+
+	<-router.Running()
+}
+
+func assertEventually(t *testing.T, expected int, get func() (int, error)) {
+	t.Helper()
+
+	assert.EventuallyWithT(t, func(t *assert.CollectT) {
+		actual, err := get()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, expected, actual)
+	}, 2*time.Second, 100*time.Millisecond)
+}
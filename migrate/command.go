@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var errUsage = errors.New("usage: migrate up | down [steps] | version")
+
+// RunCommand handles the services' migrate subcommand, so migrations can be applied
+// or reverted without starting the server.
+func (m *Migrator) RunCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+
+		return m.Down(ctx, steps)
+	case "version":
+		version, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(version)
+		return nil
+	default:
+		return errUsage
+	}
+}
@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var errLockNotTaken = errors.New("could not take the migrations lock")
+
+// Dialect holds the queries a Migrator runs on the database.
+type Dialect struct {
+	createTable string
+	lock        func(ctx context.Context, conn *sql.Conn) error
+	unlock      string
+	applied     string
+	version     string
+	insert      string
+	delete      string
+
+	// args come before the version (and name) in the queries that take them.
+	args []interface{}
+}
+
+// MySQL records migrations in the schema_migrations table, and takes a named lock (GET_LOCK) while running them.
+// The DSN needs multiStatements=true, as migration files can hold many statements.
+var MySQL = Dialect{
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version bigint NOT NULL PRIMARY KEY, " +
+		"name varchar(255) NOT NULL, " +
+		"applied_at datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3))",
+	lock:    lockMySQL,
+	unlock:  "SELECT RELEASE_LOCK('schema_migrations')",
+	applied: "SELECT version FROM schema_migrations",
+	version: "SELECT COALESCE(MAX(version), 0) FROM schema_migrations",
+	insert:  "INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
+	delete:  "DELETE FROM schema_migrations WHERE version = ?",
+}
+
+// Postgres records migrations in the schema_migrations table, and takes an advisory lock while running them.
+var Postgres = Dialect{
+	createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version bigint NOT NULL PRIMARY KEY, " +
+		"name text NOT NULL, " +
+		"applied_at timestamp(3) NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+	lock:    lockPostgres,
+	unlock:  postgresUnlock,
+	applied: "SELECT version FROM schema_migrations",
+	version: "SELECT COALESCE(MAX(version), 0) FROM schema_migrations",
+	insert:  "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+	delete:  "DELETE FROM schema_migrations WHERE version = $1",
+}
+
+// PostgresService is like Postgres, but records migrations per service,
+// for services sharing one database.
+func PostgresService(service string) Dialect {
+	return Dialect{
+		createTable: "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+			"service TEXT NOT NULL, " +
+			"version INT NOT NULL, " +
+			"name TEXT NOT NULL, " +
+			"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+			"PRIMARY KEY (service, version))",
+		lock:    lockPostgres,
+		unlock:  postgresUnlock,
+		applied: "SELECT version FROM schema_migrations WHERE service = $1",
+		version: "SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE service = $1",
+		insert:  "INSERT INTO schema_migrations (service, version, name) VALUES ($1, $2, $3)",
+		delete:  "DELETE FROM schema_migrations WHERE service = $1 AND version = $2",
+		args:    []interface{}{service},
+	}
+}
+
+const postgresUnlock = "SELECT pg_advisory_unlock(hashtext('schema_migrations'))"
+
+// lockMySQL waits for the named lock. GET_LOCK returns 1 once the lock is taken,
+// and NULL if it fails (e.g., if the session is killed while waiting).
+func lockMySQL(ctx context.Context, conn *sql.Conn) error {
+	var locked sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT GET_LOCK('schema_migrations', -1)").Scan(&locked)
+	if err != nil {
+		return err
+	}
+
+	if locked.Int64 != 1 {
+		return errLockNotTaken
+	}
+
+	return nil
+}
+
+// lockPostgres waits for the advisory lock. Unlike GET_LOCK, pg_advisory_lock
+// returns nothing, and reports failures as errors.
+func lockPostgres(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext('schema_migrations'))")
+	return err
+}
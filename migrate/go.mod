@@ -0,0 +1,3 @@
+module github.com/ThreeDotsLabs/go-web-app-antipatterns/migrate
+
+go 1.16
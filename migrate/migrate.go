@@ -0,0 +1,267 @@
+// Package migrate applies versioned schema migrations, embedded in the services as numbered up and down files.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a single schema change, loaded from a pair of numbered files,
+// like 0001_create_users.up.sql and 0001_create_users.down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies migrations and keeps track of them in the schema_migrations table.
+// Migrations run while holding a database lock, so many instances starting at once
+// don't apply the same migration twice.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// New returns a Migrator for the migrations in the dir directory of files.
+func New(db *sql.DB, dialect Dialect, files fs.FS, dir string) (*Migrator, error) {
+	files, err := fs.Sub(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies all migrations that weren't applied yet.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if applied[migration.Version] {
+				continue
+			}
+
+			log.Printf("Applying migration %04d_%s", migration.Version, migration.Name)
+
+			err = run(ctx, conn, migration.Up, m.dialect.insert, m.queryArgs(migration.Version, migration.Name)...)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the given number of most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if !applied[migration.Version] {
+				continue
+			}
+
+			log.Printf("Reverting migration %04d_%s", migration.Version, migration.Name)
+
+			err = run(ctx, conn, migration.Down, m.dialect.delete, m.queryArgs(migration.Version)...)
+			if err != nil {
+				return fmt.Errorf("reverting migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Version returns the most recently applied migration's version, or 0 if none was applied.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		return conn.QueryRowContext(ctx, m.dialect.version, m.queryArgs()...).Scan(&version)
+	})
+
+	return version, err
+}
+
+// withLock runs fn on a single connection, as database locks belong to the session that took them.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = m.dialect.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_, err := conn.ExecContext(context.Background(), m.dialect.unlock)
+		if err != nil {
+			log.Println("Error while releasing the migrations lock:", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, m.dialect.createTable)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, m.dialect.applied, m.queryArgs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		err = rows.Scan(&version)
+		if err != nil {
+			return nil, err
+		}
+
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *Migrator) queryArgs(args ...interface{}) []interface{} {
+	return append(append([]interface{}{}, m.dialect.args...), args...)
+}
+
+// run executes the migration and records it in a single transaction.
+// MySQL commits schema changes implicitly, so a failed migration might need a manual cleanup there.
+func run(ctx context.Context, conn *sql.Conn, query string, recordQuery string, recordArgs ...interface{}) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				log.Println("Error while rolling back:", rollbackErr)
+			}
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, recordQuery, recordArgs...)
+	return err
+}
+
+// loadMigrations reads migrations from the directory, sorted by their versions.
+// Each version needs both the up and down file, and versions can't have gaps.
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		matches := migrationFileName.FindStringSubmatch(e.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", e.Name())
+		}
+
+		version, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(files, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{
+				Version: version,
+				Name:    matches[2],
+			}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d has files with different names: %s and %s", version, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both the up and down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %04d_%s should have version %d, as versions can't have gaps", migration.Version, migration.Name, i+1)
+		}
+	}
+
+	return migrations, nil
+}
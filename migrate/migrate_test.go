@@ -0,0 +1,87 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Files            fstest.MapFS
+		ExpectedVersions []int
+		ExpectedErr      bool
+	}{
+		{
+			Name: "sorted_by_version",
+			Files: fstest.MapFS{
+				"0003_add_index.up.sql":      {Data: []byte("CREATE INDEX")},
+				"0003_add_index.down.sql":    {Data: []byte("DROP INDEX")},
+				"0002_add_column.up.sql":     {Data: []byte("ALTER TABLE")},
+				"0002_add_column.down.sql":   {Data: []byte("ALTER TABLE")},
+				"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE")},
+				"0001_create_users.down.sql": {Data: []byte("DROP TABLE")},
+			},
+			ExpectedVersions: []int{1, 2, 3},
+		},
+		{
+			Name: "version_gap",
+			Files: fstest.MapFS{
+				"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE")},
+				"0001_create_users.down.sql": {Data: []byte("DROP TABLE")},
+				"0003_add_index.up.sql":      {Data: []byte("CREATE INDEX")},
+				"0003_add_index.down.sql":    {Data: []byte("DROP INDEX")},
+			},
+			ExpectedErr: true,
+		},
+		{
+			Name: "missing_down",
+			Files: fstest.MapFS{
+				"0001_create_users.up.sql": {Data: []byte("CREATE TABLE")},
+			},
+			ExpectedErr: true,
+		},
+		{
+			Name: "different_names",
+			Files: fstest.MapFS{
+				"0001_create_users.up.sql":  {Data: []byte("CREATE TABLE")},
+				"0001_create_emails.up.sql": {Data: []byte("CREATE TABLE")},
+			},
+			ExpectedErr: true,
+		},
+		{
+			Name: "invalid_name",
+			Files: fstest.MapFS{
+				"create_users.sql": {Data: []byte("CREATE TABLE")},
+			},
+			ExpectedErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.Name, func(t *testing.T) {
+			migrations, err := loadMigrations(tc.Files)
+			if tc.ExpectedErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(migrations) != len(tc.ExpectedVersions) {
+				t.Fatalf("expected %d migrations, got %d", len(tc.ExpectedVersions), len(migrations))
+			}
+
+			for i, m := range migrations {
+				if m.Version != tc.ExpectedVersions[i] {
+					t.Errorf("expected migration %d to have version %d, got %d", i, tc.ExpectedVersions[i], m.Version)
+				}
+			}
+		})
+	}
+}